@@ -33,6 +33,8 @@ import (
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
@@ -48,8 +50,13 @@ import (
 	operatorConfig "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/controller/scheduledsparkapplication"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/controller/sparkapplication"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/logarchive"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/notification"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/tracing"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/uiproxy"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/webhook"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/webhook/resourceusage"
 )
 
 var (
@@ -60,8 +67,38 @@ var (
 	namespace                      = flag.String("namespace", apiv1.NamespaceAll, "The Kubernetes namespace to manage. Will manage custom resource objects of the managed CRD types for the whole cluster if unset.")
 	enableWebhook                  = flag.Bool("enable-webhook", false, "Whether to enable the mutating admission webhook for admitting and patching Spark pods.")
 	enableResourceQuotaEnforcement = flag.Bool("enable-resource-quota-enforcement", false, "Whether to enable ResourceQuota enforcement for SparkApplication resources. Requires the webhook to be enabled.")
+	resourceQuotaEnforcementMode   = flag.String("resource-quota-enforcement-mode", string(resourceusage.EnforcementModeReject), fmt.Sprintf("What happens to SparkApplications exceeding the resource quotas in namespaces without the %s label, one of (%s, %s, %s, %s). With %s, they are admitted and queued until they fit. With %s, they are admitted and only reported by a ResourceQuotaExceeded event and the webhook_quota_warning_count and webhook_quota_shortfall metrics, as the admission warnings are ignored by API servers before Kubernetes 1.19.", operatorConfig.QuotaEnforcementLabel, resourceusage.EnforcementModeReject, resourceusage.EnforcementModeQueue, resourceusage.EnforcementModeWarn, resourceusage.EnforcementModeDisabled, resourceusage.EnforcementModeQueue, resourceusage.EnforcementModeWarn))
+	resourceQuotaQueueOrdering     = flag.String("resource-quota-queue-ordering", string(sparkapplication.QueueOrderingFIFO), fmt.Sprintf("Order in which the queued SparkApplications of a namespace are submitted, one of (%s, %s).", sparkapplication.QueueOrderingFIFO, sparkapplication.QueueOrderingPriority))
+	resourceQuotaQueueRecheck      = flag.Duration("resource-quota-queue-recheck-interval", 10*time.Second, "Interval between two checks of whether a queued SparkApplication fits into the resource quotas.")
+	enableNodeFitCheck             = flag.Bool("enable-node-fit-check", false, "Whether to reject SparkApplications and ScheduledSparkApplications whose driver or executor pods fit on no schedulable node. Requires the webhook to be enabled.")
 	ingressURLFormat               = flag.String("ingress-url-format", "", "Ingress URL format.")
+	ingressAPIVersion              = flag.String("ingress-api-version", sparkapplication.IngressAPIVersionExtensionsV1beta1, fmt.Sprintf("API version of the Ingresses exposing the Spark UI, one of (%s, %s).", sparkapplication.IngressAPIVersionExtensionsV1beta1, sparkapplication.IngressAPIVersionNetworkingV1))
+	ingressClassName               = flag.String("ingress-class-name", "", "Class of the Ingresses exposing the Spark UI.")
+	ingressTLSSecretName           = flag.String("ingress-tls-secret-name", "", "Name of the Secret holding the TLS certificate of the Ingresses exposing the Spark UI. TLS is not configured if unset.")
+	uiServiceType                  = flag.String("ui-service-type", string(apiv1.ServiceTypeClusterIP), "Type of the Services exposing the Spark UI.")
 	enableUIService                = flag.Bool("enable-ui-service", true, "Enable Spark service UI.")
+	driverFailureLogTailLines      = flag.Int64("driver-failure-log-tail-lines", 0, "Number of lines at the end of the driver logs to capture into a ConfigMap when the driver container fails. Capturing is disabled if set to 0.")
+	logArchiveURL                  = flag.String("log-archive-url", "", "URL of the location logs of driver and executor pods are archived to when an application terminates, e.g., file:///var/log/spark, gs://bucket/path, or s3://bucket/path. Log archiving is disabled if unset.")
+	eventLogDir                    = flag.String("event-log-dir", "", "Default base directory of Spark event logs, e.g., gs://bucket/spark-events. Event logging is enabled by default for all applications if set.")
+	eventLogCompress               = flag.Bool("event-log-compress", false, "Whether to compress Spark event logs by default.")
+	historyServerURLFormat         = flag.String("history-server-url-format", "", "Format of the URLs of finished applications in the Spark History Server, e.g., https://spark-history.example.com/history/{{$appID}}.")
+	enableUIProxy                  = flag.Bool("enable-ui-proxy", false, "Whether to serve the driver UIs through a built-in reverse proxy under /{namespace}/{app}/. Requires the Spark UI service to be enabled, and cannot be used along with an ingress URL format with a path. The proxy serves the UI of every application, including the endpoints killing jobs and stages, without authentication, so its port must only be reachable by trusted users.")
+	uiProxyPort                    = flag.String("ui-proxy-port", "8080", "Port for the driver UI proxy.")
+	enableServiceMonitors          = flag.Bool("enable-service-monitors", false, "Whether to create a metrics Service and a ServiceMonitor of the Prometheus Operator for each application exposing driver or executor metrics. Requires the ServiceMonitor CRD to be installed.")
+	enableSparkProgress            = flag.Bool("enable-spark-progress", false, "Whether to report the progress of jobs, stages and tasks of running applications in their status, which is queried from the REST API of the drivers through the Spark UI service.")
+	sparkProgressPollInterval      = flag.Duration("spark-progress-poll-interval", 30*time.Second, fmt.Sprintf("Interval between two queries of the REST API of a driver for the progress of the application. Must be at least %v.", sparkapplication.MinSparkProgressPollInterval))
+	tracingOTLPEndpoint            = flag.String("tracing-otlp-endpoint", "", "Host and port of the OTLP/HTTP receiver the traces of the lifecycle of applications are exported to with the JSON encoding, e.g., otel-collector:4318. Tracing is disabled if unset.")
+	tracingOTLPInsecure            = flag.Bool("tracing-otlp-insecure", false, "Whether to disable TLS for the connection to the OTLP receiver.")
+	tracingServiceName             = flag.String("tracing-service-name", "spark-operator", "Name of the service the traces are reported as coming from.")
+	tracingSampleRatio             = flag.Float64("tracing-sample-ratio", 1, "Ratio of application runs that are traced, between 0 and 1.")
+	enableNotifications            = flag.Bool("enable-notifications", false, "Whether to notify the HTTP endpoints configured through annotations of SparkApplications and Namespaces of the state transitions of the applications.")
+	notificationTimeout            = flag.Duration("notification-timeout", 10*time.Second, "Timeout of a single attempt to deliver a notification.")
+	notificationMaxRetries         = flag.Int("notification-max-retries", 3, "Maximum number of retries of a failed delivery of a notification.")
+	notificationRetryInterval      = flag.Duration("notification-retry-interval", 5*time.Second, "Interval before the first retry of a failed delivery of a notification, which is doubled for every subsequent retry.")
+	notificationAllowedURLPrefixes = flag.String("notification-allowed-url-prefixes", "", "Comma-separated URL prefixes the notification URLs set through annotations of SparkApplications must start with. Notification URLs of applications are rejected if unset, while the ones set through annotations of Namespaces are not restricted.")
+	notificationWorkers            = flag.Int("notification-workers", 4, "Number of workers delivering notifications, and of workers emitting CloudEvents, in the background.")
+	notificationQueueSize          = flag.Int("notification-queue-size", 1000, "Maximum number of notifications or CloudEvents queued per worker, beyond which they are dropped.")
+	cloudEventsSinkURL             = flag.String("cloudevents-sink-url", "", "URL of the sink CloudEvents of the lifecycle transitions of SparkApplications and ScheduledSparkApplications are POSTed to. CloudEvents are not emitted if unset.")
 	enableLeaderElection           = flag.Bool("leader-election", false, "Enable Spark operator leader election.")
 	leaderElectionLockNamespace    = flag.String("leader-election-lock-namespace", "spark-operator", "Namespace in which to create the ConfigMap for leader election.")
 	leaderElectionLockName         = flag.String("leader-election-lock-name", "spark-operator-lock", "Name of the ConfigMap for leader election.")
@@ -74,7 +111,14 @@ var (
 	metricsEndpoint                = flag.String("metrics-endpoint", "/metrics", "Metrics endpoint.")
 	metricsPrefix                  = flag.String("metrics-prefix", "", "Prefix for the metrics.")
 	metricsLabels                  util.ArrayFlags
+	uiServiceAnnotations           util.ArrayFlags
+	uiServiceLabels                util.ArrayFlags
+	ingressAnnotations             util.ArrayFlags
+	serviceMonitorLabels           util.ArrayFlags
 	metricsJobStartLatencyBuckets  util.HistogramBuckets = util.DefaultJobStartLatencyBuckets
+	metricsExecutionTimeBuckets    util.HistogramBuckets = util.DefaultExecutionTimeBuckets
+	metricsSubmissionJobBuckets    util.HistogramBuckets = util.DefaultSubmissionJobDurationBuckets
+	metricsDriverPendingBuckets    util.HistogramBuckets = util.DefaultDriverPendingDurationBuckets
 )
 
 // Increase QPS for kubeClient to prevent throttling.
@@ -89,6 +133,16 @@ func main() {
 	flag.Var(&metricsJobStartLatencyBuckets, "metrics-job-start-latency-buckets",
 		"Comma-separated boundary values (in seconds) for the job start latency histogram bucket; "+
 			"it accepts any numerical values that can be parsed into a 64-bit floating point")
+	flag.Var(&metricsExecutionTimeBuckets, "metrics-execution-time-buckets",
+		"Comma-separated boundary values (in seconds) for the execution time histogram buckets of successful and failed applications")
+	flag.Var(&metricsSubmissionJobBuckets, "metrics-submission-job-duration-buckets",
+		"Comma-separated boundary values (in seconds) for the submission Job duration histogram buckets")
+	flag.Var(&metricsDriverPendingBuckets, "metrics-driver-pending-duration-buckets",
+		"Comma-separated boundary values (in seconds) for the histogram buckets of the time drivers spend pending before running")
+	flag.Var(&uiServiceAnnotations, "ui-service-annotations", "Annotations in the form of key=value added to the Services exposing the Spark UI")
+	flag.Var(&uiServiceLabels, "ui-service-labels", "Labels in the form of key=value added to the Services exposing the Spark UI")
+	flag.Var(&ingressAnnotations, "ingress-annotations", "Annotations in the form of key=value added to the Ingresses exposing the Spark UI")
+	flag.Var(&serviceMonitorLabels, "service-monitor-labels", "Labels in the form of key=value added to the ServiceMonitors of applications")
 	flag.Parse()
 
 	// Create the client config. Use kubeConfig if given, otherwise assume in-cluster.
@@ -100,6 +154,7 @@ func main() {
 	config.QPS = kubeClientQPS
 	config.Burst = kubeClientBurst
 	config.Timeout = time.Duration(kubeClientTimeoutSeconds * time.Second)
+	util.InstrumentKubeClientConfig(config)
 
 	kubeClient, err := clientset.NewForConfig(config)
 	if err != nil {
@@ -175,48 +230,201 @@ func main() {
 	var metricConfig *util.MetricConfig
 	if *enableMetrics {
 		metricConfig = &util.MetricConfig{
-			MetricsEndpoint:               *metricsEndpoint,
-			MetricsPort:                   *metricsPort,
-			MetricsPrefix:                 *metricsPrefix,
-			MetricsLabels:                 metricsLabels,
-			MetricsJobStartLatencyBuckets: metricsJobStartLatencyBuckets,
+			MetricsEndpoint:                     *metricsEndpoint,
+			MetricsPort:                         *metricsPort,
+			MetricsPrefix:                       *metricsPrefix,
+			MetricsLabels:                       metricsLabels,
+			MetricsJobStartLatencyBuckets:       metricsJobStartLatencyBuckets,
+			MetricsExecutionTimeBuckets:         metricsExecutionTimeBuckets,
+			MetricsSubmissionJobDurationBuckets: metricsSubmissionJobBuckets,
+			MetricsDriverPendingDurationBuckets: metricsDriverPendingBuckets,
 		}
 
 		glog.Info("Enabling metrics collecting and exporting to Prometheus")
 		util.InitializeMetrics(metricConfig)
 	}
 
+	var logArchiveSink logarchive.Sink
+	if *logArchiveURL != "" {
+		logArchiveSink, err = logarchive.NewSink(context.Background(), *logArchiveURL)
+		if err != nil {
+			glog.Fatalf("failed to initialize the log archive sink: %v", err)
+		}
+		glog.Infof("Archiving logs of driver and executor pods to %s", *logArchiveURL)
+	}
+
+	eventLogConfig := &sparkapplication.EventLogConfig{
+		DefaultDir:             *eventLogDir,
+		DefaultCompress:        *eventLogCompress,
+		HistoryServerURLFormat: *historyServerURLFormat,
+	}
+
+	uiConfig, err := buildSparkUIConfig()
+	if err != nil {
+		glog.Fatal(err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		glog.Fatal(err)
+	}
+	dynamicInformerFactory := buildDynamicInformerFactory(dynamicClient)
+
+	var serviceMonitorConfig *sparkapplication.ServiceMonitorConfig
+	if *enableServiceMonitors {
+		labels, err := serviceMonitorLabels.KeyValuePairs()
+		if err != nil {
+			glog.Fatalf("invalid ServiceMonitor labels: %v", err)
+		}
+		serviceMonitorConfig = &sparkapplication.ServiceMonitorConfig{Labels: labels}
+		glog.Info("Creating ServiceMonitors for applications exposing metrics")
+	}
+
+	var progressPollInterval time.Duration
+	if *enableSparkProgress {
+		if !*enableUIService {
+			glog.Fatal("Spark UI service must be enabled to report the progress of applications.")
+		}
+		if *sparkProgressPollInterval < sparkapplication.MinSparkProgressPollInterval {
+			glog.Fatalf("Spark progress poll interval must be at least %v", sparkapplication.MinSparkProgressPollInterval)
+		}
+		progressPollInterval = *sparkProgressPollInterval
+		glog.Infof("Reporting the progress of running applications every %v", progressPollInterval)
+	}
+
+	var tracer *tracing.Tracer
+	var shutdownTracing func(context.Context) error
+	if *tracingOTLPEndpoint != "" {
+		if *tracingSampleRatio < 0 || *tracingSampleRatio > 1 {
+			glog.Fatalf("tracing sample ratio must be between 0 and 1, got %v", *tracingSampleRatio)
+		}
+		tracerProvider := tracing.NewOTLPTracerProvider(tracing.Config{
+			Endpoint:    *tracingOTLPEndpoint,
+			Insecure:    *tracingOTLPInsecure,
+			ServiceName: *tracingServiceName,
+			SampleRatio: *tracingSampleRatio,
+		})
+		tracer = tracing.NewTracer(tracerProvider)
+		shutdownTracing = tracerProvider.Shutdown
+		glog.Infof("Exporting traces of applications to %s", *tracingOTLPEndpoint)
+	}
+
+	sender := notification.NewSender(*notificationTimeout, *notificationMaxRetries, *notificationRetryInterval)
+	var cloudEventEmitter *notification.CloudEventEmitter
+	if *cloudEventsSinkURL != "" {
+		cloudEventEmitter = notification.NewCloudEventEmitter(sender, *cloudEventsSinkURL,
+			notification.NewDispatcher(*notificationWorkers, *notificationQueueSize))
+		glog.Infof("Emitting CloudEvents to %s", *cloudEventsSinkURL)
+	}
+
+	var coreV1InformerFactory informers.SharedInformerFactory
+	var resourceQuotaEnforcer *resourceusage.ResourceQuotaEnforcer
+	var quotaQueueConfig *sparkapplication.QuotaQueueConfig
+	if *enableResourceQuotaEnforcement {
+		mode := resourceusage.EnforcementMode(*resourceQuotaEnforcementMode)
+		if !mode.IsValid() {
+			glog.Fatalf("unsupported resource quota enforcement mode %s", mode)
+		}
+		coreV1InformerFactory = buildCoreV1InformerFactory(kubeClient)
+		enforcer := resourceusage.NewResourceQuotaEnforcer(crInformerFactory, coreV1InformerFactory, mode)
+		resourceQuotaEnforcer = &enforcer
+		// Namespaces may select the queue mode even if it is not the default, in which case the enforcer only
+		// reports applications of those namespaces as not fitting.
+		ordering := sparkapplication.QueueOrdering(*resourceQuotaQueueOrdering)
+		if ordering != sparkapplication.QueueOrderingFIFO && ordering != sparkapplication.QueueOrderingPriority {
+			glog.Fatalf("unsupported resource quota queue ordering %s", ordering)
+		}
+		quotaQueueConfig = &sparkapplication.QuotaQueueConfig{
+			Checker:         resourceQuotaEnforcer,
+			Ordering:        ordering,
+			RecheckInterval: *resourceQuotaQueueRecheck,
+		}
+		glog.Infof("Enforcing resource quotas in %s mode by default, queueing in %s order", mode, ordering)
+	}
+	var nodeFitChecker *resourceusage.NodeFitChecker
+	if *enableNodeFitCheck {
+		if coreV1InformerFactory == nil {
+			coreV1InformerFactory = buildCoreV1InformerFactory(kubeClient)
+		}
+		checker := resourceusage.NewNodeFitChecker(coreV1InformerFactory)
+		nodeFitChecker = &checker
+		glog.Info("Checking that the driver and executor pods of applications fit on a schedulable node")
+	}
+	var notificationConfig *sparkapplication.NotificationConfig
+	if *enableNotifications {
+		allowedURLs, err := notification.ParseURLAllowlist(*notificationAllowedURLPrefixes)
+		if err != nil {
+			glog.Fatal(err)
+		}
+		if coreV1InformerFactory == nil {
+			coreV1InformerFactory = buildCoreV1InformerFactory(kubeClient)
+		}
+		notificationConfig = &sparkapplication.NotificationConfig{
+			Sender:            sender,
+			NamespaceInformer: coreV1InformerFactory.Core().V1().Namespaces(),
+			AllowedURLs:       allowedURLs,
+			Workers:           *notificationWorkers,
+			QueueSize:         *notificationQueueSize,
+		}
+		glog.Info("Notifying state transitions of applications")
+	}
+
 	applicationController := sparkapplication.NewController(
-		crClient, kubeClient, crInformerFactory, informerFactory, metricConfig, *namespace, *ingressURLFormat, batchSchedulerMgr, *enableUIService)
+		crClient, kubeClient, crInformerFactory, informerFactory, metricConfig, *namespace, *ingressURLFormat, batchSchedulerMgr, *enableUIService,
+		sparkapplication.Options{
+			DriverFailureLogTailLines: *driverFailureLogTailLines,
+			LogArchiveSink:            logArchiveSink,
+			EventLogConfig:            eventLogConfig,
+			EnableUIProxy:             *enableUIProxy,
+			UIConfig:                  uiConfig,
+			DynamicClient:             dynamicClient,
+			DynamicInformerFactory:    dynamicInformerFactory,
+			SparkProgressPollInterval: progressPollInterval,
+			ServiceMonitorConfig:      serviceMonitorConfig,
+			Tracer:                    tracer,
+			NotificationConfig:        notificationConfig,
+			CloudEventEmitter:         cloudEventEmitter,
+			QuotaQueueConfig:          quotaQueueConfig,
+		})
 	scheduledApplicationController := scheduledsparkapplication.NewController(
-		crClient, kubeClient, apiExtensionsClient, crInformerFactory, clock.RealClock{})
+		crClient, kubeClient, apiExtensionsClient, crInformerFactory, clock.RealClock{}, cloudEventEmitter)
 
 	// Start the informer factory that in turn starts the informer.
 	go crInformerFactory.Start(stopCh)
 	go informerFactory.Start(stopCh)
+	go dynamicInformerFactory.Start(stopCh)
+	if coreV1InformerFactory != nil {
+		go coreV1InformerFactory.Start(stopCh)
+	}
+
+	var uiProxy *uiproxy.Proxy
+	if *enableUIProxy {
+		if !*enableUIService {
+			glog.Fatal("Spark UI service must be enabled to use the UI proxy.")
+		}
+		if strings.Contains(*ingressURLFormat, "/") {
+			// Both would set spark.ui.proxyBase, to different paths.
+			glog.Fatal("The UI proxy cannot be used along with an ingress URL format with a path.")
+		}
+		uiProxy = uiproxy.New(crInformerFactory, *uiProxyPort)
+		uiProxy.Start()
+	}
 
 	var hook *webhook.WebHook
 	if *enableWebhook {
-		var coreV1InformerFactory informers.SharedInformerFactory
-		if *enableResourceQuotaEnforcement {
-			coreV1InformerFactory = buildCoreV1InformerFactory(kubeClient)
-		}
 		var err error
 		// Don't deregister webhook on exit if leader election enabled (i.e. multiple webhooks running)
-		hook, err = webhook.New(kubeClient, crInformerFactory, *namespace, !*enableLeaderElection, *enableResourceQuotaEnforcement, coreV1InformerFactory)
+		hook, err = webhook.New(kubeClient, crInformerFactory, *namespace, !*enableLeaderElection, resourceQuotaEnforcer, nodeFitChecker, metricConfig, tracer)
 		if err != nil {
 			glog.Fatal(err)
 		}
 
-		if *enableResourceQuotaEnforcement {
-			go coreV1InformerFactory.Start(stopCh)
-		}
-
 		if err = hook.Start(stopCh); err != nil {
 			glog.Fatal(err)
 		}
 	} else if *enableResourceQuotaEnforcement {
 		glog.Fatal("Webhook must be enabled to use resource quota enforcement.")
+	} else if *enableNodeFitCheck {
+		glog.Fatal("Webhook must be enabled to use the node fit check.")
 	}
 
 	if *enableLeaderElection {
@@ -242,11 +450,53 @@ func main() {
 	glog.Info("Shutting down the Spark Operator")
 	applicationController.Stop()
 	scheduledApplicationController.Stop()
+	if cloudEventEmitter != nil {
+		// Deliver the CloudEvents of the transitions that have already happened.
+		cloudEventEmitter.Stop()
+	}
+	if *enableUIProxy {
+		if err := uiProxy.Stop(); err != nil {
+			glog.Error(err)
+		}
+	}
 	if *enableWebhook {
 		if err := hook.Stop(); err != nil {
 			glog.Fatal(err)
 		}
 	}
+	if shutdownTracing != nil {
+		// Flush the spans yet to be exported.
+		if err := shutdownTracing(context.Background()); err != nil {
+			glog.Error(err)
+		}
+	}
+}
+
+func buildSparkUIConfig() (*sparkapplication.SparkUIConfig, error) {
+	if *ingressAPIVersion != sparkapplication.IngressAPIVersionExtensionsV1beta1 && *ingressAPIVersion != sparkapplication.IngressAPIVersionNetworkingV1 {
+		return nil, fmt.Errorf("unsupported Ingress API version %s", *ingressAPIVersion)
+	}
+	serviceAnnotations, err := uiServiceAnnotations.KeyValuePairs()
+	if err != nil {
+		return nil, fmt.Errorf("invalid Spark UI service annotations: %v", err)
+	}
+	serviceLabels, err := uiServiceLabels.KeyValuePairs()
+	if err != nil {
+		return nil, fmt.Errorf("invalid Spark UI service labels: %v", err)
+	}
+	annotations, err := ingressAnnotations.KeyValuePairs()
+	if err != nil {
+		return nil, fmt.Errorf("invalid Spark UI ingress annotations: %v", err)
+	}
+	return &sparkapplication.SparkUIConfig{
+		ServiceType:          apiv1.ServiceType(*uiServiceType),
+		ServiceAnnotations:   serviceAnnotations,
+		ServiceLabels:        serviceLabels,
+		IngressClassName:     *ingressClassName,
+		IngressAnnotations:   annotations,
+		IngressTLSSecretName: *ingressTLSSecretName,
+		IngressAPIVersion:    *ingressAPIVersion,
+	}, nil
 }
 
 func buildConfig(masterURL string, kubeConfig string) (*rest.Config, error) {
@@ -280,6 +530,13 @@ func buildInformerFactory(kubeClient clientset.Interface) informers.SharedInform
 	return informers.NewSharedInformerFactoryWithOptions(kubeClient, time.Duration(*resyncInterval)*time.Second, factoryOpts...)
 }
 
+func buildDynamicInformerFactory(dynamicClient dynamic.Interface) dynamicinformer.DynamicSharedInformerFactory {
+	tweakListOptionsFunc := func(options *metav1.ListOptions) {
+		options.LabelSelector = operatorConfig.LaunchedBySparkOperatorLabel
+	}
+	return dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicClient, time.Duration(*resyncInterval)*time.Second, *namespace, tweakListOptionsFunc)
+}
+
 func buildCoreV1InformerFactory(kubeClient clientset.Interface) informers.SharedInformerFactory {
 	var coreV1FactoryOpts []informers.SharedInformerOption
 	if *namespace != apiv1.NamespaceAll {
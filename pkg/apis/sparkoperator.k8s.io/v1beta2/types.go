@@ -261,6 +261,13 @@ type SparkApplicationSpec struct {
 	// Monitoring configures how monitoring is handled.
 	// +optional
 	Monitoring *MonitoringSpec `json:"monitoring,omitempty"`
+	// EventLog configures Spark event logging, which allows the application to be viewed in the Spark
+	// History Server after it finishes.
+	// +optional
+	EventLog *EventLogSpec `json:"eventLog,omitempty"`
+	// SparkUIOptions allows configuring the Service and the Ingress exposing the Spark UI.
+	// +optional
+	SparkUIOptions *SparkUIConfiguration `json:"sparkUIOptions,omitempty"`
 	// BatchScheduler configures which batch scheduler will be used for scheduling
 	// +optional
 	BatchScheduler *string `json:"batchScheduler,omitempty"`
@@ -287,6 +294,10 @@ type BatchSchedulerConfiguration struct {
 	// PriorityClassName stands for the name of k8s PriorityClass resource, it's being used in Volcano batch scheduler.
 	// +optional
 	PriorityClassName *string `json:"priorityClassName,omitempty"`
+	// ScheduleTimeoutSeconds is the maximum time to wait for the pods of the application to be scheduled together,
+	// it's being used in the coscheduling batch scheduler.
+	// +optional
+	ScheduleTimeoutSeconds *int32 `json:"scheduleTimeoutSeconds,omitempty"`
 }
 
 // ApplicationStateType represents the type of the current state of an application.
@@ -302,6 +313,7 @@ const (
 	CompletedState         ApplicationStateType = "COMPLETED"          // Application completed.
 	FailedState            ApplicationStateType = "FAILED"             // Application failed or submission job failed.
 	PendingRerunState      ApplicationStateType = "PENDING_RERUN"      // Application is pending being rerun.
+	QueuedState            ApplicationStateType = "QUEUED"             // Application is queued until it fits into the resource quotas.
 	InvalidatingState      ApplicationStateType = "INVALIDATING"       // Application spec has been updated and re-run is due.
 	SucceedingState        ApplicationStateType = "SUCCEEDING"         // Application succeeded but might be subject to restart.
 	FailingState           ApplicationStateType = "FAILING"            // Application failed but might be subject to restart.
@@ -312,6 +324,9 @@ const (
 type ApplicationState struct {
 	State        ApplicationStateType `json:"state"`
 	ErrorMessage string               `json:"errorMessage,omitempty"`
+	// LastTransitionTime is the time the application entered the current state.
+	// +nullable
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
 }
 
 // DriverState tells the current state of a spark driver.
@@ -344,6 +359,10 @@ type SparkApplicationStatus struct {
 	SparkApplicationID string `json:"sparkApplicationId,omitempty"`
 	// SubmissionID is a unique ID of the current submission of the application.
 	SubmissionID string `json:"submissionID,omitempty"`
+	// LastSubmissionAttemptTime is the time of the last attempt to submit the application, which starts its
+	// current run.
+	// +nullable
+	LastSubmissionAttemptTime metav1.Time `json:"lastSubmissionAttemptTime,omitempty"`
 	// SubmissionTime is the time the application is submitted.
 	// +nullable
 	SubmissionTime metav1.Time `json:"submissionTime,omitempty"`
@@ -362,6 +381,22 @@ type SparkApplicationStatus struct {
 	// SubmissionAttempts is the total number of attempts to submit an application to run.
 	// Incremented upon each attempted submission of the application and reset upon invalidation and rerun.
 	SubmissionAttempts int32 `json:"submissionAttempts,omitempty"`
+	// DriverFailureSnapshot references the driver log tail and termination details captured when the
+	// driver container exited with a non-zero exit code. Only set if driver failure capturing is enabled.
+	// +optional
+	DriverFailureSnapshot *DriverFailureSnapshot `json:"driverFailureSnapshot,omitempty"`
+	// LogArchiveLocations maps names of driver and executor pods of the current run to the locations their logs
+	// have been archived to. Only set if log archiving is enabled.
+	// +optional
+	LogArchiveLocations map[string]string `json:"logArchiveLocations,omitempty"`
+	// SparkProgress reports the progress of jobs, stages and tasks of the current run as reported by the REST
+	// API of the driver. Only set if progress reporting is enabled.
+	// +optional
+	SparkProgress *SparkProgress `json:"sparkProgress,omitempty"`
+	// QueueStatus reports the position in the queue of its namespace and the reason of an application queued
+	// until it fits into the resource quotas of the namespace. Only set while the application is queued.
+	// +optional
+	QueueStatus *QueueStatus `json:"queueStatus,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -549,6 +584,63 @@ type DriverInfo struct {
 	WebUIIngressName    string `json:"webUIIngressName,omitempty"`
 	WebUIIngressAddress string `json:"webUIIngressAddress,omitempty"`
 	PodName             string `json:"podName,omitempty"`
+	// HistoryServerURL is the URL of the application in the Spark History Server. It is set once the
+	// application finishes if event logging is enabled and a history server URL format is configured.
+	HistoryServerURL string `json:"historyServerURL,omitempty"`
+}
+
+// QueueStatus captures the state of an application queued until it fits into the resource quotas of its namespace.
+type QueueStatus struct {
+	// Position is the 1-based position of the application in the queue of its namespace.
+	Position int32 `json:"position"`
+	// Reason tells why the application is queued.
+	Reason string `json:"reason,omitempty"`
+	// QueuedTime is the time the application was queued.
+	// +nullable
+	QueuedTime metav1.Time `json:"queuedTime,omitempty"`
+}
+
+// SparkProgress captures the progress of a running application as reported by the Spark REST API.
+type SparkProgress struct {
+	// ActiveJobs is the number of running jobs.
+	ActiveJobs int32 `json:"activeJobs"`
+	// CompletedJobs is the number of jobs that succeeded.
+	CompletedJobs int32 `json:"completedJobs"`
+	// FailedJobs is the number of jobs that failed.
+	FailedJobs int32 `json:"failedJobs"`
+	// ActiveStages is the number of running stages.
+	ActiveStages int32 `json:"activeStages"`
+	// CompletedStages is the number of stages that completed.
+	CompletedStages int32 `json:"completedStages"`
+	// FailedStages is the number of stages that failed.
+	FailedStages int32 `json:"failedStages"`
+	// ActiveTasks is the number of running tasks.
+	ActiveTasks int32 `json:"activeTasks"`
+	// CompletedTasks is the number of tasks that completed.
+	CompletedTasks int32 `json:"completedTasks"`
+	// FailedTasks is the number of tasks that failed.
+	FailedTasks int32 `json:"failedTasks"`
+	// ActiveExecutors is the number of active executors, not counting the driver.
+	ActiveExecutors int32 `json:"activeExecutors"`
+	// LastUpdateTime is the time the progress was last queried from the driver.
+	// +nullable
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// DriverFailureSnapshot captures information about a driver container that exited with a non-zero exit code.
+type DriverFailureSnapshot struct {
+	// ConfigMapName is the name of the ConfigMap holding the driver log tail and the container termination message.
+	ConfigMapName string `json:"configMapName"`
+	// PodName is the name of the driver pod the snapshot was taken from.
+	PodName string `json:"podName"`
+	// ExitCode is the exit code of the driver container.
+	ExitCode int32 `json:"exitCode"`
+	// Reason is the reason of the driver container termination as reported by Kubernetes.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// CaptureTime is the time when the snapshot was taken.
+	// +nullable
+	CaptureTime metav1.Time `json:"captureTime,omitempty"`
 }
 
 // SecretInfo captures information of a secret.
@@ -581,6 +673,59 @@ type MonitoringSpec struct {
 	// Prometheus is for configuring the Prometheus JMX exporter.
 	// +optional
 	Prometheus *PrometheusSpec `json:"prometheus,omitempty"`
+	// PrometheusServlet is for exposing metrics through the PrometheusServlet built into Spark 3.0 and above,
+	// as an alternative to the Prometheus JMX exporter. It must not be set together with Prometheus.
+	// +optional
+	PrometheusServlet *PrometheusServletSpec `json:"prometheusServlet,omitempty"`
+}
+
+// EventLogSpec defines the Spark event logging specification. Fields that are not set default to the
+// operator-level event logging defaults.
+type EventLogSpec struct {
+	// Enabled specifies whether event logging is enabled. Defaults to true.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+	// Dir is the base directory event logs are written to, e.g., gs://bucket/spark-events.
+	// +optional
+	Dir *string `json:"dir,omitempty"`
+	// Compress specifies whether to compress event logs.
+	// +optional
+	Compress *bool `json:"compress,omitempty"`
+}
+
+// SparkUIConfiguration configures how the Spark UI of the driver is exposed. Fields that are not set default
+// to the operator-level settings.
+type SparkUIConfiguration struct {
+	// ServiceType is the type of the Service exposing the Spark UI. Defaults to ClusterIP.
+	// +optional
+	ServiceType *apiv1.ServiceType `json:"serviceType,omitempty"`
+	// ServiceAnnotations are annotations added to the Service exposing the Spark UI, in addition to the
+	// operator-level annotations.
+	// +optional
+	ServiceAnnotations map[string]string `json:"serviceAnnotations,omitempty"`
+	// ServiceLabels are labels added to the Service exposing the Spark UI, in addition to the operator-level labels.
+	// +optional
+	ServiceLabels map[string]string `json:"serviceLabels,omitempty"`
+	// IngressClassName is the class of the Ingress exposing the Spark UI.
+	// +optional
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+	// IngressAnnotations are annotations added to the Ingress exposing the Spark UI, in addition to the
+	// operator-level annotations.
+	// +optional
+	IngressAnnotations map[string]string `json:"ingressAnnotations,omitempty"`
+	// IngressTLS is the TLS configuration of the Ingress exposing the Spark UI.
+	// +optional
+	IngressTLS []IngressTLS `json:"ingressTLS,omitempty"`
+}
+
+// IngressTLS describes the TLS configuration of the Ingress exposing the Spark UI.
+type IngressTLS struct {
+	// Hosts is the list of hosts included in the TLS certificate. Defaults to the host of the Ingress.
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
+	// SecretName is the name of the Secret holding the TLS certificate and key.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
 }
 
 // PrometheusSpec defines the Prometheus specification when Prometheus is to be used for
@@ -605,6 +750,14 @@ type PrometheusSpec struct {
 	Configuration *string `json:"configuration,omitempty"`
 }
 
+// PrometheusServletSpec defines the specification for exposing metrics through the PrometheusServlet of Spark.
+type PrometheusServletSpec struct {
+	// Path is the path the driver serves its metrics at in the Prometheus format.
+	// If not specified, /metrics/prometheus will be used as the default.
+	// +optional
+	Path *string `json:"path,omitempty"`
+}
+
 type GPUSpec struct {
 	// Name is GPU resource name, such as: nvidia.com/gpu or amd.com/gpu
 	Name string `json:"name"`
@@ -617,6 +770,11 @@ func (s *SparkApplication) PrometheusMonitoringEnabled() bool {
 	return s.Spec.Monitoring != nil && s.Spec.Monitoring.Prometheus != nil
 }
 
+// PrometheusServletMonitoringEnabled returns if monitoring through the PrometheusServlet is enabled or not.
+func (s *SparkApplication) PrometheusServletMonitoringEnabled() bool {
+	return s.Spec.Monitoring != nil && s.Spec.Monitoring.PrometheusServlet != nil
+}
+
 // HasPrometheusConfigFile returns if Prometheus monitoring uses a configruation file in the container.
 func (s *SparkApplication) HasPrometheusConfigFile() bool {
 	return s.PrometheusMonitoringEnabled() &&
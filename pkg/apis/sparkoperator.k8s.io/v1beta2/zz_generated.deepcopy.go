@@ -30,6 +30,7 @@ import (
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApplicationState) DeepCopyInto(out *ApplicationState) {
 	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
 	return
 }
 
@@ -56,6 +57,11 @@ func (in *BatchSchedulerConfiguration) DeepCopyInto(out *BatchSchedulerConfigura
 		*out = new(string)
 		**out = **in
 	}
+	if in.ScheduleTimeoutSeconds != nil {
+		in, out := &in.ScheduleTimeoutSeconds, &out.ScheduleTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
 	return
 }
 
@@ -100,6 +106,23 @@ func (in *Dependencies) DeepCopy() *Dependencies {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriverFailureSnapshot) DeepCopyInto(out *DriverFailureSnapshot) {
+	*out = *in
+	in.CaptureTime.DeepCopyInto(&out.CaptureTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriverFailureSnapshot.
+func (in *DriverFailureSnapshot) DeepCopy() *DriverFailureSnapshot {
+	if in == nil {
+		return nil
+	}
+	out := new(DriverFailureSnapshot)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DriverInfo) DeepCopyInto(out *DriverInfo) {
 	*out = *in
@@ -158,6 +181,37 @@ func (in *DriverSpec) DeepCopy() *DriverSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EventLogSpec) DeepCopyInto(out *EventLogSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Dir != nil {
+		in, out := &in.Dir, &out.Dir
+		*out = new(string)
+		**out = **in
+	}
+	if in.Compress != nil {
+		in, out := &in.Compress, &out.Compress
+		*out = new(bool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventLogSpec.
+func (in *EventLogSpec) DeepCopy() *EventLogSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EventLogSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExecutorSpec) DeepCopyInto(out *ExecutorSpec) {
 	*out = *in
@@ -211,6 +265,27 @@ func (in *GPUSpec) DeepCopy() *GPUSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressTLS) DeepCopyInto(out *IngressTLS) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressTLS.
+func (in *IngressTLS) DeepCopy() *IngressTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MonitoringSpec) DeepCopyInto(out *MonitoringSpec) {
 	*out = *in
@@ -229,6 +304,11 @@ func (in *MonitoringSpec) DeepCopyInto(out *MonitoringSpec) {
 		*out = new(PrometheusSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.PrometheusServlet != nil {
+		in, out := &in.PrometheusServlet, &out.PrometheusServlet
+		*out = new(PrometheusServletSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -274,6 +354,27 @@ func (in *NamePath) DeepCopy() *NamePath {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusServletSpec) DeepCopyInto(out *PrometheusServletSpec) {
+	*out = *in
+	if in.Path != nil {
+		in, out := &in.Path, &out.Path
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusServletSpec.
+func (in *PrometheusServletSpec) DeepCopy() *PrometheusServletSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusServletSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PrometheusSpec) DeepCopyInto(out *PrometheusSpec) {
 	*out = *in
@@ -305,6 +406,23 @@ func (in *PrometheusSpec) DeepCopy() *PrometheusSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QueueStatus) DeepCopyInto(out *QueueStatus) {
+	*out = *in
+	in.QueuedTime.DeepCopyInto(&out.QueuedTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QueueStatus.
+func (in *QueueStatus) DeepCopy() *QueueStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(QueueStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RestartPolicy) DeepCopyInto(out *RestartPolicy) {
 	*out = *in
@@ -639,6 +757,16 @@ func (in *SparkApplicationSpec) DeepCopyInto(out *SparkApplicationSpec) {
 		*out = new(MonitoringSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.EventLog != nil {
+		in, out := &in.EventLog, &out.EventLog
+		*out = new(EventLogSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SparkUIOptions != nil {
+		in, out := &in.SparkUIOptions, &out.SparkUIOptions
+		*out = new(SparkUIConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.BatchScheduler != nil {
 		in, out := &in.BatchScheduler, &out.BatchScheduler
 		*out = new(string)
@@ -675,10 +803,11 @@ func (in *SparkApplicationSpec) DeepCopy() *SparkApplicationSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SparkApplicationStatus) DeepCopyInto(out *SparkApplicationStatus) {
 	*out = *in
+	in.LastSubmissionAttemptTime.DeepCopyInto(&out.LastSubmissionAttemptTime)
 	in.SubmissionTime.DeepCopyInto(&out.SubmissionTime)
 	in.TerminationTime.DeepCopyInto(&out.TerminationTime)
 	out.DriverInfo = in.DriverInfo
-	out.AppState = in.AppState
+	in.AppState.DeepCopyInto(&out.AppState)
 	if in.ExecutorState != nil {
 		in, out := &in.ExecutorState, &out.ExecutorState
 		*out = make(map[string]ExecutorState, len(*in))
@@ -686,6 +815,28 @@ func (in *SparkApplicationStatus) DeepCopyInto(out *SparkApplicationStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.DriverFailureSnapshot != nil {
+		in, out := &in.DriverFailureSnapshot, &out.DriverFailureSnapshot
+		*out = new(DriverFailureSnapshot)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LogArchiveLocations != nil {
+		in, out := &in.LogArchiveLocations, &out.LogArchiveLocations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SparkProgress != nil {
+		in, out := &in.SparkProgress, &out.SparkProgress
+		*out = new(SparkProgress)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.QueueStatus != nil {
+		in, out := &in.QueueStatus, &out.QueueStatus
+		*out = new(QueueStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -861,3 +1012,74 @@ func (in *SparkPodSpec) DeepCopy() *SparkPodSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SparkProgress) DeepCopyInto(out *SparkProgress) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SparkProgress.
+func (in *SparkProgress) DeepCopy() *SparkProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(SparkProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SparkUIConfiguration) DeepCopyInto(out *SparkUIConfiguration) {
+	*out = *in
+	if in.ServiceType != nil {
+		in, out := &in.ServiceType, &out.ServiceType
+		*out = new(v1.ServiceType)
+		**out = **in
+	}
+	if in.ServiceAnnotations != nil {
+		in, out := &in.ServiceAnnotations, &out.ServiceAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ServiceLabels != nil {
+		in, out := &in.ServiceLabels, &out.ServiceLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.IngressClassName != nil {
+		in, out := &in.IngressClassName, &out.IngressClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.IngressAnnotations != nil {
+		in, out := &in.IngressAnnotations, &out.IngressAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.IngressTLS != nil {
+		in, out := &in.IngressTLS, &out.IngressTLS
+		*out = make([]IngressTLS, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SparkUIConfiguration.
+func (in *SparkUIConfiguration) DeepCopy() *SparkUIConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(SparkUIConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package coscheduling
+
+import (
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	schedulerinterface "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/batchscheduler/interface"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/webhook/resourceusage"
+)
+
+const (
+	PodGroupName = "podgroups.scheduling.sigs.k8s.io"
+	// PodGroupLabel is the label the coscheduling plugin finds the PodGroup of a pod by.
+	PodGroupLabel = "pod-group.scheduling.sigs.k8s.io"
+)
+
+// PodGroupResource is the resource of PodGroups of the coscheduling plugin of scheduler-plugins, which are managed
+// through the dynamic client so that the operator does not depend on the scheduler-plugins API.
+var PodGroupResource = schema.GroupVersionResource{
+	Group:    "scheduling.sigs.k8s.io",
+	Version:  "v1alpha1",
+	Resource: "podgroups",
+}
+
+type CoschedulingBatchScheduler struct {
+	dynamicClient dynamic.Interface
+}
+
+func GetPluginName() string {
+	return "coscheduling"
+}
+
+func (c *CoschedulingBatchScheduler) Name() string {
+	return GetPluginName()
+}
+
+func (c *CoschedulingBatchScheduler) ShouldSchedule(app *v1beta2.SparkApplication) bool {
+	//NOTE: There is no additional requirement for coscheduling scheduler
+	return true
+}
+
+func (c *CoschedulingBatchScheduler) DoBatchSchedulingOnSubmission(app *v1beta2.SparkApplication) error {
+	if app.Spec.Driver.Labels == nil {
+		app.Spec.Driver.Labels = make(map[string]string)
+	}
+	if app.Spec.Executor.Labels == nil {
+		app.Spec.Executor.Labels = make(map[string]string)
+	}
+
+	// Pods are scheduled by the scheduler profile named after the plugin, unless they are given the name of
+	// another profile running the coscheduling plugin.
+	schedulerName := GetPluginName()
+	if app.Spec.Driver.SchedulerName == nil {
+		app.Spec.Driver.SchedulerName = &schedulerName
+	}
+	if app.Spec.Executor.SchedulerName == nil {
+		app.Spec.Executor.SchedulerName = &schedulerName
+	}
+
+	podGroupName := getAppPodGroupName(app)
+	// The driver runs in the submission pod rather than in a pod of its own in client mode, so only the executors
+	// are considered then.
+	minResources, err := getExecutorRequestResource(app)
+	if err != nil {
+		return err
+	}
+	if app.Spec.Mode != v1beta2.ClientMode {
+		driverResources, err := resourceusage.SparkPodRequests(app.Spec.Driver.SparkPodSpec, app.Spec.Driver.CoreRequest, app.Spec.MemoryOverheadFactor, app.Spec.Type)
+		if err != nil {
+			return fmt.Errorf("failed to compute the resources of the driver: %v", err)
+		}
+		addResourceList(minResources, driverResources)
+	}
+
+	//NOTE: The size of the PodGroup is 1 as the executors are only created once the driver runs, so waiting for
+	//them before scheduling the driver would never end. The PodGroup is only scheduled if the resources of all of
+	//the pods are available though.
+	if err := c.syncPodGroup(app, podGroupName, 1, minResources); err != nil {
+		return err
+	}
+	if app.Spec.Mode != v1beta2.ClientMode {
+		app.Spec.Driver.Labels[PodGroupLabel] = podGroupName
+	}
+	app.Spec.Executor.Labels[PodGroupLabel] = podGroupName
+	return nil
+}
+
+func getAppPodGroupName(app *v1beta2.SparkApplication) string {
+	return fmt.Sprintf("spark-%s-pg", app.Name)
+}
+
+func (c *CoschedulingBatchScheduler) syncPodGroup(app *v1beta2.SparkApplication, name string, size int64, minResources corev1.ResourceList) error {
+	podGroup := buildPodGroup(app, name, size, minResources)
+	client := c.dynamicClient.Resource(PodGroupResource).Namespace(app.Namespace)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := client.Get(name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			_, createErr := client.Create(podGroup, metav1.CreateOptions{})
+			return createErr
+		}
+		if err != nil {
+			return err
+		}
+
+		if reflect.DeepEqual(existing.Object["spec"], podGroup.Object["spec"]) {
+			return nil
+		}
+		existing.Object["spec"] = podGroup.Object["spec"]
+		_, updateErr := client.Update(existing, metav1.UpdateOptions{})
+		return updateErr
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sync PodGroup with error: %s. Abandon schedule pods via coscheduling", err)
+	}
+	return nil
+}
+
+func (c *CoschedulingBatchScheduler) OnSpecInvalidation(app *v1beta2.SparkApplication) error {
+	//NOTE: The PodGroup is synced with the new spec on resubmission.
+	return nil
+}
+
+func (c *CoschedulingBatchScheduler) OnRerun(app *v1beta2.SparkApplication) error {
+	return nil
+}
+
+func (c *CoschedulingBatchScheduler) OnTermination(app *v1beta2.SparkApplication) error {
+	return c.deletePodGroup(app)
+}
+
+func (c *CoschedulingBatchScheduler) OnDeletion(app *v1beta2.SparkApplication) error {
+	return c.deletePodGroup(app)
+}
+
+func (c *CoschedulingBatchScheduler) deletePodGroup(app *v1beta2.SparkApplication) error {
+	err := c.dynamicClient.Resource(PodGroupResource).Namespace(app.Namespace).Delete(getAppPodGroupName(app), &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete PodGroup with error: %s", err)
+	}
+	return nil
+}
+
+func buildPodGroup(app *v1beta2.SparkApplication, name string, size int64, minResources corev1.ResourceList) *unstructured.Unstructured {
+	resources := make(map[string]interface{})
+	for resourceName, quantity := range minResources {
+		resources[string(resourceName)] = quantity.String()
+	}
+	spec := map[string]interface{}{
+		"minMember":    size,
+		"minResources": resources,
+	}
+	if app.Spec.BatchSchedulerOptions != nil && app.Spec.BatchSchedulerOptions.ScheduleTimeoutSeconds != nil {
+		spec["scheduleTimeoutSeconds"] = int64(*app.Spec.BatchSchedulerOptions.ScheduleTimeoutSeconds)
+	}
+
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": spec,
+		},
+	}
+	obj.SetAPIVersion(PodGroupResource.GroupVersion().String())
+	obj.SetKind("PodGroup")
+	obj.SetName(name)
+	obj.SetNamespace(app.Namespace)
+	obj.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(app, v1beta2.SchemeGroupVersion.WithKind("SparkApplication")),
+	})
+	return obj
+}
+
+func New(config *rest.Config) (schedulerinterface.BatchScheduler, error) {
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize dynamic client with error %v", err)
+	}
+	extClient, err := apiextensionsclient.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize k8s extension client with error %v", err)
+	}
+
+	if _, err := extClient.ApiextensionsV1beta1().CustomResourceDefinitions().Get(
+		PodGroupName, metav1.GetOptions{}); err != nil {
+		return nil, fmt.Errorf("podGroup CRD is required to exists in current cluster error: %s", err)
+	}
+	return &CoschedulingBatchScheduler{dynamicClient: dynamicClient}, nil
+}
+
+func getExecutorRequestResource(app *v1beta2.SparkApplication) (corev1.ResourceList, error) {
+	executorResources, err := resourceusage.SparkPodRequests(app.Spec.Executor.SparkPodSpec, app.Spec.Executor.CoreRequest, app.Spec.MemoryOverheadFactor, app.Spec.Type)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute the resources of the executors: %v", err)
+	}
+	var instances int64 = 1
+	if app.Spec.Executor.Instances != nil {
+		instances = int64(*app.Spec.Executor.Instances)
+	}
+	minResources := corev1.ResourceList{}
+	for name, quantity := range executorResources {
+		if quantity.MilliValue()%1000 == 0 {
+			minResources[name] = *resource.NewQuantity(quantity.Value()*instances, quantity.Format)
+		} else {
+			minResources[name] = *resource.NewMilliQuantity(quantity.MilliValue()*instances, quantity.Format)
+		}
+	}
+	return minResources, nil
+}
+
+func addResourceList(total corev1.ResourceList, list corev1.ResourceList) {
+	for name, quantity := range list {
+		if value, ok := total[name]; !ok {
+			total[name] = quantity.DeepCopy()
+		} else {
+			value.Add(quantity)
+			total[name] = value
+		}
+	}
+}
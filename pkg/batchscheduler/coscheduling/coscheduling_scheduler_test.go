@@ -0,0 +1,274 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package coscheduling
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func TestDoBatchSchedulingOnSubmission(t *testing.T) {
+
+	oneCore := int32(1)
+	oneGB := "1g"
+	twoGB := "2g"
+	halfGB := "512m"
+	twoInstances := int32(2)
+	timeout := int32(60)
+
+	testcases := []struct {
+		Name                   string
+		mode                   v1beta2.DeployMode
+		options                *v1beta2.BatchSchedulerConfiguration
+		minResources           v1.ResourceList
+		scheduleTimeoutSeconds int64
+	}{
+		{
+			Name:    "Validate cluster mode",
+			mode:    v1beta2.ClusterMode,
+			options: &v1beta2.BatchSchedulerConfiguration{ScheduleTimeoutSeconds: &timeout},
+			minResources: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("3"),
+				v1.ResourceMemory: resource.MustParse("7680Mi"),
+			},
+			scheduleTimeoutSeconds: 60,
+		},
+		{
+			Name: "Validate client mode",
+			mode: v1beta2.ClientMode,
+			minResources: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("6Gi"),
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			app := &v1beta2.SparkApplication{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "default",
+					UID:       "foo-123",
+				},
+				Spec: v1beta2.SparkApplicationSpec{
+					Mode:                  testcase.mode,
+					BatchSchedulerOptions: testcase.options,
+					Driver: v1beta2.DriverSpec{
+						SparkPodSpec: v1beta2.SparkPodSpec{
+							Cores:          &oneCore,
+							Memory:         &oneGB,
+							MemoryOverhead: &halfGB,
+						},
+					},
+					Executor: v1beta2.ExecutorSpec{
+						SparkPodSpec: v1beta2.SparkPodSpec{
+							Cores:          &oneCore,
+							Memory:         &twoGB,
+							MemoryOverhead: &oneGB,
+						},
+						Instances: &twoInstances,
+					},
+				},
+			}
+			dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+			scheduler := &CoschedulingBatchScheduler{dynamicClient: dynamicClient}
+			if err := scheduler.DoBatchSchedulingOnSubmission(app); err != nil {
+				t.Fatal(err)
+			}
+
+			podGroupName := "spark-foo-pg"
+			if testcase.mode == v1beta2.ClientMode {
+				if _, ok := app.Spec.Driver.Labels[PodGroupLabel]; ok {
+					t.Errorf("expecting driver pod not to be in a PodGroup in client mode")
+				}
+			} else if app.Spec.Driver.Labels[PodGroupLabel] != podGroupName {
+				t.Errorf("expecting driver pod to be in PodGroup %s, while get %q", podGroupName, app.Spec.Driver.Labels[PodGroupLabel])
+			}
+			if app.Spec.Executor.Labels[PodGroupLabel] != podGroupName {
+				t.Errorf("expecting executor pods to be in PodGroup %s, while get %q", podGroupName, app.Spec.Executor.Labels[PodGroupLabel])
+			}
+			if *app.Spec.Driver.SchedulerName != GetPluginName() || *app.Spec.Executor.SchedulerName != GetPluginName() {
+				t.Errorf("expecting pods to be scheduled by %s, while get %s and %s", GetPluginName(), *app.Spec.Driver.SchedulerName, *app.Spec.Executor.SchedulerName)
+			}
+
+			podGroup, err := dynamicClient.Resource(PodGroupResource).Namespace("default").Get(podGroupName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if owners := podGroup.GetOwnerReferences(); len(owners) != 1 || owners[0].Name != "foo" {
+				t.Errorf("expecting PodGroup to be owned by the application, while get %v", owners)
+			}
+			if minMember, _, _ := unstructured.NestedInt64(podGroup.Object, "spec", "minMember"); minMember != 1 {
+				t.Errorf("expecting PodGroup to have 1 min member, while get %d", minMember)
+			}
+			if timeout, _, _ := unstructured.NestedInt64(podGroup.Object, "spec", "scheduleTimeoutSeconds"); timeout != testcase.scheduleTimeoutSeconds {
+				t.Errorf("expecting PodGroup to have a schedule timeout of %d seconds, while get %d", testcase.scheduleTimeoutSeconds, timeout)
+			}
+			assertMinResources(t, podGroup, testcase.minResources)
+
+			// Resubmitting the application with more executors updates the existing PodGroup.
+			threeInstances := int32(3)
+			app.Spec.Executor.Instances = &threeInstances
+			if err := scheduler.DoBatchSchedulingOnSubmission(app); err != nil {
+				t.Fatal(err)
+			}
+			podGroup, err = dynamicClient.Resource(PodGroupResource).Namespace("default").Get(podGroupName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			cpu := testcase.minResources[v1.ResourceCPU]
+			cpu.Add(resource.MustParse("1"))
+			memory := testcase.minResources[v1.ResourceMemory]
+			memory.Add(resource.MustParse("3Gi"))
+			assertMinResources(t, podGroup, v1.ResourceList{v1.ResourceCPU: cpu, v1.ResourceMemory: memory})
+		})
+	}
+}
+
+func TestDoBatchSchedulingOnSubmissionWithSchedulerProfile(t *testing.T) {
+	profile := "scheduler-plugins-scheduler"
+	coreRequest := "500m"
+	threeInstances := int32(3)
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			UID:       "foo-123",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Type: v1beta2.JavaApplicationType,
+			Mode: v1beta2.ClientMode,
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{SchedulerName: &profile},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{SchedulerName: &profile},
+				CoreRequest:  &coreRequest,
+				Instances:    &threeInstances,
+			},
+		},
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	scheduler := &CoschedulingBatchScheduler{dynamicClient: dynamicClient}
+	if err := scheduler.DoBatchSchedulingOnSubmission(app); err != nil {
+		t.Fatal(err)
+	}
+
+	// The scheduler profiles set on the pods are kept.
+	if *app.Spec.Driver.SchedulerName != profile || *app.Spec.Executor.SchedulerName != profile {
+		t.Errorf("expecting pods to be scheduled by %s, while get %s and %s", profile, *app.Spec.Driver.SchedulerName, *app.Spec.Executor.SchedulerName)
+	}
+	podGroup, err := dynamicClient.Resource(PodGroupResource).Namespace("default").Get("spark-foo-pg", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Fractional core requests are multiplied by the number of executors.
+	assertMinResources(t, podGroup, v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("1500m"),
+		v1.ResourceMemory: resource.MustParse("4224Mi"),
+	})
+}
+
+func assertMinResources(t *testing.T, podGroup *unstructured.Unstructured, expected v1.ResourceList) {
+	minResources, _, _ := unstructured.NestedStringMap(podGroup.Object, "spec", "minResources")
+	if len(minResources) != len(expected) {
+		t.Errorf("expecting PodGroup to have min resources %v, while get %v", expected, minResources)
+	}
+	for name, quantity := range expected {
+		if value, ok := minResources[string(name)]; !ok {
+			t.Errorf("expecting PodGroup to have resource %s, while get none", name)
+		} else if actual := resource.MustParse(value); quantity.Cmp(actual) != 0 {
+			t.Errorf("expecting PodGroup to have resource %s with value %s, while get %s",
+				name, quantity.String(), actual.String())
+		}
+	}
+}
+
+func TestPodGroupHooks(t *testing.T) {
+	// The PodGroup is synced with the spec on resubmission, so it is only deleted once the application is over.
+	testcases := []struct {
+		Name          string
+		hook          func(scheduler *CoschedulingBatchScheduler, app *v1beta2.SparkApplication) error
+		expectDeleted bool
+	}{
+		{
+			Name:          "OnSpecInvalidation",
+			hook:          (*CoschedulingBatchScheduler).OnSpecInvalidation,
+			expectDeleted: false,
+		},
+		{
+			Name:          "OnRerun",
+			hook:          (*CoschedulingBatchScheduler).OnRerun,
+			expectDeleted: false,
+		},
+		{
+			Name:          "OnTermination",
+			hook:          (*CoschedulingBatchScheduler).OnTermination,
+			expectDeleted: true,
+		},
+		{
+			Name:          "OnDeletion",
+			hook:          (*CoschedulingBatchScheduler).OnDeletion,
+			expectDeleted: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			app := &v1beta2.SparkApplication{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "default",
+					UID:       "foo-123",
+				},
+				Spec: v1beta2.SparkApplicationSpec{
+					Mode: v1beta2.ClusterMode,
+				},
+			}
+			dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+			scheduler := &CoschedulingBatchScheduler{dynamicClient: dynamicClient}
+
+			// An application without a PodGroup is handled fine.
+			if err := testcase.hook(scheduler, app); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := scheduler.DoBatchSchedulingOnSubmission(app); err != nil {
+				t.Fatal(err)
+			}
+			if err := testcase.hook(scheduler, app); err != nil {
+				t.Fatal(err)
+			}
+			_, err := dynamicClient.Resource(PodGroupResource).Namespace("default").Get("spark-foo-pg", metav1.GetOptions{})
+			if testcase.expectDeleted && !errors.IsNotFound(err) {
+				t.Errorf("expecting PodGroup to be deleted by %s, while get %v", testcase.Name, err)
+			} else if !testcase.expectDeleted && err != nil {
+				t.Errorf("expecting PodGroup to be kept by %s, while get %v", testcase.Name, err)
+			}
+		})
+	}
+}
@@ -25,4 +25,13 @@ type BatchScheduler interface {
 
 	ShouldSchedule(app *v1beta2.SparkApplication) bool
 	DoBatchSchedulingOnSubmission(app *v1beta2.SparkApplication) error
+	// OnSpecInvalidation is called once the current run of the application has been invalidated by a spec change.
+	OnSpecInvalidation(app *v1beta2.SparkApplication) error
+	// OnRerun is called once the resources of the previous run of the application have been deleted, right before
+	// the application is submitted again.
+	OnRerun(app *v1beta2.SparkApplication) error
+	// OnTermination is called once the application has moved to a terminal state.
+	OnTermination(app *v1beta2.SparkApplication) error
+	// OnDeletion is called once the application has been deleted.
+	OnDeletion(app *v1beta2.SparkApplication) error
 }
@@ -22,14 +22,18 @@ import (
 
 	"k8s.io/client-go/rest"
 
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/batchscheduler/coscheduling"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/batchscheduler/interface"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/batchscheduler/volcano"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/batchscheduler/yunikorn"
 )
 
 type schedulerInitializeFunc func(config *rest.Config) (schedulerinterface.BatchScheduler, error)
 
 var schedulerContainers = map[string]schedulerInitializeFunc{
-	volcano.GetPluginName(): volcano.New,
+	volcano.GetPluginName():      volcano.New,
+	yunikorn.GetPluginName():     yunikorn.New,
+	coscheduling.GetPluginName(): coscheduling.New,
 }
 
 func GetRegisteredNames() []string {
@@ -54,12 +58,17 @@ func NewSchedulerManager(config *rest.Config) *SchedulerManager {
 	return &manager
 }
 
-func (batch *SchedulerManager) GetScheduler(schedulerName string) (schedulerinterface.BatchScheduler, error) {
-	iniFunc, registered := schedulerContainers[schedulerName]
-	if !registered {
-		return nil, fmt.Errorf("unregistered scheduler plugin %s", schedulerName)
+// NewSchedulerManagerWithPlugins creates a SchedulerManager serving the given, already initialized plugins by name,
+// e.g. fakes in tests, besides the registered ones.
+func NewSchedulerManagerWithPlugins(config *rest.Config, plugins ...schedulerinterface.BatchScheduler) *SchedulerManager {
+	manager := NewSchedulerManager(config)
+	for _, plugin := range plugins {
+		manager.plugins[plugin.Name()] = plugin
 	}
+	return manager
+}
 
+func (batch *SchedulerManager) GetScheduler(schedulerName string) (schedulerinterface.BatchScheduler, error) {
 	batch.Lock()
 	defer batch.Unlock()
 
@@ -69,6 +78,10 @@ func (batch *SchedulerManager) GetScheduler(schedulerName string) (schedulerinte
 		return nil, fmt.Errorf(
 			"failed to get scheduler plugin %s, previous initialization has failed", schedulerName)
 	} else {
+		iniFunc, registered := schedulerContainers[schedulerName]
+		if !registered {
+			return nil, fmt.Errorf("unregistered scheduler plugin %s", schedulerName)
+		}
 		if plugin, err := iniFunc(batch.config); err != nil {
 			batch.plugins[schedulerName] = nil
 			return nil, err
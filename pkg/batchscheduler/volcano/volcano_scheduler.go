@@ -151,6 +151,33 @@ func (v *VolcanoBatchScheduler) syncPodGroup(app *v1beta2.SparkApplication, size
 	return nil
 }
 
+func (v *VolcanoBatchScheduler) OnSpecInvalidation(app *v1beta2.SparkApplication) error {
+	//NOTE: The PodGroup still requires the resources of the previous spec, it's created again on resubmission.
+	return v.deletePodGroup(app)
+}
+
+func (v *VolcanoBatchScheduler) OnRerun(app *v1beta2.SparkApplication) error {
+	//NOTE: The PodGroup of the previous run may have completed already, a new one is created on resubmission.
+	return v.deletePodGroup(app)
+}
+
+func (v *VolcanoBatchScheduler) OnTermination(app *v1beta2.SparkApplication) error {
+	//NOTE: The PodGroup of a terminated application would keep its resources allocated to the queue.
+	return v.deletePodGroup(app)
+}
+
+func (v *VolcanoBatchScheduler) OnDeletion(app *v1beta2.SparkApplication) error {
+	return v.deletePodGroup(app)
+}
+
+func (v *VolcanoBatchScheduler) deletePodGroup(app *v1beta2.SparkApplication) error {
+	err := v.volcanoClient.SchedulingV1alpha2().PodGroups(app.Namespace).Delete(v.getAppPodGroupName(app), &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete PodGroup with error: %s", err)
+	}
+	return nil
+}
+
 func New(config *rest.Config) (schedulerinterface.BatchScheduler, error) {
 	vkClient, err := volcanoclient.NewForConfig(config)
 	if err != nil {
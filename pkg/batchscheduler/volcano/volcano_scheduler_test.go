@@ -20,7 +20,11 @@ import (
 	"testing"
 
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"volcano.sh/volcano/pkg/apis/scheduling/v1alpha2"
+	volcanofake "volcano.sh/volcano/pkg/client/clientset/versioned/fake"
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
 )
@@ -175,3 +179,70 @@ func TestGetExecutorResource(t *testing.T) {
 		})
 	}
 }
+
+func TestPodGroupDeletionHooks(t *testing.T) {
+	instances := int32(2)
+	testcases := []struct {
+		Name string
+		hook func(scheduler *VolcanoBatchScheduler, app *v1beta2.SparkApplication) error
+	}{
+		{
+			Name: "OnSpecInvalidation",
+			hook: (*VolcanoBatchScheduler).OnSpecInvalidation,
+		},
+		{
+			Name: "OnRerun",
+			hook: (*VolcanoBatchScheduler).OnRerun,
+		},
+		{
+			Name: "OnTermination",
+			hook: (*VolcanoBatchScheduler).OnTermination,
+		},
+		{
+			Name: "OnDeletion",
+			hook: (*VolcanoBatchScheduler).OnDeletion,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			app := &v1beta2.SparkApplication{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "default",
+					UID:       "foo-123",
+				},
+				Spec: v1beta2.SparkApplicationSpec{
+					Mode: v1beta2.ClusterMode,
+					Executor: v1beta2.ExecutorSpec{
+						Instances: &instances,
+					},
+				},
+			}
+			volcanoClient := volcanofake.NewSimpleClientset()
+			scheduler := &VolcanoBatchScheduler{volcanoClient: volcanoClient}
+
+			// An application without a PodGroup is handled fine.
+			if err := testcase.hook(scheduler, app); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := scheduler.DoBatchSchedulingOnSubmission(app); err != nil {
+				t.Fatal(err)
+			}
+			if app.Spec.Driver.Annotations[v1alpha2.GroupNameAnnotationKey] != "spark-foo-pg" {
+				t.Errorf("expecting driver pod to be in PodGroup spark-foo-pg, while get %q", app.Spec.Driver.Annotations[v1alpha2.GroupNameAnnotationKey])
+			}
+			if _, err := volcanoClient.SchedulingV1alpha2().PodGroups("default").Get("spark-foo-pg", metav1.GetOptions{}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := testcase.hook(scheduler, app); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := volcanoClient.SchedulingV1alpha2().PodGroups("default").Get("spark-foo-pg", metav1.GetOptions{}); !errors.IsNotFound(err) {
+				t.Errorf("expecting PodGroup to be deleted by %s, while get %v", testcase.Name, err)
+			}
+		})
+	}
+}
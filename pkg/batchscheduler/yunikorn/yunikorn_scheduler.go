@@ -0,0 +1,201 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yunikorn
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	schedulerinterface "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/batchscheduler/interface"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/webhook/resourceusage"
+)
+
+const (
+	// ApplicationIDLabel is the label YuniKorn groups the pods of an application by.
+	ApplicationIDLabel = "applicationId"
+	// QueueLabel is the label YuniKorn places the pods of an application into a queue by.
+	QueueLabel = "queue"
+	// TaskGroupNameAnnotation is the annotation naming the task group a pod belongs to.
+	TaskGroupNameAnnotation = "yunikorn.apache.org/task-group-name"
+	// TaskGroupsAnnotation is the annotation defining the task groups of an application, which YuniKorn reads from
+	// the first pod of the application it sees.
+	TaskGroupsAnnotation = "yunikorn.apache.org/task-groups"
+
+	DriverTaskGroupName   = "spark-driver"
+	ExecutorTaskGroupName = "spark-executor"
+)
+
+// taskGroup is a group of pods YuniKorn reserves resources for with placeholder pods before any of them is
+// scheduled, which is how YuniKorn does gang scheduling.
+type taskGroup struct {
+	Name         string              `json:"name"`
+	MinMember    int32               `json:"minMember"`
+	MinResource  corev1.ResourceList `json:"minResource"`
+	NodeSelector map[string]string   `json:"nodeSelector,omitempty"`
+	Tolerations  []corev1.Toleration `json:"tolerations,omitempty"`
+	Affinity     *corev1.Affinity    `json:"affinity,omitempty"`
+}
+
+type YuniKornBatchScheduler struct{}
+
+func GetPluginName() string {
+	return "yunikorn"
+}
+
+func (y *YuniKornBatchScheduler) Name() string {
+	return GetPluginName()
+}
+
+func (y *YuniKornBatchScheduler) ShouldSchedule(app *v1beta2.SparkApplication) bool {
+	//NOTE: There is no additional requirement for yunikorn scheduler
+	return true
+}
+
+func (y *YuniKornBatchScheduler) DoBatchSchedulingOnSubmission(app *v1beta2.SparkApplication) error {
+	if app.Spec.Driver.Labels == nil {
+		app.Spec.Driver.Labels = make(map[string]string)
+	}
+	if app.Spec.Executor.Labels == nil {
+		app.Spec.Executor.Labels = make(map[string]string)
+	}
+	if app.Spec.Driver.Annotations == nil {
+		app.Spec.Driver.Annotations = make(map[string]string)
+	}
+	if app.Spec.Executor.Annotations == nil {
+		app.Spec.Executor.Annotations = make(map[string]string)
+	}
+
+	schedulerName := GetPluginName()
+	if app.Spec.Driver.SchedulerName == nil {
+		app.Spec.Driver.SchedulerName = &schedulerName
+	}
+	if app.Spec.Executor.SchedulerName == nil {
+		app.Spec.Executor.SchedulerName = &schedulerName
+	}
+
+	// The driver and executors of every submission make up a new YuniKorn application, unless users group pods
+	// into applications themselves.
+	applicationID, ok := app.Spec.Driver.Labels[ApplicationIDLabel]
+	if !ok {
+		applicationID = fmt.Sprintf("spark-%s", uuid.New().String())
+	}
+	app.Spec.Driver.Labels[ApplicationIDLabel] = applicationID
+	app.Spec.Executor.Labels[ApplicationIDLabel] = applicationID
+
+	// Pods without a queue label are placed into a queue by the placement rules of YuniKorn.
+	if app.Spec.BatchSchedulerOptions != nil && app.Spec.BatchSchedulerOptions.Queue != nil {
+		app.Spec.Driver.Labels[QueueLabel] = *app.Spec.BatchSchedulerOptions.Queue
+		app.Spec.Executor.Labels[QueueLabel] = *app.Spec.BatchSchedulerOptions.Queue
+	}
+
+	if _, ok := app.Spec.Driver.Annotations[TaskGroupsAnnotation]; ok {
+		return nil
+	}
+	if _, ok := app.Spec.Executor.Annotations[TaskGroupsAnnotation]; ok {
+		return nil
+	}
+	return addTaskGroups(app)
+}
+
+// addTaskGroups defines a task group for the driver and one for the executors of the application, and puts each
+// pod into its group.
+func addTaskGroups(app *v1beta2.SparkApplication) error {
+	var taskGroups []taskGroup
+	// The driver runs in the submission pod rather than in a pod of its own in client mode.
+	if app.Spec.Mode != v1beta2.ClientMode {
+		driverTaskGroup, err := newTaskGroup(DriverTaskGroupName, 1, app.Spec, app.Spec.Driver.SparkPodSpec, app.Spec.Driver.CoreRequest)
+		if err != nil {
+			return err
+		}
+		taskGroups = append(taskGroups, driverTaskGroup)
+		app.Spec.Driver.Annotations[TaskGroupNameAnnotation] = DriverTaskGroupName
+	}
+
+	var executors int32 = 1
+	if app.Spec.Executor.Instances != nil {
+		executors = *app.Spec.Executor.Instances
+	}
+	if executors > 0 {
+		executorTaskGroup, err := newTaskGroup(ExecutorTaskGroupName, executors, app.Spec, app.Spec.Executor.SparkPodSpec, app.Spec.Executor.CoreRequest)
+		if err != nil {
+			return err
+		}
+		taskGroups = append(taskGroups, executorTaskGroup)
+		app.Spec.Executor.Annotations[TaskGroupNameAnnotation] = ExecutorTaskGroupName
+	}
+	if len(taskGroups) == 0 {
+		return nil
+	}
+
+	value, err := json.Marshal(taskGroups)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the task groups of SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+	}
+	// The task groups are defined on the first pod of the application, which is the driver in cluster mode.
+	if app.Spec.Mode == v1beta2.ClientMode {
+		app.Spec.Executor.Annotations[TaskGroupsAnnotation] = string(value)
+	} else {
+		app.Spec.Driver.Annotations[TaskGroupsAnnotation] = string(value)
+	}
+	return nil
+}
+
+func newTaskGroup(name string, minMember int32, spec v1beta2.SparkApplicationSpec, podSpec v1beta2.SparkPodSpec, coreRequest *string) (taskGroup, error) {
+	minResource, err := resourceusage.SparkPodRequests(podSpec, coreRequest, spec.MemoryOverheadFactor, spec.Type)
+	if err != nil {
+		return taskGroup{}, fmt.Errorf("failed to compute the resources of task group %s: %v", name, err)
+	}
+	nodeSelector := resourceusage.SparkPodNodeSelector(spec, podSpec)
+	if len(nodeSelector) == 0 {
+		nodeSelector = nil
+	}
+	return taskGroup{
+		Name:         name,
+		MinMember:    minMember,
+		MinResource:  minResource,
+		NodeSelector: nodeSelector,
+		Tolerations:  podSpec.Tolerations,
+		Affinity:     podSpec.Affinity,
+	}, nil
+}
+
+func (y *YuniKornBatchScheduler) OnSpecInvalidation(app *v1beta2.SparkApplication) error {
+	//NOTE: YuniKorn keeps no state for an application besides its pods, so there is nothing to clean up.
+	return nil
+}
+
+func (y *YuniKornBatchScheduler) OnRerun(app *v1beta2.SparkApplication) error {
+	return nil
+}
+
+func (y *YuniKornBatchScheduler) OnTermination(app *v1beta2.SparkApplication) error {
+	return nil
+}
+
+func (y *YuniKornBatchScheduler) OnDeletion(app *v1beta2.SparkApplication) error {
+	return nil
+}
+
+func New(config *rest.Config) (schedulerinterface.BatchScheduler, error) {
+	// YuniKorn reads everything it needs from the labels and annotations of pods, so no client is needed.
+	return &YuniKornBatchScheduler{}, nil
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yunikorn
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func TestDoBatchSchedulingOnSubmission(t *testing.T) {
+
+	oneCore := int32(1)
+	halfCoreStr := "500m"
+	oneGB := "1g"
+	twoGB := "2g"
+	halfGB := "512m"
+	noInstances := int32(0)
+	threeInstances := int32(3)
+	queue := "root.spark"
+	applicationID := "my-application"
+
+	driver := v1beta2.DriverSpec{
+		SparkPodSpec: v1beta2.SparkPodSpec{
+			Cores:          &oneCore,
+			Memory:         &oneGB,
+			MemoryOverhead: &halfGB,
+			NodeSelector:   map[string]string{"disk": "ssd"},
+		},
+	}
+	executor := v1beta2.ExecutorSpec{
+		SparkPodSpec: v1beta2.SparkPodSpec{
+			Memory:         &twoGB,
+			MemoryOverhead: &oneGB,
+			Tolerations:    []v1.Toleration{{Key: "spark", Operator: v1.TolerationOpExists}},
+		},
+		CoreRequest: &halfCoreStr,
+		Instances:   &threeInstances,
+	}
+
+	driverTaskGroup := taskGroup{
+		Name:      DriverTaskGroupName,
+		MinMember: 1,
+		MinResource: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("1"),
+			v1.ResourceMemory: resource.MustParse("1536Mi"),
+		},
+		NodeSelector: map[string]string{"disk": "ssd"},
+	}
+	executorTaskGroup := taskGroup{
+		Name:      ExecutorTaskGroupName,
+		MinMember: 3,
+		MinResource: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("500m"),
+			v1.ResourceMemory: resource.MustParse("3Gi"),
+		},
+		Tolerations: []v1.Toleration{{Key: "spark", Operator: v1.TolerationOpExists}},
+	}
+
+	testcases := []struct {
+		Name               string
+		app                v1beta2.SparkApplication
+		applicationID      string
+		queue              string
+		driverTaskGroups   []taskGroup
+		executorTaskGroups []taskGroup
+	}{
+		{
+			Name: "Validate cluster mode",
+			app: v1beta2.SparkApplication{
+				Spec: v1beta2.SparkApplicationSpec{
+					Mode:                  v1beta2.ClusterMode,
+					BatchSchedulerOptions: &v1beta2.BatchSchedulerConfiguration{Queue: &queue},
+					Driver:                driver,
+					Executor:              executor,
+				},
+			},
+			queue:            queue,
+			driverTaskGroups: []taskGroup{driverTaskGroup, executorTaskGroup},
+		},
+		{
+			Name: "Validate client mode",
+			app: v1beta2.SparkApplication{
+				Spec: v1beta2.SparkApplicationSpec{
+					Mode:     v1beta2.ClientMode,
+					Driver:   driver,
+					Executor: executor,
+				},
+			},
+			executorTaskGroups: []taskGroup{executorTaskGroup},
+		},
+		{
+			Name: "Validate application ID and no executors",
+			app: v1beta2.SparkApplication{
+				Spec: v1beta2.SparkApplicationSpec{
+					Mode: v1beta2.ClusterMode,
+					Driver: v1beta2.DriverSpec{
+						SparkPodSpec: v1beta2.SparkPodSpec{
+							Cores:          &oneCore,
+							Memory:         &oneGB,
+							MemoryOverhead: &halfGB,
+							NodeSelector:   map[string]string{"disk": "ssd"},
+							Labels:         map[string]string{ApplicationIDLabel: applicationID},
+						},
+					},
+					Executor: v1beta2.ExecutorSpec{
+						SparkPodSpec: executor.SparkPodSpec,
+						Instances:    &noInstances,
+					},
+				},
+			},
+			applicationID:    applicationID,
+			driverTaskGroups: []taskGroup{driverTaskGroup},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			scheduler := &YuniKornBatchScheduler{}
+			app := testcase.app.DeepCopy()
+			if err := scheduler.DoBatchSchedulingOnSubmission(app); err != nil {
+				t.Fatal(err)
+			}
+
+			if *app.Spec.Driver.SchedulerName != GetPluginName() || *app.Spec.Executor.SchedulerName != GetPluginName() {
+				t.Errorf("expecting pods to be scheduled by %s, while get %s and %s",
+					GetPluginName(), *app.Spec.Driver.SchedulerName, *app.Spec.Executor.SchedulerName)
+			}
+
+			driverApplicationID := app.Spec.Driver.Labels[ApplicationIDLabel]
+			if testcase.applicationID != "" && driverApplicationID != testcase.applicationID {
+				t.Errorf("expecting application ID %s, while get %s", testcase.applicationID, driverApplicationID)
+			}
+			if testcase.applicationID == "" && !strings.HasPrefix(driverApplicationID, "spark-") {
+				t.Errorf("expecting a generated application ID, while get %s", driverApplicationID)
+			}
+			if executorApplicationID := app.Spec.Executor.Labels[ApplicationIDLabel]; executorApplicationID != driverApplicationID {
+				t.Errorf("expecting executor application ID %s, while get %s", driverApplicationID, executorApplicationID)
+			}
+
+			if app.Spec.Driver.Labels[QueueLabel] != testcase.queue || app.Spec.Executor.Labels[QueueLabel] != testcase.queue {
+				t.Errorf("expecting queue %q, while get %q and %q",
+					testcase.queue, app.Spec.Driver.Labels[QueueLabel], app.Spec.Executor.Labels[QueueLabel])
+			}
+
+			if len(testcase.driverTaskGroups) > 0 && app.Spec.Driver.Annotations[TaskGroupNameAnnotation] != DriverTaskGroupName {
+				t.Errorf("expecting driver pod to be in task group %s, while get %q",
+					DriverTaskGroupName, app.Spec.Driver.Annotations[TaskGroupNameAnnotation])
+			}
+			if app.Spec.Executor.Annotations[TaskGroupNameAnnotation] != ExecutorTaskGroupName && *app.Spec.Executor.Instances > 0 {
+				t.Errorf("expecting executor pods to be in task group %s, while get %q",
+					ExecutorTaskGroupName, app.Spec.Executor.Annotations[TaskGroupNameAnnotation])
+			}
+
+			assertTaskGroups(t, "driver", app.Spec.Driver.Annotations, testcase.driverTaskGroups)
+			assertTaskGroups(t, "executor", app.Spec.Executor.Annotations, testcase.executorTaskGroups)
+		})
+	}
+}
+
+func assertTaskGroups(t *testing.T, role string, annotations map[string]string, expected []taskGroup) {
+	value, ok := annotations[TaskGroupsAnnotation]
+	if len(expected) == 0 {
+		if ok {
+			t.Errorf("expecting %s pod to define no task groups, while get %s", role, value)
+		}
+		return
+	}
+	var actual []taskGroup
+	if err := json.Unmarshal([]byte(value), &actual); err != nil {
+		t.Fatalf("failed to unmarshal the task groups of the %s pod %q: %v", role, value, err)
+	}
+	if len(actual) != len(expected) {
+		t.Fatalf("expecting %s pod to define %d task groups, while get %s", role, len(expected), value)
+	}
+	for i, group := range expected {
+		if actual[i].Name != group.Name || actual[i].MinMember != group.MinMember {
+			t.Errorf("expecting task group %s with %d members, while get %s with %d members",
+				group.Name, group.MinMember, actual[i].Name, actual[i].MinMember)
+		}
+		for name, quantity := range group.MinResource {
+			if value, ok := actual[i].MinResource[name]; !ok {
+				t.Errorf("expecting task group %s to have resource %s, while get none", group.Name, name)
+			} else if quantity.Cmp(value) != 0 {
+				t.Errorf("expecting task group %s to have resource %s with value %s, while get %s",
+					group.Name, name, quantity.String(), value.String())
+			}
+		}
+		if len(actual[i].NodeSelector) != len(group.NodeSelector) || len(actual[i].Tolerations) != len(group.Tolerations) {
+			t.Errorf("expecting task group %s to have node selector %v and tolerations %v, while get %v and %v",
+				group.Name, group.NodeSelector, group.Tolerations, actual[i].NodeSelector, actual[i].Tolerations)
+		}
+	}
+}
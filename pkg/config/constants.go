@@ -54,6 +54,24 @@ const (
 	SparkExecutorRole = "executor"
 	// SubmissionIDLabel is the label that records the submission ID of the current run of an application.
 	SubmissionIDLabel = LabelAnnotationPrefix + "submission-id"
+	// NotificationURLsAnnotation is the annotation of SparkApplications and Namespaces holding the comma-separated
+	// URLs notifications of the state transitions of the applications are POSTed to.
+	NotificationURLsAnnotation = LabelAnnotationPrefix + "notification-urls"
+	// NotificationStatesAnnotation is the annotation of SparkApplications and Namespaces holding the comma-separated
+	// states transitions into which are notified. Transitions into the states recorded as events of the applications
+	// are notified if it is not set.
+	NotificationStatesAnnotation = LabelAnnotationPrefix + "notification-states"
+	// NotificationSecretAnnotation is the annotation of SparkApplications and Namespaces holding the name of the
+	// Secret in the namespace of the applications with the key notifications are signed with.
+	NotificationSecretAnnotation = LabelAnnotationPrefix + "notification-secret"
+	// NotificationSigningKeySecretKey is the key of the signing key of notifications in the Secret.
+	NotificationSigningKeySecretKey = "signing-key"
+	// QueuePriorityAnnotation is the annotation of SparkApplications holding the integer priority of applications
+	// queued until they fit into the resource quotas, if queued applications are ordered by priority.
+	QueuePriorityAnnotation = LabelAnnotationPrefix + "queue-priority"
+	// QuotaEnforcementLabel is the label of namespaces selecting the resource quota enforcement mode of the namespace,
+	// one of reject, queue, warn and disabled.
+	QuotaEnforcementLabel = LabelAnnotationPrefix + "quota-enforcement"
 )
 
 const (
@@ -131,6 +149,12 @@ const (
 	// SparkMaxSimultaneousDownloads is the Spark configuration key for specifying the maximum number of remote
 	// dependencies to download.
 	SparkMaxSimultaneousDownloads = "spark.kubernetes.mountDependencies.maxSimultaneousDownloads"
+	// SparkEventLogEnabledKey is the Spark configuration key for specifying whether to enable event logging.
+	SparkEventLogEnabledKey = "spark.eventLog.enabled"
+	// SparkEventLogDirKey is the Spark configuration key for specifying the base directory of event logs.
+	SparkEventLogDirKey = "spark.eventLog.dir"
+	// SparkEventLogCompressKey is the Spark configuration key for specifying whether to compress event logs.
+	SparkEventLogCompressKey = "spark.eventLog.compress"
 	// SparkWaitAppCompletion is the Spark configuration key for specifying whether to wait for application to complete.
 	SparkWaitAppCompletion = "spark.kubernetes.submission.waitAppCompletion"
 	// SparkPythonVersion is the Spark configuration key for specifying python version used.
@@ -272,6 +296,13 @@ rules:
 // DefaultPrometheusJavaAgentPort is the default port used by the Prometheus JMX exporter.
 const DefaultPrometheusJavaAgentPort int32 = 8090
 
+const (
+	// DefaultPrometheusServletPath is the default path the PrometheusServlet serves driver metrics at.
+	DefaultPrometheusServletPath = "/metrics/prometheus"
+	// PrometheusServletExecutorsPath is the path the driver serves executor metrics at in the Prometheus format.
+	PrometheusServletExecutorsPath = "/metrics/executors/prometheus"
+)
+
 const (
 	// SparkDriverContainerName is name of driver container in spark driver pod
 	SparkDriverContainerName = "spark-kubernetes-driver"
@@ -284,3 +315,14 @@ const (
 	// SparkLocalDirVolumePrefix is the volume name prefix for "scratch" space directory
 	SparkLocalDirVolumePrefix = "spark-local-dir-"
 )
+
+const (
+	// SparkSubmitPodCPURequest is the CPU request of the spark-submit-runner container of submission Jobs.
+	SparkSubmitPodCPURequest = "512m"
+	// SparkSubmitPodMemoryRequest is the memory request of the spark-submit-runner container of submission Jobs.
+	SparkSubmitPodMemoryRequest = "500Mi"
+	// SparkSubmitPodCPULimit is the CPU limit of the spark-submit-runner container of submission Jobs.
+	SparkSubmitPodCPULimit = "1024m"
+	// SparkSubmitPodMemoryLimit is the memory limit of the spark-submit-runner container of submission Jobs.
+	SparkSubmitPodMemoryLimit = "1000Mi"
+)
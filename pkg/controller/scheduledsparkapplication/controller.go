@@ -43,8 +43,12 @@ import (
 	crdinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
 	crdlisters "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/listers/sparkoperator.k8s.io/v1beta2"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/notification"
 )
 
+// cloudEventTimeout bounds the time spent on emitting a single CloudEvent.
+const cloudEventTimeout = 5 * time.Minute
+
 var (
 	keyFunc = cache.DeletionHandlingMetaNamespaceKeyFunc
 )
@@ -58,6 +62,8 @@ type Controller struct {
 	ssaLister        crdlisters.ScheduledSparkApplicationLister
 	saLister         crdlisters.SparkApplicationLister
 	clock            clock.Clock
+	// cloudEventEmitter emits CloudEvents of the runs created. CloudEvents are not emitted if it is nil.
+	cloudEventEmitter *notification.CloudEventEmitter
 }
 
 func NewController(
@@ -65,18 +71,20 @@ func NewController(
 	kubeClient kubernetes.Interface,
 	extensionsClient apiextensionsclient.Interface,
 	informerFactory crdinformers.SharedInformerFactory,
-	clock clock.Clock) *Controller {
+	clock clock.Clock,
+	cloudEventEmitter *notification.CloudEventEmitter) *Controller {
 	crdscheme.AddToScheme(scheme.Scheme)
 
 	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(),
 		"scheduled-spark-application-controller")
 
 	controller := &Controller{
-		crdClient:        crdClient,
-		kubeClient:       kubeClient,
-		extensionsClient: extensionsClient,
-		queue:            queue,
-		clock:            clock,
+		crdClient:         crdClient,
+		kubeClient:        kubeClient,
+		extensionsClient:  extensionsClient,
+		queue:             queue,
+		clock:             clock,
+		cloudEventEmitter: cloudEventEmitter,
 	}
 
 	informer := informerFactory.Sparkoperator().V1beta2().ScheduledSparkApplications()
@@ -194,6 +202,7 @@ func (c *Controller) syncScheduledSparkApplication(key string) error {
 				status.LastRun = metav1.NewTime(now)
 				status.NextRun = metav1.NewTime(schedule.Next(status.LastRun.Time))
 				status.LastRunName = name
+				c.emitRunCreatedEvent(app, status, name, now)
 			}
 		}
 
@@ -296,6 +305,30 @@ func (c *Controller) startNextRun(app *v1beta2.ScheduledSparkApplication, now ti
 	return name, nil
 }
 
+// emitRunCreatedEvent queues a CloudEvent of the creation of the run with the given name to be emitted in the
+// background.
+func (c *Controller) emitRunCreatedEvent(
+	app *v1beta2.ScheduledSparkApplication,
+	status *v1beta2.ScheduledSparkApplicationStatus,
+	runName string,
+	now time.Time) {
+	if c.cloudEventEmitter == nil {
+		return
+	}
+	event := notification.NewScheduledSparkApplicationEvent(notification.ScheduledSparkApplicationRunCreatedEventType,
+		app.DeepCopy(), status.DeepCopy(), runName, now)
+	key := fmt.Sprintf("%s/%s", app.Namespace, app.Name)
+	emitted := c.cloudEventEmitter.EmitAsync(key, event, cloudEventTimeout, func(err error) {
+		if err != nil {
+			glog.Errorf("failed to emit CloudEvent of run %s of ScheduledSparkApplication %s: %v", runName, key, err)
+		}
+	})
+	if !emitted {
+		glog.Errorf("dropped CloudEvent of run %s of ScheduledSparkApplication %s as the CloudEvent queue is full or stopped",
+			runName, key)
+	}
+}
+
 func (c *Controller) hasLastRunFinished(app *v1beta2.SparkApplication) bool {
 	return app.Status.AppState.State == v1beta2.CompletedState ||
 		app.Status.AppState.State == v1beta2.FailedState
@@ -17,6 +17,10 @@ limitations under the License.
 package scheduledsparkapplication
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -26,6 +30,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
 	kubeclientfake "k8s.io/client-go/kubernetes/fake"
 	kubetesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
@@ -34,6 +39,7 @@ import (
 	crdclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
 	crdinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/notification"
 )
 
 func TestSyncScheduledSparkApplication_Allow(t *testing.T) {
@@ -487,13 +493,59 @@ func TestCheckAndUpdatePastRuns(t *testing.T) {
 	assert.Nil(t, existing)
 }
 
+func TestSyncScheduledSparkApplicationEmitsRunCreatedEvent(t *testing.T) {
+	events := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var event map[string]interface{}
+		json.Unmarshal(body, &event)
+		events <- event
+	}))
+	defer server.Close()
+
+	app := &v1beta2.ScheduledSparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "test-app-events",
+		},
+		Spec: v1beta2.ScheduledSparkApplicationSpec{
+			Schedule:          "@every 10m",
+			ConcurrencyPolicy: v1beta2.ConcurrencyAllow,
+		},
+	}
+	c, clk := newFakeController()
+	c.cloudEventEmitter = notification.NewCloudEventEmitter(notification.NewSender(time.Second, 0, time.Millisecond), server.URL,
+		notification.NewDispatcher(1, 1))
+	defer c.cloudEventEmitter.Stop()
+	c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Create(app)
+
+	key, _ := cache.MetaNamespaceKeyFunc(app)
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	clk.Step(10 * time.Minute)
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(app.Name, metav1.GetOptions{})
+
+	select {
+	case event := <-events:
+		assert.Equal(t, notification.ScheduledSparkApplicationRunCreatedEventType, event["type"])
+		data := event["data"].(map[string]interface{})
+		assert.Equal(t, app.Status.LastRunName, data["runName"])
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("timed out waiting for the CloudEvent")
+	}
+}
+
 func newFakeController() (*Controller, *clock.FakeClock) {
 	crdClient := crdclientfake.NewSimpleClientset()
 	kubeClient := kubeclientfake.NewSimpleClientset()
 	apiExtensionsClient := apiextensionsfake.NewSimpleClientset()
 	informerFactory := crdinformers.NewSharedInformerFactory(crdClient, 1*time.Second)
 	clk := clock.NewFakeClock(time.Now())
-	controller := NewController(crdClient, kubeClient, apiExtensionsClient, informerFactory, clk)
+	controller := NewController(crdClient, kubeClient, apiExtensionsClient, informerFactory, clk, nil)
 	ssaInformer := informerFactory.Sparkoperator().V1beta2().ScheduledSparkApplications().Informer()
 	saInformer := informerFactory.Sparkoperator().V1beta2().SparkApplications().Informer()
 	crdClient.PrependReactor("create", "scheduledsparkapplications",
@@ -31,11 +31,14 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	v1 "k8s.io/client-go/listers/core/v1"
+	extensionslisters "k8s.io/client-go/listers/extensions/v1beta1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
@@ -49,6 +52,10 @@ import (
 	crdinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
 	crdlisters "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/listers/sparkoperator.k8s.io/v1beta2"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/logarchive"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/notification"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/tracing"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/uiproxy"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
 )
 
@@ -71,6 +78,7 @@ type Controller struct {
 	cacheSynced             cache.InformerSynced
 	recorder                record.EventRecorder
 	metrics                 *sparkAppMetrics
+	controllerMetrics       *controllerMetrics
 	applicationLister       crdlisters.SparkApplicationLister
 	podLister               v1.PodLister
 	ingressURLFormat        string
@@ -78,6 +86,78 @@ type Controller struct {
 	subJobManager           submissionJobManager
 	clientModeSubPodManager clientModeSubmissionPodManager
 	enableUIService         bool
+	// driverFailureLogTailLines is the number of driver log lines to capture when the driver container
+	// fails. Capturing is disabled if it is zero.
+	driverFailureLogTailLines int64
+	// logArchiver archives logs of driver and executor pods in the background. Archiving is disabled if it is nil.
+	logArchiver *logArchiver
+	// eventLogConfig holds the operator-level defaults of Spark event logging.
+	eventLogConfig *EventLogConfig
+	// enableUIProxy tells if the driver UIs are served through the built-in UI proxy.
+	enableUIProxy bool
+	// uiConfig holds the operator-level settings of the Service and the Ingress exposing the Spark UI.
+	uiConfig *SparkUIConfig
+	// dynamicClient is used to manage objects of APIs not supported by the typed clients.
+	dynamicClient dynamic.Interface
+	// serviceLister and ingressLister list the Services and the extensions/v1beta1 Ingresses exposing the Spark UI.
+	serviceLister v1.ServiceLister
+	ingressLister extensionslisters.IngressLister
+	// progressPoller queries the REST API of drivers for the progress of applications. Progress reporting is
+	// disabled if it is nil.
+	progressPoller *sparkProgressPoller
+	// serviceMonitorConfig holds the settings of the ServiceMonitors created for monitored applications. No
+	// ServiceMonitors are created if it is nil.
+	serviceMonitorConfig *ServiceMonitorConfig
+	// tracer records the lifecycle of applications as traces. Tracing is disabled if it is nil.
+	tracer *tracing.Tracer
+	// notificationConfig holds the settings of notifying the endpoints configured through annotations of the state
+	// transitions of applications. Notifications are disabled if it is nil.
+	notificationConfig *NotificationConfig
+	// notificationDispatcher delivers the notifications in the background if notifications are enabled.
+	notificationDispatcher *notification.Dispatcher
+	// cloudEventEmitter emits CloudEvents of the state transitions of applications. CloudEvents are not emitted if it
+	// is nil.
+	cloudEventEmitter *notification.CloudEventEmitter
+	// quotaQueueConfig holds the settings of queueing applications exceeding the resource quotas of their
+	// namespaces. Applications are not queued if it is nil.
+	quotaQueueConfig *QuotaQueueConfig
+}
+
+// Options holds the settings of the optional subsystems of the Controller. The zero value disables all of them.
+type Options struct {
+	// DriverFailureLogTailLines is the number of driver log lines to capture when the driver container fails.
+	// Capturing is disabled if it is zero.
+	DriverFailureLogTailLines int64
+	// LogArchiveSink is the sink logs of driver and executor pods are archived to. Archiving is disabled if it is nil.
+	LogArchiveSink logarchive.Sink
+	// EventLogConfig holds the operator-level defaults of Spark event logging.
+	EventLogConfig *EventLogConfig
+	// EnableUIProxy tells if the driver UIs are served through the built-in UI proxy.
+	EnableUIProxy bool
+	// UIConfig holds the operator-level settings of the Service and the Ingress exposing the Spark UI.
+	UIConfig *SparkUIConfig
+	// DynamicClient is used to manage objects of APIs not supported by the typed clients.
+	DynamicClient dynamic.Interface
+	// DynamicInformerFactory provides the informers of objects of APIs not supported by the typed informers, e.g.,
+	// networking.k8s.io/v1 Ingresses, which must be filtered by the same label selector as the typed ones.
+	DynamicInformerFactory dynamicinformer.DynamicSharedInformerFactory
+	// SparkProgressPollInterval is the interval between two queries of the REST API of a driver for the progress of
+	// the application. Progress reporting is disabled if it is zero.
+	SparkProgressPollInterval time.Duration
+	// ServiceMonitorConfig holds the settings of the ServiceMonitors created for monitored applications. No
+	// ServiceMonitors are created if it is nil.
+	ServiceMonitorConfig *ServiceMonitorConfig
+	// Tracer records the lifecycle of applications as traces. Tracing is disabled if it is nil.
+	Tracer *tracing.Tracer
+	// NotificationConfig holds the settings of notifying the endpoints configured through annotations of the state
+	// transitions of applications. Notifications are disabled if it is nil.
+	NotificationConfig *NotificationConfig
+	// CloudEventEmitter emits CloudEvents of the state transitions of applications. CloudEvents are not emitted if
+	// it is nil.
+	CloudEventEmitter *notification.CloudEventEmitter
+	// QuotaQueueConfig holds the settings of queueing applications exceeding the resource quotas of their
+	// namespaces. Applications are not queued if it is nil.
+	QuotaQueueConfig *QuotaQueueConfig
 }
 
 // NewController creates a new Controller.
@@ -90,7 +170,8 @@ func NewController(
 	namespace string,
 	ingressURLFormat string,
 	batchSchedulerMgr *batchscheduler.SchedulerManager,
-	enableUIService bool) *Controller {
+	enableUIService bool,
+	options Options) *Controller {
 	crdscheme.AddToScheme(scheme.Scheme)
 
 	eventBroadcaster := record.NewBroadcaster()
@@ -100,7 +181,7 @@ func NewController(
 	})
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, apiv1.EventSource{Component: "spark-operator"})
 
-	return newSparkApplicationController(crdClient, kubeClient, crdInformerFactory, informerFactory, recorder, metricsConfig, ingressURLFormat, batchSchedulerMgr, enableUIService)
+	return newSparkApplicationController(crdClient, kubeClient, crdInformerFactory, informerFactory, recorder, metricsConfig, ingressURLFormat, batchSchedulerMgr, enableUIService, options)
 }
 
 func newSparkApplicationController(
@@ -112,25 +193,47 @@ func newSparkApplicationController(
 	metricsConfig *util.MetricConfig,
 	ingressURLFormat string,
 	batchSchedulerMgr *batchscheduler.SchedulerManager,
-	enableUIService bool) *Controller {
+	enableUIService bool,
+	options Options) *Controller {
 	queue := workqueue.NewNamedRateLimitingQueue(&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(queueTokenRefillRate), queueTokenBucketSize)},
 		"spark-application-controller")
 
 	controller := &Controller{
-		crdClient:               crdClient,
-		kubeClient:              kubeClient,
-		recorder:                eventRecorder,
-		queue:                   queue,
-		ingressURLFormat:        ingressURLFormat,
-		batchSchedulerMgr:       batchSchedulerMgr,
-		subJobManager:           &realSubmissionJobManager{kubeClient: kubeClient},
-		clientModeSubPodManager: &realClientModeSubmissionPodManager{kubeClient: kubeClient},
-		enableUIService:         enableUIService,
+		crdClient:                 crdClient,
+		kubeClient:                kubeClient,
+		recorder:                  eventRecorder,
+		queue:                     queue,
+		ingressURLFormat:          ingressURLFormat,
+		batchSchedulerMgr:         batchSchedulerMgr,
+		subJobManager:             &realSubmissionJobManager{kubeClient: kubeClient},
+		clientModeSubPodManager:   &realClientModeSubmissionPodManager{kubeClient: kubeClient},
+		enableUIService:           enableUIService,
+		driverFailureLogTailLines: options.DriverFailureLogTailLines,
+		eventLogConfig:            options.EventLogConfig,
+		enableUIProxy:             options.EnableUIProxy,
+		uiConfig:                  options.UIConfig,
+		dynamicClient:             options.DynamicClient,
+		serviceMonitorConfig:      options.ServiceMonitorConfig,
+		tracer:                    options.Tracer,
+		notificationConfig:        options.NotificationConfig,
+		cloudEventEmitter:         options.CloudEventEmitter,
+		quotaQueueConfig:          options.QuotaQueueConfig,
+	}
+	if controller.uiConfig == nil {
+		controller.uiConfig = &SparkUIConfig{}
+	}
+	if options.LogArchiveSink != nil {
+		controller.logArchiver = newLogArchiver(options.LogArchiveSink)
+	}
+	if options.SparkProgressPollInterval > 0 {
+		controller.progressPoller = newSparkProgressPoller(options.SparkProgressPollInterval)
 	}
 
 	if metricsConfig != nil {
 		controller.metrics = newSparkAppMetrics(metricsConfig)
 		controller.metrics.registerMetrics()
+		controller.controllerMetrics = newControllerMetrics(metricsConfig)
+		controller.controllerMetrics.registerMetrics()
 	}
 
 	crdInformer := crdInformerFactory.Sparkoperator().V1beta2().SparkApplications()
@@ -140,6 +243,9 @@ func newSparkApplicationController(
 		DeleteFunc: controller.onDelete,
 	})
 	controller.applicationLister = crdInformer.Lister()
+	if metricsConfig != nil {
+		util.RegisterMetric(newSparkAppStateCollector(metricsConfig.MetricsPrefix, controller.applicationLister))
+	}
 
 	podsInformer := informerFactory.Core().V1().Pods()
 	sparkObjectEventHandler := newSparkObjectEventHandler(controller.queue.AddRateLimited, controller.applicationLister)
@@ -159,8 +265,51 @@ func newSparkApplicationController(
 	controller.subJobManager = &realSubmissionJobManager{kubeClient: kubeClient, jobLister: jobInformer.Lister()}
 	controller.clientModeSubPodManager = &realClientModeSubmissionPodManager{kubeClient: kubeClient, podLister: podsInformer.Lister()}
 
+	// Watch the objects exposing the Spark UI so they are reconciled when they get modified or deleted.
+	uiInformersSynced := func() bool { return true }
+	if enableUIService {
+		serviceInformer := informerFactory.Core().V1().Services()
+		serviceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    sparkObjectEventHandler.onObjectAdded,
+			UpdateFunc: sparkObjectEventHandler.onObjectUpdated,
+			DeleteFunc: sparkObjectEventHandler.onObjectDeleted,
+		})
+		controller.serviceLister = serviceInformer.Lister()
+		uiInformersSynced = serviceInformer.Informer().HasSynced
+
+		if ingressURLFormat != "" && controller.uiConfig.IngressAPIVersion != IngressAPIVersionNetworkingV1 {
+			ingressInformer := informerFactory.Extensions().V1beta1().Ingresses()
+			ingressInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+				AddFunc:    sparkObjectEventHandler.onObjectAdded,
+				UpdateFunc: sparkObjectEventHandler.onObjectUpdated,
+				DeleteFunc: sparkObjectEventHandler.onObjectDeleted,
+			})
+			controller.ingressLister = ingressInformer.Lister()
+			uiInformersSynced = func() bool {
+				return serviceInformer.Informer().HasSynced() && ingressInformer.Informer().HasSynced()
+			}
+		} else if ingressURLFormat != "" && options.DynamicInformerFactory != nil {
+			ingressInformer := options.DynamicInformerFactory.ForResource(networkingV1IngressResource)
+			ingressInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+				AddFunc:    sparkObjectEventHandler.onObjectAdded,
+				UpdateFunc: sparkObjectEventHandler.onObjectUpdated,
+				DeleteFunc: sparkObjectEventHandler.onObjectDeleted,
+			})
+			uiInformersSynced = func() bool {
+				return serviceInformer.Informer().HasSynced() && ingressInformer.Informer().HasSynced()
+			}
+		}
+	}
+
+	namespacesSynced := func() bool { return true }
+	if options.NotificationConfig != nil {
+		controller.notificationDispatcher = notification.NewDispatcher(options.NotificationConfig.Workers, options.NotificationConfig.QueueSize)
+		namespacesSynced = options.NotificationConfig.NamespaceInformer.Informer().HasSynced
+	}
+
 	controller.cacheSynced = func() bool {
-		return crdInformer.Informer().HasSynced() && podsInformer.Informer().HasSynced()
+		return crdInformer.Informer().HasSynced() && podsInformer.Informer().HasSynced() && uiInformersSynced() &&
+			namespacesSynced()
 	}
 
 	return controller
@@ -173,6 +322,12 @@ func (c *Controller) Start(workers int, stopCh <-chan struct{}) error {
 		// runWorker will loop until "something bad" happens. Until will then rekick
 		// the worker after one second.
 		go wait.Until(c.runWorker, time.Second, stopCh)
+		if c.logArchiver != nil {
+			go wait.Until(c.runLogArchiveWorker, time.Second, stopCh)
+		}
+		if c.progressPoller != nil {
+			go wait.Until(c.runSparkProgressWorker, time.Second, stopCh)
+		}
 	}
 
 	// Wait for all involved caches to be synced, before processing items from the queue is started.
@@ -186,6 +341,16 @@ func (c *Controller) Start(workers int, stopCh <-chan struct{}) error {
 func (c *Controller) Stop() {
 	glog.Info("Stopping the SparkApplication controller")
 	c.queue.ShutDown()
+	if c.logArchiver != nil {
+		c.logArchiver.queue.ShutDown()
+	}
+	if c.progressPoller != nil {
+		c.progressPoller.queue.ShutDown()
+	}
+	if c.notificationDispatcher != nil {
+		// Deliver the notifications of the transitions that have already happened.
+		c.notificationDispatcher.Stop()
+	}
 }
 
 // Callback function called when a new SparkApplication object gets created.
@@ -206,8 +371,9 @@ func (c *Controller) onUpdate(oldObj, newObj interface{}) {
 	}
 
 	// The spec has changed. This is currently best effort as we can potentially miss updates
-	// and end up in an inconsistent state.
-	if !equality.Semantic.DeepEqual(oldApp.Spec, newApp.Spec) {
+	// and end up in an inconsistent state. Queued applications have nothing to clean up and are
+	// checked against the resource quotas with the new spec.
+	if !equality.Semantic.DeepEqual(oldApp.Spec, newApp.Spec) && newApp.Status.AppState.State != v1beta2.QueuedState {
 		// Force-set the application status to Invalidating which handles clean-up and application re-run.
 		if _, err := c.updateApplicationStatusWithRetries(newApp, func(status *v1beta2.SparkApplicationStatus) {
 			status.AppState.State = v1beta2.InvalidatingState
@@ -246,6 +412,12 @@ func (c *Controller) onDelete(obj interface{}) {
 
 	if app != nil {
 		c.handleSparkApplicationDeletion(app)
+		if c.progressPoller != nil {
+			c.progressPoller.forget(createMetaNamespaceKey(app.Namespace, app.Name))
+		}
+		if c.logArchiver != nil {
+			c.logArchiver.forget(createMetaNamespaceKey(app.Namespace, app.Name))
+		}
 		c.recorder.Eventf(
 			app,
 			apiv1.EventTypeNormal,
@@ -282,6 +454,9 @@ func (c *Controller) processNextItem() bool {
 
 	// There was a failure so be sure to report it. This method allows for pluggable error handling
 	// which can be used for things like cluster-monitoring
+	if c.controllerMetrics != nil {
+		c.controllerMetrics.recordReconcileError(err)
+	}
 	utilruntime.HandleError(fmt.Errorf("failed to sync SparkApplication %q: %v", key, err))
 	return true
 }
@@ -351,6 +526,9 @@ func (c *Controller) getAndUpdateDriverState(app *v1beta2.SparkApplication) erro
 			if state != nil {
 				if state.ExitCode != 0 {
 					app.Status.AppState.ErrorMessage = fmt.Sprintf("driver container failed with ExitCode: %d, Reason: %s", state.ExitCode, state.Reason)
+					if c.driverFailureLogTailLines > 0 {
+						c.captureDriverFailure(app, driverPod, state)
+					}
 				}
 			} else {
 				app.Status.AppState.ErrorMessage = "driver container status missing"
@@ -441,6 +619,7 @@ func (c *Controller) handleSparkApplicationDeletion(app *v1beta2.SparkApplicatio
 	if err := c.deleteSparkResources(app); err != nil {
 		glog.Errorf("failed to delete resources associated with deleted SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
 	}
+	c.runBatchSchedulerHook(app, "OnDeletion", schedulerinterface.BatchScheduler.OnDeletion)
 }
 
 // ShouldRetry determines if SparkApplication in a given state should be retried.
@@ -529,21 +708,35 @@ func (c *Controller) syncSparkApplication(key string) error {
 		// SparkApplication not found.
 		return nil
 	}
+	if c.controllerMetrics != nil {
+		defer c.controllerMetrics.observeReconcile(app.Status.AppState.State, time.Now())
+	}
 	if !app.DeletionTimestamp.IsZero() {
 		c.handleSparkApplicationDeletion(app)
 		return nil
 	}
 
 	appToUpdate := app.DeepCopy()
+	if c.logArchiver != nil {
+		c.recordLogArchiveLocations(appToUpdate)
+	}
 
 	// Take action based on application state.
 	switch appToUpdate.Status.AppState.State {
 	case v1beta2.NewState:
 		c.recordSparkApplicationEvent(appToUpdate)
+		validationStart := time.Now()
 		if err := c.validateSparkApplication(appToUpdate); err != nil {
 			appToUpdate.Status.AppState.State = v1beta2.FailedState
 			appToUpdate.Status.AppState.ErrorMessage = err.Error()
-		} else {
+			c.recordValidationSpan(appToUpdate, validationStart, time.Now(), err)
+		} else if !c.queueIfOverQuota(appToUpdate) {
+			validationEnd := time.Now()
+			appToUpdate = c.submitSparkApplication(appToUpdate)
+			c.recordValidationSpan(appToUpdate, validationStart, validationEnd, nil)
+		}
+	case v1beta2.QueuedState:
+		if c.dequeueIfUnderQuota(appToUpdate) {
 			appToUpdate = c.submitSparkApplication(appToUpdate)
 		}
 	case v1beta2.PendingSubmissionState:
@@ -585,11 +778,17 @@ func (c *Controller) syncSparkApplication(key string) error {
 
 		}
 	case v1beta2.SucceedingState:
+		archivingLogs := c.logArchiver != nil && c.archiveLogs(appToUpdate)
 		// The current run of the application has completed, check if it needs to be restarted.
 		if !shouldRetry(appToUpdate) {
 			// Application is not subject to retry. Move to terminal CompletedState.
 			appToUpdate.Status.AppState.State = v1beta2.CompletedState
+			c.setHistoryServerURL(appToUpdate)
 			c.recordSparkApplicationEvent(appToUpdate)
+			c.runBatchSchedulerHook(appToUpdate, "OnTermination", schedulerinterface.BatchScheduler.OnTermination)
+		} else if archivingLogs {
+			// The application is enqueued again once the logs have been archived.
+			glog.V(2).Infof("SparkApplication %s/%s waiting for its logs to be archived before rerunning", appToUpdate.Namespace, appToUpdate.Name)
 		} else {
 			if err := c.deleteSparkResources(appToUpdate); err != nil {
 				glog.Errorf("failed to delete resources associated with SparkApplication %s/%s: %v",
@@ -599,11 +798,14 @@ func (c *Controller) syncSparkApplication(key string) error {
 			appToUpdate.Status.AppState.State = v1beta2.PendingRerunState
 		}
 	case v1beta2.FailingState:
+		archivingLogs := c.logArchiver != nil && c.archiveLogs(appToUpdate)
 		if !shouldRetry(appToUpdate) {
 			// Application is not subject to retry. Move to terminal FailedState.
 			appToUpdate.Status.AppState.State = v1beta2.FailedState
+			c.setHistoryServerURL(appToUpdate)
 			c.recordSparkApplicationEvent(appToUpdate)
-		} else if hasRetryIntervalPassed(appToUpdate.Spec.RestartPolicy.OnFailureRetryInterval, appToUpdate.Status.ExecutionAttempts, appToUpdate.Status.TerminationTime) {
+			c.runBatchSchedulerHook(appToUpdate, "OnTermination", schedulerinterface.BatchScheduler.OnTermination)
+		} else if !archivingLogs && hasRetryIntervalPassed(appToUpdate.Spec.RestartPolicy.OnFailureRetryInterval, appToUpdate.Status.ExecutionAttempts, appToUpdate.Status.TerminationTime) {
 			if err := c.deleteSparkResources(appToUpdate); err != nil {
 				glog.Errorf("failed to delete resources associated with SparkApplication %s/%s: %v",
 					appToUpdate.Namespace, appToUpdate.Name, err)
@@ -617,6 +819,7 @@ func (c *Controller) syncSparkApplication(key string) error {
 			// Application is not subject to retry. Move to terminal FailedState.
 			appToUpdate.Status.AppState.State = v1beta2.FailedState
 			c.recordSparkApplicationEvent(appToUpdate)
+			c.runBatchSchedulerHook(appToUpdate, "OnTermination", schedulerinterface.BatchScheduler.OnTermination)
 		} else {
 			if appToUpdate.Spec.Mode == v1beta2.ClusterMode {
 				// Application is subject to retry. Move to PendingRerunState.
@@ -631,6 +834,7 @@ func (c *Controller) syncSparkApplication(key string) error {
 				appToUpdate.Namespace, appToUpdate.Name, err)
 			return err
 		}
+		c.runBatchSchedulerHook(appToUpdate, "OnSpecInvalidation", schedulerinterface.BatchScheduler.OnSpecInvalidation)
 		c.clearStatus(&appToUpdate.Status)
 		appToUpdate.Status.AppState.State = v1beta2.PendingRerunState
 	case v1beta2.PendingRerunState:
@@ -638,6 +842,7 @@ func (c *Controller) syncSparkApplication(key string) error {
 		if c.validateSparkResourceDeletion(appToUpdate) {
 			glog.V(2).Infof("Resources for SparkApplication %s/%s successfully deleted", appToUpdate.Namespace, appToUpdate.Name)
 			c.recordSparkApplicationEvent(appToUpdate)
+			c.runBatchSchedulerHook(appToUpdate, "OnRerun", schedulerinterface.BatchScheduler.OnRerun)
 			c.clearStatus(&appToUpdate.Status)
 			appToUpdate = c.submitSparkApplication(appToUpdate)
 		}
@@ -645,6 +850,14 @@ func (c *Controller) syncSparkApplication(key string) error {
 		if err := c.getAndUpdateAppState(appToUpdate); err != nil {
 			return err
 		}
+		if appToUpdate.Status.AppState.State == v1beta2.RunningState {
+			c.reconcileSparkUIResources(appToUpdate)
+			if c.progressPoller != nil {
+				c.updateSparkProgress(appToUpdate)
+			}
+		} else if c.progressPoller != nil {
+			c.progressPoller.forget(key)
+		}
 	case v1beta2.CompletedState, v1beta2.FailedState:
 		if appToUpdate.Spec.Mode == v1beta2.ClientMode {
 			c.deleteSparkUI(appToUpdate)
@@ -696,6 +909,21 @@ func (c *Controller) submitSparkApplication(app *v1beta2.SparkApplication) *v1be
 		if err := configPrometheusMonitoring(app, c.kubeClient); err != nil {
 			glog.Error(err)
 		}
+	} else if app.PrometheusServletMonitoringEnabled() {
+		configPrometheusServletMonitoring(app)
+	}
+	if c.serviceMonitorConfig != nil {
+		if err := createMetricsServiceMonitor(app, c.serviceMonitorConfig, c.kubeClient, c.dynamicClient); err != nil {
+			glog.Error(err)
+		}
+	}
+
+	configEventLog(app, c.eventLogConfig)
+	if c.enableUIProxy {
+		configSparkUIProxyBase(app, uiproxy.GetProxyBase(app.Namespace, app.Name))
+	}
+	if c.enableUIService && c.ingressURLFormat != "" {
+		configSparkUIIngressPath(app, c.ingressURLFormat)
 	}
 
 	// Use batch scheduler to perform scheduling task before submitting.
@@ -710,6 +938,7 @@ func (c *Controller) submitSparkApplication(app *v1beta2.SparkApplication) *v1be
 	var submissionID string
 	var driverPodName string
 	var err error
+	attemptTime := metav1.Now()
 
 	if app.Spec.Mode == v1beta2.ClientMode {
 		submissionID, driverPodName, err = c.clientModeSubPodManager.createClientDriverPod(app)
@@ -725,7 +954,8 @@ func (c *Controller) submitSparkApplication(app *v1beta2.SparkApplication) *v1be
 						State:        v1beta2.PendingSubmissionState,
 						ErrorMessage: err.Error(),
 					},
-					SubmissionAttempts: app.Status.SubmissionAttempts + 1,
+					SubmissionAttempts:        app.Status.SubmissionAttempts + 1,
+					LastSubmissionAttemptTime: attemptTime,
 				}
 			} else {
 				app.Status = v1beta2.SparkApplicationStatus{
@@ -733,7 +963,8 @@ func (c *Controller) submitSparkApplication(app *v1beta2.SparkApplication) *v1be
 						State:        v1beta2.FailedSubmissionState,
 						ErrorMessage: err.Error(),
 					},
-					SubmissionAttempts: app.Status.SubmissionAttempts,
+					SubmissionAttempts:        app.Status.SubmissionAttempts,
+					LastSubmissionAttemptTime: attemptTime,
 				}
 			}
 		} else if !errors.IsAlreadyExists(err) || app.Spec.Mode == v1beta2.ClientMode {
@@ -742,11 +973,13 @@ func (c *Controller) submitSparkApplication(app *v1beta2.SparkApplication) *v1be
 					State:        v1beta2.FailedSubmissionState,
 					ErrorMessage: err.Error(),
 				},
-				SubmissionAttempts: app.Status.SubmissionAttempts + 1,
+				SubmissionAttempts:        app.Status.SubmissionAttempts + 1,
+				LastSubmissionAttemptTime: attemptTime,
 			}
 		}
 
 		c.recordSparkApplicationEvent(app)
+		c.recordSubmissionSpan(app, attemptTime.Time, err)
 		return app
 	}
 
@@ -758,14 +991,16 @@ func (c *Controller) submitSparkApplication(app *v1beta2.SparkApplication) *v1be
 		appState = v1beta2.PendingSubmissionState
 	}
 	app.Status = v1beta2.SparkApplicationStatus{
-		SubmissionID:       submissionID,
-		DriverInfo:         v1beta2.DriverInfo{PodName: driverPodName},
-		AppState:           v1beta2.ApplicationState{State: appState},
-		SubmissionAttempts: app.Status.SubmissionAttempts + 1,
-		ExecutionAttempts:  app.Status.ExecutionAttempts + 1,
+		SubmissionID:              submissionID,
+		DriverInfo:                v1beta2.DriverInfo{PodName: driverPodName},
+		AppState:                  v1beta2.ApplicationState{State: appState},
+		SubmissionAttempts:        app.Status.SubmissionAttempts + 1,
+		ExecutionAttempts:         app.Status.ExecutionAttempts + 1,
+		LastSubmissionAttemptTime: attemptTime,
 	}
 
 	c.recordSparkApplicationEvent(app)
+	c.recordSubmissionSpan(app, attemptTime.Time, nil)
 	if app.Spec.Mode == v1beta2.ClientMode {
 		c.createSparkUIResources(app)
 	}
@@ -775,18 +1010,18 @@ func (c *Controller) submitSparkApplication(app *v1beta2.SparkApplication) *v1be
 
 func (c *Controller) createSparkUIResources(app *v1beta2.SparkApplication) {
 	if c.enableUIService {
-		service, err := createSparkUIService(app, c.kubeClient)
+		service, err := createSparkUIService(app, c.uiConfig, c.kubeClient)
 		if err != nil {
 			glog.Errorf("failed to create UI service for SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
 			return
 		}
 
 		app.Status.DriverInfo.WebUIServiceName = service.serviceName
-		app.Status.DriverInfo.WebUIAddress = fmt.Sprintf("%s:%d", service.serviceIP, app.Status.DriverInfo.WebUIPort)
 		app.Status.DriverInfo.WebUIPort = service.servicePort
+		app.Status.DriverInfo.WebUIAddress = fmt.Sprintf("%s:%d", service.serviceIP, app.Status.DriverInfo.WebUIPort)
 		// Create UI Ingress if ingress-format is set.
 		if c.ingressURLFormat != "" {
-			ingress, err := createSparkUIIngress(app, *service, c.ingressURLFormat, c.kubeClient)
+			ingress, err := createSparkUIIngress(app, *service, c.ingressURLFormat, c.uiConfig, c.kubeClient, c.dynamicClient)
 			if err != nil {
 				glog.Errorf("failed to create UI Ingress for SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
 			} else {
@@ -797,6 +1032,33 @@ func (c *Controller) createSparkUIResources(app *v1beta2.SparkApplication) {
 	}
 }
 
+// reconcileSparkUIResources brings the Service and Ingress exposing the Spark UI back to the desired state if they
+// have been deleted or modified, and keeps the driver information in the status in line with them.
+func (c *Controller) reconcileSparkUIResources(app *v1beta2.SparkApplication) {
+	if !c.enableUIService || c.serviceLister == nil {
+		return
+	}
+
+	service, err := reconcileSparkUIService(app, c.uiConfig, c.kubeClient, c.serviceLister)
+	if err != nil {
+		glog.Errorf("failed to reconcile UI service for SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+		return
+	}
+	app.Status.DriverInfo.WebUIServiceName = service.serviceName
+	app.Status.DriverInfo.WebUIPort = service.servicePort
+	app.Status.DriverInfo.WebUIAddress = fmt.Sprintf("%s:%d", service.serviceIP, app.Status.DriverInfo.WebUIPort)
+
+	if c.ingressURLFormat != "" {
+		ingress, err := reconcileSparkUIIngress(app, *service, c.ingressURLFormat, c.uiConfig, c.kubeClient, c.dynamicClient, c.ingressLister)
+		if err != nil {
+			glog.Errorf("failed to reconcile UI Ingress for SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+			return
+		}
+		app.Status.DriverInfo.WebUIIngressAddress = ingress.ingressURL
+		app.Status.DriverInfo.WebUIIngressName = ingress.ingressName
+	}
+}
+
 func (c *Controller) shouldDoBatchScheduling(app *v1beta2.SparkApplication) (bool, schedulerinterface.BatchScheduler) {
 	if c.batchSchedulerMgr == nil || app.Spec.BatchScheduler == nil || *app.Spec.BatchScheduler == "" {
 		return false, nil
@@ -810,11 +1072,28 @@ func (c *Controller) shouldDoBatchScheduling(app *v1beta2.SparkApplication) (boo
 	return scheduler.ShouldSchedule(app), scheduler
 }
 
+// runBatchSchedulerHook calls a lifecycle hook of the batch scheduler of the application, if any. Failures are only
+// logged, as the application moves on regardless.
+func (c *Controller) runBatchSchedulerHook(
+	app *v1beta2.SparkApplication,
+	hookName string,
+	hook func(schedulerinterface.BatchScheduler, *v1beta2.SparkApplication) error) {
+	needScheduling, scheduler := c.shouldDoBatchScheduling(app)
+	if !needScheduling {
+		return
+	}
+	if err := hook(scheduler, app); err != nil {
+		glog.Errorf("failed to process batch scheduler %s for SparkApplication %s/%s with error %v", hookName, app.Namespace, app.Name, err)
+	}
+}
+
 func (c *Controller) updateApplicationStatusWithRetries(
 	original *v1beta2.SparkApplication,
 	updateFunc func(status *v1beta2.SparkApplicationStatus)) (*v1beta2.SparkApplication, error) {
 	toUpdate := original.DeepCopy()
+	retries := -1
 	updateErr := wait.ExponentialBackoff(retry.DefaultBackoff, func() (ok bool, err error) {
+		retries++
 		updateFunc(&toUpdate.Status)
 		if equality.Semantic.DeepEqual(original.Status, toUpdate.Status) {
 			return true, nil
@@ -827,6 +1106,9 @@ func (c *Controller) updateApplicationStatusWithRetries(
 		if !errors.IsConflict(err) {
 			return false, err
 		}
+		if c.controllerMetrics != nil {
+			c.controllerMetrics.statusUpdateConflicts.Inc()
+		}
 
 		// There was a conflict updating the SparkApplication, fetch the latest version from the API server.
 		toUpdate, err = c.crdClient.SparkoperatorV1beta2().SparkApplications(original.Namespace).Get(original.Name, metav1.GetOptions{})
@@ -838,6 +1120,9 @@ func (c *Controller) updateApplicationStatusWithRetries(
 		// Retry with the latest version.
 		return false, nil
 	})
+	if c.controllerMetrics != nil {
+		c.controllerMetrics.statusUpdateRetries.Observe(float64(retries))
+	}
 
 	if updateErr != nil {
 		glog.Errorf("failed to update SparkApplication %s/%s: %v", original.Namespace, original.Name, updateErr)
@@ -854,6 +1139,12 @@ func (c *Controller) updateStatusAndExportMetrics(oldApp, newApp *v1beta2.SparkA
 		return nil
 	}
 
+	if newApp.Status.AppState.State != oldApp.Status.AppState.State {
+		newApp.Status.AppState.LastTransitionTime = metav1.Now()
+	} else if newApp.Status.AppState.LastTransitionTime.IsZero() {
+		newApp.Status.AppState.LastTransitionTime = oldApp.Status.AppState.LastTransitionTime
+	}
+
 	oldStatusJSON, err := printStatus(&oldApp.Status)
 	if err != nil {
 		return err
@@ -875,6 +1166,9 @@ func (c *Controller) updateStatusAndExportMetrics(oldApp, newApp *v1beta2.SparkA
 	if c.metrics != nil {
 		c.metrics.exportMetrics(oldApp, updatedApp)
 	}
+	c.recordTransitionSpans(oldApp, updatedApp)
+	c.notifyStateTransition(oldApp, updatedApp)
+	c.emitCloudEvent(oldApp, updatedApp)
 
 	return nil
 }
@@ -936,7 +1230,7 @@ func (c *Controller) deleteSparkResources(app *v1beta2.SparkApplication) error {
 	sparkUIIngressName := app.Status.DriverInfo.WebUIIngressName
 	if sparkUIIngressName != "" {
 		glog.V(2).Infof("Deleting Spark UI Ingress %s in namespace %s", sparkUIIngressName, app.Namespace)
-		err := c.kubeClient.ExtensionsV1beta1().Ingresses(app.Namespace).Delete(sparkUIIngressName, metav1.NewDeleteOptions(0))
+		err := deleteSparkUIIngress(app, sparkUIIngressName, c.uiConfig, c.kubeClient, c.dynamicClient)
 		if err != nil && !errors.IsNotFound(err) {
 			return err
 		}
@@ -952,6 +1246,9 @@ func (c *Controller) validateSparkApplication(app *v1beta2.SparkApplication) err
 	if appSpec.NodeSelector != nil && (driverSpec.NodeSelector != nil || executorSpec.NodeSelector != nil) {
 		return fmt.Errorf("NodeSelector property can be defined at SparkApplication or at any of Driver,Executor")
 	}
+	if app.PrometheusMonitoringEnabled() && app.PrometheusServletMonitoringEnabled() {
+		return fmt.Errorf("only one of Prometheus and PrometheusServlet monitoring can be configured")
+	}
 
 	return nil
 }
@@ -984,7 +1281,7 @@ func (c *Controller) validateSparkResourceDeletion(app *v1beta2.SparkApplication
 
 	sparkUIIngressName := app.Status.DriverInfo.WebUIIngressName
 	if sparkUIIngressName != "" {
-		_, err := c.kubeClient.ExtensionsV1beta1().Ingresses(app.Namespace).Get(sparkUIIngressName, metav1.GetOptions{})
+		err := getSparkUIIngress(app, sparkUIIngressName, c.uiConfig, c.kubeClient, c.dynamicClient)
 		if err == nil || !errors.IsNotFound(err) {
 			return false
 		}
@@ -1066,6 +1363,14 @@ func (c *Controller) recordSparkApplicationEvent(app *v1beta2.SparkApplication)
 			"SparkApplicationPendingRerun",
 			"SparkApplication %s is pending rerun",
 			app.Name)
+	case v1beta2.QueuedState:
+		c.recorder.Eventf(
+			app,
+			apiv1.EventTypeNormal,
+			"SparkApplicationQueued",
+			"SparkApplication %s was queued: %s",
+			app.Name,
+			app.Status.QueueStatus.Reason)
 	}
 }
 
@@ -1107,11 +1412,14 @@ func (c *Controller) clearStatus(status *v1beta2.SparkApplicationStatus) {
 		status.TerminationTime = metav1.Time{}
 		status.AppState.ErrorMessage = ""
 		status.ExecutorState = nil
+		status.SparkProgress = nil
 	} else if status.AppState.State == v1beta2.PendingRerunState {
 		status.SparkApplicationID = ""
 		status.DriverInfo = v1beta2.DriverInfo{}
 		status.AppState.ErrorMessage = ""
 		status.ExecutorState = nil
+		status.LogArchiveLocations = nil
+		status.SparkProgress = nil
 	}
 }
 
@@ -0,0 +1,95 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+const unknownErrorReason = "Unknown"
+
+// controllerMetrics holds the metrics of the health of the controller itself, as opposed to the metrics of the
+// applications it manages.
+type controllerMetrics struct {
+	reconcileDuration     *prometheus.HistogramVec
+	reconcileErrors       *prometheus.CounterVec
+	statusUpdateConflicts prometheus.Counter
+	statusUpdateRetries   prometheus.Histogram
+}
+
+func newControllerMetrics(metricsConfig *util.MetricConfig) *controllerMetrics {
+	prefix := metricsConfig.MetricsPrefix
+	return &controllerMetrics{
+		reconcileDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    util.CreateValidMetricNameLabel(prefix, "spark_app_reconcile_duration_seconds"),
+				Help:    "Duration of the reconciliations of Spark Apps by the state of the apps",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{stateMetricLabel},
+		),
+		reconcileErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: util.CreateValidMetricNameLabel(prefix, "spark_app_reconcile_error_count"),
+				Help: "Number of failed reconciliations of Spark Apps by the reason of the failure",
+			},
+			[]string{"reason"},
+		),
+		statusUpdateConflicts: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: util.CreateValidMetricNameLabel(prefix, "spark_app_status_update_conflict_count"),
+				Help: "Number of conflicts updating the status of Spark Apps",
+			},
+		),
+		statusUpdateRetries: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    util.CreateValidMetricNameLabel(prefix, "spark_app_status_update_retries"),
+				Help:    "Number of retries of the updates of the status of Spark Apps",
+				Buckets: []float64{0, 1, 2, 3, 4},
+			},
+		),
+	}
+}
+
+func (cm *controllerMetrics) registerMetrics() {
+	util.RegisterMetric(cm.reconcileDuration)
+	util.RegisterMetric(cm.reconcileErrors)
+	util.RegisterMetric(cm.statusUpdateConflicts)
+	util.RegisterMetric(cm.statusUpdateRetries)
+}
+
+// observeReconcile records the duration of a reconciliation started at the given time of an application in the
+// given state.
+func (cm *controllerMetrics) observeReconcile(state v1beta2.ApplicationStateType, start time.Time) {
+	cm.reconcileDuration.WithLabelValues(getStateMetricLabelValue(state)).Observe(time.Since(start).Seconds())
+}
+
+// recordReconcileError records a failed reconciliation. Errors returned by the API server are counted by their
+// reason, e.g., Conflict or NotFound.
+func (cm *controllerMetrics) recordReconcileError(err error) {
+	reason := string(errors.ReasonForError(err))
+	if reason == "" {
+		reason = unknownErrorReason
+	}
+	cm.reconcileErrors.WithLabelValues(reason).Inc()
+}
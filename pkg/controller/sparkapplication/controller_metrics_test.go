@@ -0,0 +1,73 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	prometheus_model "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func TestRecordReconcileError(t *testing.T) {
+	ctrl, _ := newFakeController(nil, nil)
+	resource := schema.GroupResource{Group: v1beta2.SchemeGroupVersion.Group, Resource: "sparkapplications"}
+
+	ctrl.controllerMetrics.recordReconcileError(apiErrors.NewConflict(resource, "foo", fmt.Errorf("conflict")))
+	ctrl.controllerMetrics.recordReconcileError(apiErrors.NewNotFound(resource, "foo"))
+	ctrl.controllerMetrics.recordReconcileError(fmt.Errorf("failed to sync"))
+
+	assert.Equal(t, float64(1), fetchCounterValue(ctrl.controllerMetrics.reconcileErrors,
+		map[string]string{"reason": string(metav1.StatusReasonConflict)}))
+	assert.Equal(t, float64(1), fetchCounterValue(ctrl.controllerMetrics.reconcileErrors,
+		map[string]string{"reason": string(metav1.StatusReasonNotFound)}))
+	assert.Equal(t, float64(1), fetchCounterValue(ctrl.controllerMetrics.reconcileErrors,
+		map[string]string{"reason": unknownErrorReason}))
+}
+
+func TestSyncSparkApplicationExportsControllerMetrics(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: v1beta2.FailedSubmissionState},
+		},
+	}
+	ctrl, _ := newFakeController(app, nil)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(app); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ctrl.syncSparkApplication("default/foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	reconcileDuration := fetchHistogram(ctrl.controllerMetrics.reconcileDuration,
+		map[string]string{"state": string(v1beta2.FailedSubmissionState)})
+	assert.Equal(t, uint64(1), reconcileDuration.GetSampleCount())
+
+	pb := &prometheus_model.Metric{}
+	ctrl.controllerMetrics.statusUpdateRetries.(prometheus.Metric).Write(pb)
+	assert.Equal(t, uint64(1), pb.GetHistogram().GetSampleCount())
+	assert.Equal(t, float64(0), pb.GetHistogram().GetSampleSum())
+}
@@ -33,6 +33,9 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/informers"
 	kubeclientfake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -40,6 +43,7 @@ import (
 	"k8s.io/client-go/tools/record"
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/batchscheduler"
 	crdclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
 	crdinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
@@ -69,7 +73,7 @@ func newFakeController(app *v1beta2.SparkApplication, jobManager submissionJobMa
 
 	podInformerFactory := informers.NewSharedInformerFactory(kubeClient, 0*time.Second)
 	controller := newSparkApplicationController(crdClient, kubeClient, informerFactory, podInformerFactory, recorder,
-		&util.MetricConfig{}, "", nil, true)
+		&util.MetricConfig{}, "", nil, true, Options{})
 	controller.subJobManager = jobManager
 	informer := informerFactory.Sparkoperator().V1beta2().SparkApplications().Informer()
 	if app != nil {
@@ -356,6 +360,7 @@ func TestSyncSparkApplication_Submission(t *testing.T) {
 	updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(app.Name, metav1.GetOptions{})
 	assert.Nil(t, err)
 	assert.Equal(t, v1beta2.SubmittedState, updatedApp.Status.AppState.State)
+	assert.False(t, updatedApp.Status.AppState.LastTransitionTime.IsZero())
 	assert.Equal(t, float64(1), fetchCounterValue(ctrl.metrics.sparkAppSubmitCount, map[string]string{}))
 
 	event = <-recorder.Events
@@ -485,6 +490,30 @@ func TestValidateDetectsNodeSelectorFailsAppAndPodLevel(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestValidateDetectsConflictingMonitoring(t *testing.T) {
+	ctrl, _ := newFakeController(nil, nil)
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Monitoring: &v1beta2.MonitoringSpec{
+				ExposeDriverMetrics: true,
+				PrometheusServlet:   &v1beta2.PrometheusServletSpec{},
+			},
+		},
+	}
+
+	err := ctrl.validateSparkApplication(app)
+	assert.Nil(t, err)
+
+	app.Spec.Monitoring.Prometheus = &v1beta2.PrometheusSpec{JmxExporterJar: "/prometheus/exporter.jar"}
+	err = ctrl.validateSparkApplication(app)
+	assert.NotNil(t, err)
+}
+
 //change this test back to true, as of now retries are based on onFailureRetries
 func TestShouldRetry(t *testing.T) {
 	type testcase struct {
@@ -1434,6 +1463,94 @@ func TestSyncSparkApplication_ExecutingState(t *testing.T) {
 	}
 }
 
+func TestSyncSparkApplication_ReconcileSparkUIService(t *testing.T) {
+	appName := "foo"
+	driverPodName := appName + "-driver"
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      appName,
+			Namespace: "test",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Mode: v1beta2.ClusterMode,
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{
+				State: v1beta2.RunningState,
+			},
+			DriverInfo: v1beta2.DriverInfo{
+				PodName:          driverPodName,
+				WebUIServiceName: "stale-ui-svc",
+				WebUIPort:        8080,
+			},
+		},
+	}
+	driverPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      driverPodName,
+			Namespace: "test",
+			Labels: map[string]string{
+				config.SparkRoleLabel:    config.SparkDriverRole,
+				config.SparkAppNameLabel: appName,
+			},
+		},
+		Status: apiv1.PodStatus{
+			Phase: apiv1.PodRunning,
+		},
+	}
+
+	ctrl, _ := newFakeController(app, nil, driverPod)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(app); err != nil {
+		t.Fatal(err)
+	}
+	err := ctrl.syncSparkApplication(fmt.Sprintf("%s/%s", app.Namespace, app.Name))
+	assert.Nil(t, err)
+
+	// The UI Service that does not exist is recreated and the driver information points to it.
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.RunningState, updatedApp.Status.AppState.State)
+	assert.Equal(t, getDefaultUIServiceName(app), updatedApp.Status.DriverInfo.WebUIServiceName)
+	assert.Equal(t, int32(4040), updatedApp.Status.DriverInfo.WebUIPort)
+	_, err = ctrl.kubeClient.CoreV1().Services(app.Namespace).Get(getDefaultUIServiceName(app), metav1.GetOptions{})
+	assert.Nil(t, err)
+}
+
+func TestNetworkingV1IngressEventEnqueuesApplication(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "test",
+		},
+	}
+	crdClient := crdclientfake.NewSimpleClientset()
+	crdInformerFactory := crdinformers.NewSharedInformerFactory(crdClient, 0*time.Second)
+	kubeClient := kubeclientfake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, 0*time.Second)
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	dynamicInformerFactory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 0*time.Second)
+	ctrl := newSparkApplicationController(crdClient, kubeClient, crdInformerFactory, informerFactory,
+		record.NewFakeRecorder(3), nil, "{{$appName}}.example.com", nil, true, Options{
+			UIConfig:               &SparkUIConfig{IngressAPIVersion: IngressAPIVersionNetworkingV1},
+			DynamicClient:          dynamicClient,
+			DynamicInformerFactory: dynamicInformerFactory,
+		})
+	crdInformerFactory.Sparkoperator().V1beta2().SparkApplications().Informer().GetIndexer().Add(app)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	dynamicInformerFactory.Start(stopCh)
+	dynamicInformerFactory.WaitForCacheSync(stopCh)
+
+	// Modifying the Ingress exposing the UI of the application triggers a sync of the application.
+	ingress := buildNetworkingV1Ingress(app, sparkUIIngress{name: "foo-ui-ingress"})
+	_, err := dynamicClient.Resource(networkingV1IngressResource).Namespace(app.Namespace).Create(ingress, metav1.CreateOptions{})
+	assert.Nil(t, err)
+	key, quit := ctrl.queue.Get()
+	assert.False(t, quit)
+	assert.Equal(t, "test/foo", key)
+}
+
 func TestSyncSparkApplication_ApplicationExpired(t *testing.T) {
 	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
 	os.Setenv(kubernetesServicePortEnvVar, "443")
@@ -1481,6 +1598,138 @@ func TestSyncSparkApplication_ApplicationExpired(t *testing.T) {
 	assert.True(t, apiErrors.IsNotFound(err))
 }
 
+type fakeBatchScheduler struct {
+	calls map[string]int
+}
+
+func (s *fakeBatchScheduler) Name() string {
+	return "fake"
+}
+
+func (s *fakeBatchScheduler) ShouldSchedule(app *v1beta2.SparkApplication) bool {
+	return true
+}
+
+func (s *fakeBatchScheduler) DoBatchSchedulingOnSubmission(app *v1beta2.SparkApplication) error {
+	s.calls["DoBatchSchedulingOnSubmission"]++
+	return nil
+}
+
+func (s *fakeBatchScheduler) OnSpecInvalidation(app *v1beta2.SparkApplication) error {
+	s.calls["OnSpecInvalidation"]++
+	return nil
+}
+
+func (s *fakeBatchScheduler) OnRerun(app *v1beta2.SparkApplication) error {
+	s.calls["OnRerun"]++
+	return nil
+}
+
+func (s *fakeBatchScheduler) OnTermination(app *v1beta2.SparkApplication) error {
+	s.calls["OnTermination"]++
+	return nil
+}
+
+func (s *fakeBatchScheduler) OnDeletion(app *v1beta2.SparkApplication) error {
+	s.calls["OnDeletion"]++
+	return nil
+}
+
+func TestSyncSparkApplication_BatchSchedulerHooks(t *testing.T) {
+	type testcase struct {
+		name          string
+		state         v1beta2.ApplicationStateType
+		deleted       bool
+		expectedCalls map[string]int
+	}
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	mockJobManager := fakeSubmissionJobManager{
+		createSubmissionJobCb: func(app *v1beta2.SparkApplication) (string, string, error) {
+			return "uuid", "foo-driver", nil
+		},
+		deleteSubmissionJobCb: func(app *v1beta2.SparkApplication) error {
+			return nil
+		},
+		getSubmissionJobCb: func(app *v1beta2.SparkApplication) (*batchv1.Job, error) {
+			return nil, apiErrors.NewNotFound(batchv1.Resource("jobs"), app.Name)
+		},
+	}
+
+	testcases := []testcase{
+		{
+			name:          "succeeding application completes",
+			state:         v1beta2.SucceedingState,
+			expectedCalls: map[string]int{"OnTermination": 1},
+		},
+		{
+			name:          "failing application fails",
+			state:         v1beta2.FailingState,
+			expectedCalls: map[string]int{"OnTermination": 1},
+		},
+		{
+			name:          "application failing submission fails",
+			state:         v1beta2.FailedSubmissionState,
+			expectedCalls: map[string]int{"OnTermination": 1},
+		},
+		{
+			name:          "invalidated application",
+			state:         v1beta2.InvalidatingState,
+			expectedCalls: map[string]int{"OnSpecInvalidation": 1},
+		},
+		{
+			name:          "application pending rerun is resubmitted",
+			state:         v1beta2.PendingRerunState,
+			expectedCalls: map[string]int{"OnRerun": 1, "DoBatchSchedulingOnSubmission": 1},
+		},
+		{
+			name:          "deleted application",
+			state:         v1beta2.RunningState,
+			deleted:       true,
+			expectedCalls: map[string]int{"OnDeletion": 1},
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			app := &v1beta2.SparkApplication{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "test",
+				},
+				Spec: v1beta2.SparkApplicationSpec{
+					BatchScheduler: stringptr("fake"),
+					RestartPolicy: v1beta2.RestartPolicy{
+						Type: v1beta2.Never,
+					},
+				},
+				Status: v1beta2.SparkApplicationStatus{
+					AppState: v1beta2.ApplicationState{
+						State: test.state,
+					},
+				},
+			}
+			if test.deleted {
+				now := metav1.Now()
+				app.DeletionTimestamp = &now
+			}
+
+			ctrl, _ := newFakeController(app, &mockJobManager)
+			scheduler := &fakeBatchScheduler{calls: map[string]int{}}
+			ctrl.batchSchedulerMgr = batchscheduler.NewSchedulerManagerWithPlugins(nil, scheduler)
+			_, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(app)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = ctrl.syncSparkApplication(fmt.Sprintf("%s/%s", app.Namespace, app.Name))
+			assert.Nil(t, err)
+			assert.Equal(t, test.expectedCalls, scheduler.calls)
+		})
+	}
+}
+
 func TestHasRetryIntervalPassed(t *testing.T) {
 	// Failure cases.
 	assert.False(t, hasRetryIntervalPassed(nil, 3, metav1.Time{Time: metav1.Now().Add(-100 * time.Second)}))
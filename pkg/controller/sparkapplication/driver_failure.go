@@ -0,0 +1,102 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"github.com/golang/glog"
+	apiv1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+const (
+	driverFailureLogKey                = "driver.log"
+	driverFailureTerminationMessageKey = "terminationMessage"
+	// driverFailureLogLimitBytes bounds the size of the captured log tail so that the ConfigMap
+	// stays well below the size limit of Kubernetes objects.
+	driverFailureLogLimitBytes int64 = 256 * 1024
+)
+
+// getPodLogs fetches the logs of a pod container. It is a variable so that it can be stubbed out in tests.
+var getPodLogs = func(kubeClient clientset.Interface, namespace string, podName string, options *apiv1.PodLogOptions) ([]byte, error) {
+	return kubeClient.CoreV1().Pods(namespace).GetLogs(podName, options).Do().Raw()
+}
+
+// captureDriverFailure stores the tail of the driver container logs together with the container termination
+// message in a ConfigMap owned by the application, and references the ConfigMap from the application status.
+func (c *Controller) captureDriverFailure(app *v1beta2.SparkApplication, driverPod *apiv1.Pod, state *apiv1.ContainerStateTerminated) {
+	tailLines := c.driverFailureLogTailLines
+	limitBytes := driverFailureLogLimitBytes
+	logs, err := getPodLogs(c.kubeClient, app.Namespace, driverPod.Name, &apiv1.PodLogOptions{
+		Container:  config.SparkDriverContainerName,
+		TailLines:  &tailLines,
+		LimitBytes: &limitBytes,
+	})
+	if err != nil {
+		// Still record the termination message, which is often enough to tell why the driver failed.
+		glog.Errorf("failed to get logs of driver pod %s/%s: %v", app.Namespace, driverPod.Name, err)
+	}
+
+	configMapName := getDriverFailureConfigMapName(app)
+	configMap := buildDriverFailureConfigMap(app, configMapName, string(logs), state.Message)
+	retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := c.kubeClient.CoreV1().ConfigMaps(app.Namespace).Get(configMapName, metav1.GetOptions{})
+		if apiErrors.IsNotFound(err) {
+			_, createErr := c.kubeClient.CoreV1().ConfigMaps(app.Namespace).Create(configMap)
+			return createErr
+		}
+		if err != nil {
+			return err
+		}
+
+		cm.Data = configMap.Data
+		_, updateErr := c.kubeClient.CoreV1().ConfigMaps(app.Namespace).Update(cm)
+		return updateErr
+	})
+	if retryErr != nil {
+		glog.Errorf("failed to apply %s in namespace %s: %v", configMapName, app.Namespace, retryErr)
+		return
+	}
+
+	app.Status.DriverFailureSnapshot = &v1beta2.DriverFailureSnapshot{
+		ConfigMapName: configMapName,
+		PodName:       driverPod.Name,
+		ExitCode:      state.ExitCode,
+		Reason:        state.Reason,
+		CaptureTime:   metav1.Now(),
+	}
+}
+
+func buildDriverFailureConfigMap(app *v1beta2.SparkApplication, name string, logs string, terminationMessage string) *apiv1.ConfigMap {
+	return &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       app.Namespace,
+			Labels:          map[string]string{config.SparkAppNameLabel: app.Name},
+			OwnerReferences: []metav1.OwnerReference{*getOwnerReference(app)},
+		},
+		Data: map[string]string{
+			driverFailureLogKey:                logs,
+			driverFailureTerminationMessageKey: terminationMessage,
+		},
+	}
+}
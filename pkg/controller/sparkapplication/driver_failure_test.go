@@ -0,0 +1,96 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestCaptureDriverFailure(t *testing.T) {
+	defer func(original func(clientset.Interface, string, string, *apiv1.PodLogOptions) ([]byte, error)) {
+		getPodLogs = original
+	}(getPodLogs)
+
+	var logOptions *apiv1.PodLogOptions
+	logsErr := error(nil)
+	getPodLogs = func(kubeClient clientset.Interface, namespace string, podName string, options *apiv1.PodLogOptions) ([]byte, error) {
+		logOptions = options
+		if logsErr != nil {
+			return nil, logsErr
+		}
+		return []byte("Exception in thread \"main\"\n"), nil
+	}
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+	}
+	driverPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-driver",
+			Namespace: "default",
+		},
+	}
+	state := &apiv1.ContainerStateTerminated{
+		ExitCode: 1,
+		Reason:   "Error",
+		Message:  "java.lang.OutOfMemoryError",
+	}
+
+	ctrl, _ := newFakeController(app, nil)
+	ctrl.driverFailureLogTailLines = 50
+	ctrl.captureDriverFailure(app, driverPod, state)
+
+	assert.Equal(t, config.SparkDriverContainerName, logOptions.Container)
+	assert.Equal(t, int64(50), *logOptions.TailLines)
+	assert.Equal(t, driverFailureLogLimitBytes, *logOptions.LimitBytes)
+
+	configMapName := getDriverFailureConfigMapName(app)
+	cm, err := ctrl.kubeClient.CoreV1().ConfigMaps(app.Namespace).Get(configMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", cm.Labels[config.SparkAppNameLabel])
+	assert.Equal(t, 1, len(cm.OwnerReferences))
+	assert.Equal(t, "Exception in thread \"main\"\n", cm.Data[driverFailureLogKey])
+	assert.Equal(t, "java.lang.OutOfMemoryError", cm.Data[driverFailureTerminationMessageKey])
+
+	assert.NotNil(t, app.Status.DriverFailureSnapshot)
+	assert.Equal(t, configMapName, app.Status.DriverFailureSnapshot.ConfigMapName)
+	assert.Equal(t, "foo-driver", app.Status.DriverFailureSnapshot.PodName)
+	assert.Equal(t, int32(1), app.Status.DriverFailureSnapshot.ExitCode)
+	assert.Equal(t, "Error", app.Status.DriverFailureSnapshot.Reason)
+
+	// A failure to get the logs still updates the ConfigMap with the termination message.
+	logsErr = fmt.Errorf("logs unavailable")
+	state.Message = "killed"
+	ctrl.captureDriverFailure(app, driverPod, state)
+
+	cm, err = ctrl.kubeClient.CoreV1().ConfigMaps(app.Namespace).Get(configMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", cm.Data[driverFailureLogKey])
+	assert.Equal(t, "killed", cm.Data[driverFailureTerminationMessageKey])
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// EventLogConfig holds the operator-level defaults of Spark event logging and the History Server integration.
+type EventLogConfig struct {
+	// DefaultDir is the default base directory of event logs. Event logging is enabled for all applications
+	// by default if it is set.
+	DefaultDir string
+	// DefaultCompress specifies whether to compress event logs by default.
+	DefaultCompress bool
+	// HistoryServerURLFormat is the format of the URLs of finished applications in the Spark History Server.
+	HistoryServerURLFormat string
+}
+
+var (
+	historyServerAppIDRegex        = regexp.MustCompile("{{\\s*[$]appID\\s*}}")
+	historyServerAppNameRegex      = regexp.MustCompile("{{\\s*[$]appName\\s*}}")
+	historyServerAppNamespaceRegex = regexp.MustCompile("{{\\s*[$]appNamespace\\s*}}")
+)
+
+// configEventLog sets the Spark event logging configuration properties of the application based on the
+// eventLog section of the application spec and the operator-level defaults. Configuration properties
+// explicitly set in the sparkConf of the application take precedence.
+func configEventLog(app *v1beta2.SparkApplication, eventLogConfig *EventLogConfig) {
+	if !isEventLogEnabled(app, eventLogConfig) {
+		return
+	}
+
+	var dir string
+	var compress bool
+	if eventLogConfig != nil {
+		dir = eventLogConfig.DefaultDir
+		compress = eventLogConfig.DefaultCompress
+	}
+	if app.Spec.EventLog != nil {
+		if app.Spec.EventLog.Dir != nil {
+			dir = *app.Spec.EventLog.Dir
+		}
+		if app.Spec.EventLog.Compress != nil {
+			compress = *app.Spec.EventLog.Compress
+		}
+	}
+
+	if app.Spec.SparkConf == nil {
+		app.Spec.SparkConf = make(map[string]string)
+	}
+	setSparkConfIfAbsent(app, config.SparkEventLogEnabledKey, "true")
+	// Spark falls back to its own default event log directory if none is configured.
+	if dir != "" {
+		setSparkConfIfAbsent(app, config.SparkEventLogDirKey, dir)
+	}
+	setSparkConfIfAbsent(app, config.SparkEventLogCompressKey, strconv.FormatBool(compress))
+}
+
+// isEventLogEnabled tells if event logging is enabled for the application, either explicitly in the sparkConf,
+// through the eventLog section of the spec, or through the operator-level defaults.
+func isEventLogEnabled(app *v1beta2.SparkApplication, eventLogConfig *EventLogConfig) bool {
+	if value, ok := app.Spec.SparkConf[config.SparkEventLogEnabledKey]; ok {
+		enabled, _ := strconv.ParseBool(value)
+		return enabled
+	}
+	if app.Spec.EventLog != nil {
+		return app.Spec.EventLog.Enabled == nil || *app.Spec.EventLog.Enabled
+	}
+	return eventLogConfig != nil && eventLogConfig.DefaultDir != ""
+}
+
+func setSparkConfIfAbsent(app *v1beta2.SparkApplication, key string, value string) {
+	if _, ok := app.Spec.SparkConf[key]; !ok {
+		app.Spec.SparkConf[key] = value
+	}
+}
+
+func getHistoryServerURL(historyServerURLFormat string, app *v1beta2.SparkApplication) string {
+	url := historyServerAppIDRegex.ReplaceAllString(historyServerURLFormat, app.Status.SparkApplicationID)
+	url = historyServerAppNameRegex.ReplaceAllString(url, app.Name)
+	return historyServerAppNamespaceRegex.ReplaceAllString(url, app.Namespace)
+}
+
+// setHistoryServerURL records the URL of the finished application in the Spark History Server in the status
+// if event logging is enabled for the application.
+func (c *Controller) setHistoryServerURL(app *v1beta2.SparkApplication) {
+	if c.eventLogConfig == nil || c.eventLogConfig.HistoryServerURLFormat == "" {
+		return
+	}
+	if app.Status.SparkApplicationID == "" || !isEventLogEnabled(app, c.eventLogConfig) {
+		return
+	}
+	app.Status.DriverInfo.HistoryServerURL = getHistoryServerURL(c.eventLogConfig.HistoryServerURLFormat, app)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestConfigEventLog(t *testing.T) {
+	type testcase struct {
+		name           string
+		spec           v1beta2.SparkApplicationSpec
+		eventLogConfig *EventLogConfig
+		expectedConf   map[string]string
+	}
+
+	enabled := true
+	disabled := false
+	dir := "s3a://bucket/app-events"
+	testFn := func(test testcase, t *testing.T) {
+		app := &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec:       test.spec,
+		}
+		configEventLog(app, test.eventLogConfig)
+		assert.Equal(t, test.expectedConf, app.Spec.SparkConf, test.name)
+	}
+
+	testcases := []testcase{
+		{
+			name:           "event logging disabled by default",
+			spec:           v1beta2.SparkApplicationSpec{},
+			eventLogConfig: &EventLogConfig{},
+			expectedConf:   nil,
+		},
+		{
+			name:           "no operator-level config",
+			spec:           v1beta2.SparkApplicationSpec{EventLog: &v1beta2.EventLogSpec{Dir: &dir}},
+			eventLogConfig: nil,
+			expectedConf: map[string]string{
+				config.SparkEventLogEnabledKey:  "true",
+				config.SparkEventLogDirKey:      dir,
+				config.SparkEventLogCompressKey: "false",
+			},
+		},
+		{
+			name:           "operator-level defaults",
+			spec:           v1beta2.SparkApplicationSpec{},
+			eventLogConfig: &EventLogConfig{DefaultDir: "gs://bucket/events", DefaultCompress: true},
+			expectedConf: map[string]string{
+				config.SparkEventLogEnabledKey:  "true",
+				config.SparkEventLogDirKey:      "gs://bucket/events",
+				config.SparkEventLogCompressKey: "true",
+			},
+		},
+		{
+			name:           "spec overrides operator-level defaults",
+			spec:           v1beta2.SparkApplicationSpec{EventLog: &v1beta2.EventLogSpec{Dir: &dir, Compress: &disabled}},
+			eventLogConfig: &EventLogConfig{DefaultDir: "gs://bucket/events", DefaultCompress: true},
+			expectedConf: map[string]string{
+				config.SparkEventLogEnabledKey:  "true",
+				config.SparkEventLogDirKey:      dir,
+				config.SparkEventLogCompressKey: "false",
+			},
+		},
+		{
+			name:           "disabled in spec",
+			spec:           v1beta2.SparkApplicationSpec{EventLog: &v1beta2.EventLogSpec{Enabled: &disabled}},
+			eventLogConfig: &EventLogConfig{DefaultDir: "gs://bucket/events"},
+			expectedConf:   nil,
+		},
+		{
+			name: "sparkConf takes precedence",
+			spec: v1beta2.SparkApplicationSpec{
+				EventLog:  &v1beta2.EventLogSpec{Enabled: &enabled},
+				SparkConf: map[string]string{config.SparkEventLogDirKey: "hdfs://namenode/events"},
+			},
+			eventLogConfig: &EventLogConfig{DefaultDir: "gs://bucket/events"},
+			expectedConf: map[string]string{
+				config.SparkEventLogEnabledKey:  "true",
+				config.SparkEventLogDirKey:      "hdfs://namenode/events",
+				config.SparkEventLogCompressKey: "false",
+			},
+		},
+		{
+			name: "disabled in sparkConf",
+			spec: v1beta2.SparkApplicationSpec{
+				SparkConf: map[string]string{config.SparkEventLogEnabledKey: "false"},
+			},
+			eventLogConfig: &EventLogConfig{DefaultDir: "gs://bucket/events"},
+			expectedConf:   map[string]string{config.SparkEventLogEnabledKey: "false"},
+		},
+	}
+
+	for _, test := range testcases {
+		testFn(test, t)
+	}
+}
+
+func TestSetHistoryServerURL(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status: v1beta2.SparkApplicationStatus{
+			SparkApplicationID: "spark-123",
+		},
+	}
+
+	ctrl, _ := newFakeController(app, nil)
+	ctrl.setHistoryServerURL(app)
+	assert.Equal(t, "", app.Status.DriverInfo.HistoryServerURL)
+
+	ctrl.eventLogConfig = &EventLogConfig{
+		DefaultDir:             "gs://bucket/events",
+		HistoryServerURLFormat: "https://history.example.com/{{$appNamespace}}/{{ $appName }}/history/{{$appID}}",
+	}
+	ctrl.setHistoryServerURL(app)
+	assert.Equal(t, "https://history.example.com/default/foo/history/spark-123", app.Status.DriverInfo.HistoryServerURL)
+
+	// No URL is recorded if event logging is disabled for the application.
+	app.Status.DriverInfo.HistoryServerURL = ""
+	disabled := false
+	app.Spec.EventLog = &v1beta2.EventLogSpec{Enabled: &disabled}
+	ctrl.setHistoryServerURL(app)
+	assert.Equal(t, "", app.Status.DriverInfo.HistoryServerURL)
+}
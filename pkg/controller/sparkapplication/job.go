@@ -36,13 +36,6 @@ import (
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
 )
 
-const (
-	sparkSubmitPodMemoryRequest = "500Mi"
-	sparkSubmitPodCpuRequest    = "512m"
-	sparkSubmitPodMemoryLimit   = "1000Mi"
-	sparkSubmitPodCpuLimit      = "1024m"
-)
-
 type submissionJobManager interface {
 	createSubmissionJob(app *v1beta2.SparkApplication) (string, string, error)
 	deleteSubmissionJob(app *v1beta2.SparkApplication) error
@@ -118,12 +111,12 @@ func (sjm *realSubmissionJobManager) createSubmissionJob(app *v1beta2.SparkAppli
 							ImagePullPolicy: imagePullPolicy,
 							Resources: corev1.ResourceRequirements{
 								Requests: corev1.ResourceList{
-									corev1.ResourceCPU:    resource.MustParse(sparkSubmitPodCpuRequest),
-									corev1.ResourceMemory: resource.MustParse(sparkSubmitPodMemoryRequest),
+									corev1.ResourceCPU:    resource.MustParse(config.SparkSubmitPodCPURequest),
+									corev1.ResourceMemory: resource.MustParse(config.SparkSubmitPodMemoryRequest),
 								},
 								Limits: corev1.ResourceList{
-									corev1.ResourceCPU:    resource.MustParse(sparkSubmitPodCpuLimit),
-									corev1.ResourceMemory: resource.MustParse(sparkSubmitPodMemoryLimit),
+									corev1.ResourceCPU:    resource.MustParse(config.SparkSubmitPodCPULimit),
+									corev1.ResourceMemory: resource.MustParse(config.SparkSubmitPodMemoryLimit),
 								},
 							},
 						},
@@ -0,0 +1,240 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	apiv1 "k8s.io/api/core/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/logarchive"
+)
+
+// logArchiveTimeout bounds the time spent on archiving the logs of the pods of a run of an application.
+const logArchiveTimeout = 5 * time.Minute
+
+// streamPodLogs opens a stream of the logs of a pod container, which is closed when the given context is done. It is
+// a variable so that it can be stubbed out in tests.
+var streamPodLogs = func(ctx context.Context, kubeClient clientset.Interface, namespace string, podName string, options *apiv1.PodLogOptions) (io.ReadCloser, error) {
+	return kubeClient.CoreV1().Pods(namespace).GetLogs(podName, options).Context(ctx).Stream()
+}
+
+// logArchiver keeps track of the archiving of the logs of the runs of applications, which is done in the background
+// by the log archive workers so that it does not block the sync workers.
+type logArchiver struct {
+	sink  logarchive.Sink
+	queue workqueue.Interface
+	mutex sync.Mutex
+	// runs maps keys of applications to the archiving of the logs of their current runs.
+	runs map[string]*logArchiveRun
+}
+
+// logArchiveRun is the archiving of the logs of a run of an application.
+type logArchiveRun struct {
+	// app is a copy of the application taken when archiving was requested.
+	app *v1beta2.SparkApplication
+	// retry tells if the run retries archiving logs that failed to be archived before, which does not hold off the
+	// deletion of the pods, so that pods whose logs keep failing to be archived do not block the application.
+	retry     bool
+	done      bool
+	failed    bool
+	locations map[string]string
+}
+
+func newLogArchiver(sink logarchive.Sink) *logArchiver {
+	return &logArchiver{
+		sink:  sink,
+		queue: workqueue.NewNamed("spark-application-log-archive"),
+		runs:  make(map[string]*logArchiveRun),
+	}
+}
+
+// forget removes the record of the application with the given key.
+func (a *logArchiver) forget(key string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	delete(a.runs, key)
+}
+
+// recordLogArchiveLocations records the locations of the logs archived so far for the current run of the
+// application in its status.
+func (c *Controller) recordLogArchiveLocations(app *v1beta2.SparkApplication) {
+	c.logArchiver.mutex.Lock()
+	defer c.logArchiver.mutex.Unlock()
+	run, ok := c.logArchiver.runs[createMetaNamespaceKey(app.Namespace, app.Name)]
+	if !ok || run.app.Status.SubmissionID != app.Status.SubmissionID || len(run.locations) == 0 {
+		return
+	}
+	if app.Status.LogArchiveLocations == nil {
+		app.Status.LogArchiveLocations = make(map[string]string)
+	}
+	for podName, location := range run.locations {
+		app.Status.LogArchiveLocations[podName] = location
+	}
+}
+
+// archiveLogs requests the logs of the driver and executor pods of the current run of the application that still
+// exist and have not been archived yet to be archived in the background. The application is enqueued once archiving
+// is done, so that the archive locations get recorded in its status. Archiving is best-effort: logs that fail to be
+// archived are only archived by a later call, which happens if the application is synced again in the same state,
+// e.g., while waiting for the retry interval to pass. It returns true while the first attempt at archiving the logs
+// of the current run is in progress, during which the pods must not be deleted.
+func (c *Controller) archiveLogs(app *v1beta2.SparkApplication) bool {
+	key := createMetaNamespaceKey(app.Namespace, app.Name)
+	c.logArchiver.mutex.Lock()
+	defer c.logArchiver.mutex.Unlock()
+	run, ok := c.logArchiver.runs[key]
+	sameRun := ok && run.app.Status.SubmissionID == app.Status.SubmissionID
+	if sameRun && !run.done {
+		return !run.retry
+	}
+	if sameRun && !run.failed {
+		return false
+	}
+	newRun := &logArchiveRun{app: app.DeepCopy(), retry: sameRun, locations: make(map[string]string)}
+	if sameRun {
+		for podName, location := range run.locations {
+			newRun.locations[podName] = location
+		}
+	}
+	c.logArchiver.runs[key] = newRun
+	c.logArchiver.queue.Add(key)
+	return !newRun.retry
+}
+
+// runLogArchiveWorker archives the logs of the runs of applications as they are requested, until the queue is shut
+// down.
+func (c *Controller) runLogArchiveWorker() {
+	defer utilruntime.HandleCrash()
+	for c.processNextLogArchive() {
+	}
+}
+
+func (c *Controller) processNextLogArchive() bool {
+	key, quit := c.logArchiver.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.logArchiver.queue.Done(key)
+
+	c.logArchiver.mutex.Lock()
+	run, ok := c.logArchiver.runs[key.(string)]
+	c.logArchiver.mutex.Unlock()
+	if !ok || run.done {
+		return true
+	}
+
+	locations, failed := c.archivePodsLogs(run.app)
+
+	c.logArchiver.mutex.Lock()
+	for podName, location := range locations {
+		run.locations[podName] = location
+	}
+	run.failed = failed
+	run.done = true
+	c.logArchiver.mutex.Unlock()
+	c.queue.Add(key)
+	return true
+}
+
+// archivePodsLogs archives the logs of the driver and executor pods of the current run of the application that
+// still exist and are not recorded as archived in its status. It returns the locations of the archived logs, and
+// whether the logs of any pod failed to be archived.
+func (c *Controller) archivePodsLogs(app *v1beta2.SparkApplication) (map[string]string, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), logArchiveTimeout)
+	defer cancel()
+
+	var pods []*apiv1.Pod
+	failed := false
+	driverPod, err := c.getDriverPod(app)
+	if err != nil {
+		glog.Errorf("failed to get driver pod of SparkApplication %s/%s for log archiving: %v", app.Namespace, app.Name, err)
+		failed = true
+	} else if driverPod != nil {
+		pods = append(pods, driverPod)
+	}
+	executorPods, err := c.getExecutorPods(app)
+	if err != nil {
+		glog.Errorf("failed to get executor pods of SparkApplication %s/%s for log archiving: %v", app.Namespace, app.Name, err)
+		failed = true
+	}
+	pods = append(pods, executorPods...)
+
+	locations := make(map[string]string)
+	for _, pod := range pods {
+		if _, archived := app.Status.LogArchiveLocations[pod.Name]; archived {
+			continue
+		}
+		if ctx.Err() != nil {
+			glog.Errorf("timed out archiving logs of SparkApplication %s/%s, skipping pod %s", app.Namespace, app.Name, pod.Name)
+			failed = true
+			continue
+		}
+		location, err := c.archivePodLogs(ctx, app, pod)
+		if err != nil {
+			glog.Errorf("failed to archive logs of pod %s/%s to %s: %v", pod.Namespace, pod.Name, c.logArchiver.sink.Name(), err)
+			failed = true
+			continue
+		}
+		glog.V(2).Infof("Archived logs of pod %s/%s to %s", pod.Namespace, pod.Name, location)
+		locations[pod.Name] = location
+	}
+	return locations, failed
+}
+
+func (c *Controller) archivePodLogs(ctx context.Context, app *v1beta2.SparkApplication, pod *apiv1.Pod) (string, error) {
+	stream, err := streamPodLogs(ctx, c.kubeClient, pod.Namespace, pod.Name, &apiv1.PodLogOptions{
+		Container: getSparkContainerName(pod),
+	})
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	return c.logArchiver.sink.Archive(ctx, getLogArchiveKey(app, pod), stream)
+}
+
+// getSparkContainerName returns the name of the container running Spark in the given driver or executor pod.
+func getSparkContainerName(pod *apiv1.Pod) string {
+	if pod.Labels[config.SparkRoleLabel] == config.SparkDriverRole {
+		return config.SparkDriverContainerName
+	}
+	for _, container := range pod.Spec.Containers {
+		if container.Name == config.SparkExecutorContainerName || container.Name == config.Spark3DefaultExecutorContainerName {
+			return container.Name
+		}
+	}
+	if len(pod.Spec.Containers) > 0 {
+		return pod.Spec.Containers[0].Name
+	}
+	return ""
+}
+
+func getLogArchiveKey(app *v1beta2.SparkApplication, pod *apiv1.Pod) string {
+	return path.Join(app.Namespace, app.Name, app.Status.SubmissionID, fmt.Sprintf("%s.log", pod.Name))
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/logarchive"
+)
+
+func TestArchiveLogs(t *testing.T) {
+	defer func(original func(context.Context, clientset.Interface, string, string, *apiv1.PodLogOptions) (io.ReadCloser, error)) {
+		streamPodLogs = original
+	}(streamPodLogs)
+
+	root, err := ioutil.TempDir("", "spark-logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	containers := make(map[string]string)
+	failingPods := make(map[string]bool)
+	streamPodLogs = func(ctx context.Context, kubeClient clientset.Interface, namespace string, podName string, options *apiv1.PodLogOptions) (io.ReadCloser, error) {
+		if failingPods[podName] {
+			return nil, fmt.Errorf("logs of %s unavailable", podName)
+		}
+		containers[podName] = options.Container
+		return ioutil.NopCloser(strings.NewReader(fmt.Sprintf("logs of %s", podName))), nil
+	}
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			SubmissionID: "s1",
+			DriverInfo: v1beta2.DriverInfo{
+				PodName: "foo-driver",
+			},
+		},
+	}
+	driverPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-driver",
+			Namespace: "default",
+			Labels: map[string]string{
+				config.SparkRoleLabel:    config.SparkDriverRole,
+				config.SparkAppNameLabel: "foo",
+				config.SubmissionIDLabel: "s1",
+			},
+		},
+	}
+	executorPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-exec-1",
+			Namespace: "default",
+			Labels: map[string]string{
+				config.SparkRoleLabel:    config.SparkExecutorRole,
+				config.SparkAppNameLabel: "foo",
+				config.SubmissionIDLabel: "s1",
+			},
+		},
+		Spec: apiv1.PodSpec{
+			Containers: []apiv1.Container{{Name: config.Spark3DefaultExecutorContainerName}},
+		},
+	}
+
+	ctrl, _ := newFakeController(app, nil, driverPod, executorPod)
+	ctrl.logArchiver = newLogArchiver(logarchive.NewFileSystemSink(root))
+	key := createMetaNamespaceKey(app.Namespace, app.Name)
+
+	// Archiving happens in the background, and holds off the deletion of the pods until it is done.
+	failingPods["foo-exec-1"] = true
+	assert.True(t, ctrl.archiveLogs(app))
+	assert.True(t, ctrl.archiveLogs(app))
+	assert.Empty(t, containers)
+	assert.True(t, ctrl.processNextLogArchive())
+	assert.Equal(t, 1, ctrl.queue.Len())
+	ctrl.recordLogArchiveLocations(app)
+	driverLocation := filepath.Join(root, "default", "foo", "s1", "foo-driver.log")
+	assert.Equal(t, map[string]string{"foo-driver": driverLocation}, app.Status.LogArchiveLocations)
+	assert.Equal(t, config.SparkDriverContainerName, containers["foo-driver"])
+	content, err := ioutil.ReadFile(driverLocation)
+	assert.Nil(t, err)
+	assert.Equal(t, "logs of foo-driver", string(content))
+
+	// Archiving again only archives the pods that failed to be archived before, while pods already archived are
+	// skipped. Retries do not hold off the deletion of the pods.
+	delete(containers, "foo-driver")
+	failingPods["foo-exec-1"] = false
+	assert.False(t, ctrl.archiveLogs(app))
+	assert.True(t, ctrl.processNextLogArchive())
+	ctrl.recordLogArchiveLocations(app)
+	executorLocation := filepath.Join(root, "default", "foo", "s1", "foo-exec-1.log")
+	assert.Equal(t, driverLocation, app.Status.LogArchiveLocations["foo-driver"])
+	assert.Equal(t, executorLocation, app.Status.LogArchiveLocations["foo-exec-1"])
+	assert.Equal(t, config.Spark3DefaultExecutorContainerName, containers["foo-exec-1"])
+	_, called := containers["foo-driver"]
+	assert.False(t, called)
+	content, err = ioutil.ReadFile(executorLocation)
+	assert.Nil(t, err)
+	assert.Equal(t, "logs of foo-exec-1", string(content))
+
+	// Nothing is left to archive once all the logs have been archived.
+	assert.False(t, ctrl.archiveLogs(app))
+	assert.Equal(t, 0, ctrl.logArchiver.queue.Len())
+
+	// A new run is archived anew.
+	ctrl.logArchiver.forget(key)
+	app.Status.LogArchiveLocations = nil
+	assert.True(t, ctrl.archiveLogs(app))
+}
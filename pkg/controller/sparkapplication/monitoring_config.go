@@ -38,6 +38,16 @@ const (
 	prometheusPathAnnotation   = "prometheus.io/path"
 )
 
+const (
+	sparkMetricsNamespaceKey    = "spark.metrics.namespace"
+	sparkUIPrometheusEnabledKey = "spark.ui.prometheus.enabled"
+	prometheusServletSinkPrefix = "spark.metrics.conf.*.sink.prometheusServlet."
+	prometheusServletClass      = "org.apache.spark.metrics.sink.PrometheusServlet"
+	driverJvmSourceClassKey     = "spark.metrics.conf.driver.source.jvm.class"
+	executorJvmSourceClassKey   = "spark.metrics.conf.executor.source.jvm.class"
+	jvmSourceClass              = "org.apache.spark.metrics.source.JvmSource"
+)
+
 func configPrometheusMonitoring(app *v1beta2.SparkApplication, kubeClient clientset.Interface) error {
 	port := config.DefaultPrometheusJavaAgentPort
 	if app.Spec.Monitoring.Prometheus.Port != nil {
@@ -91,7 +101,7 @@ func configPrometheusMonitoring(app *v1beta2.SparkApplication, kubeClient client
 	if app.Spec.SparkConf == nil {
 		app.Spec.SparkConf = make(map[string]string)
 	}
-	app.Spec.SparkConf["spark.metrics.namespace"] = metricNamespace
+	app.Spec.SparkConf[sparkMetricsNamespaceKey] = metricNamespace
 	app.Spec.SparkConf["spark.metrics.conf"] = metricConf
 
 	if app.HasMetricsPropertiesFile() {
@@ -130,6 +140,44 @@ func configPrometheusMonitoring(app *v1beta2.SparkApplication, kubeClient client
 	return nil
 }
 
+// configPrometheusServletMonitoring configures the PrometheusServlet built into Spark 3.0 and above to serve
+// metrics through the Spark UI of the driver, which needs neither the JMX exporter nor a ConfigMap. Executors
+// do not run an HTTP server, so their metrics are served by the driver as well.
+func configPrometheusServletMonitoring(app *v1beta2.SparkApplication) {
+	path := config.DefaultPrometheusServletPath
+	if app.Spec.Monitoring.PrometheusServlet.Path != nil && *app.Spec.Monitoring.PrometheusServlet.Path != "" {
+		path = *app.Spec.Monitoring.PrometheusServlet.Path
+	}
+
+	if app.Spec.SparkConf == nil {
+		app.Spec.SparkConf = make(map[string]string)
+	}
+	setSparkConfIfAbsent(app, sparkMetricsNamespaceKey, fmt.Sprintf("%s.%s", app.Namespace, app.Name))
+	setSparkConfIfAbsent(app, prometheusServletSinkPrefix+"class", prometheusServletClass)
+	setSparkConfIfAbsent(app, prometheusServletSinkPrefix+"path", path)
+	setSparkConfIfAbsent(app, driverJvmSourceClassKey, jvmSourceClass)
+
+	if app.Spec.Monitoring.ExposeExecutorMetrics {
+		setSparkConfIfAbsent(app, sparkUIPrometheusEnabledKey, "true")
+		setSparkConfIfAbsent(app, executorJvmSourceClassKey, jvmSourceClass)
+	}
+
+	if !app.Spec.Monitoring.ExposeDriverMetrics && !app.Spec.Monitoring.ExposeExecutorMetrics {
+		return
+	}
+	// Pods can only be annotated with a single scrape path, which is the one of the executor metrics if only
+	// those are exposed.
+	if !app.Spec.Monitoring.ExposeDriverMetrics {
+		path = config.PrometheusServletExecutorsPath
+	}
+	if app.Spec.Driver.Annotations == nil {
+		app.Spec.Driver.Annotations = make(map[string]string)
+	}
+	app.Spec.Driver.Annotations[prometheusScrapeAnnotation] = "true"
+	app.Spec.Driver.Annotations[prometheusPortAnnotation] = getUITargetPort(app)
+	app.Spec.Driver.Annotations[prometheusPathAnnotation] = path
+}
+
 func buildPrometheusConfigMap(app *v1beta2.SparkApplication, prometheusConfigMapName string) *corev1.ConfigMap {
 	configMapData := make(map[string]string)
 
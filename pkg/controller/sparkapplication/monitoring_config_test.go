@@ -18,6 +18,7 @@ package sparkapplication
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -264,3 +265,139 @@ func TestConfigPrometheusMonitoring(t *testing.T) {
 		testFn(test, t)
 	}
 }
+
+func TestConfigPrometheusServletMonitoring(t *testing.T) {
+	type testcase struct {
+		name                string
+		app                 *v1beta2.SparkApplication
+		expectedSparkConf   map[string]string
+		expectedAnnotations map[string]string
+	}
+
+	testFn := func(test testcase, t *testing.T) {
+		configPrometheusServletMonitoring(test.app)
+		for key, value := range test.expectedSparkConf {
+			if test.app.Spec.SparkConf[key] != value {
+				t.Errorf("%s: %s expected %s got %s", test.name, key, value, test.app.Spec.SparkConf[key])
+			}
+		}
+		if _, ok := test.expectedSparkConf[sparkUIPrometheusEnabledKey]; !ok {
+			if _, ok := test.app.Spec.SparkConf[sparkUIPrometheusEnabledKey]; ok {
+				t.Errorf("%s: %s unexpectedly set", test.name, sparkUIPrometheusEnabledKey)
+			}
+		}
+		if !reflect.DeepEqual(test.expectedAnnotations, test.app.Spec.Driver.Annotations) {
+			t.Errorf("%s: driver annotations expected %v got %v", test.name, test.expectedAnnotations, test.app.Spec.Driver.Annotations)
+		}
+		if test.app.Spec.Executor.Annotations != nil {
+			t.Errorf("%s: unexpected executor annotations %v", test.name, test.app.Spec.Executor.Annotations)
+		}
+		if test.app.Spec.Driver.JavaOptions != nil || test.app.Spec.Executor.JavaOptions != nil {
+			t.Errorf("%s: unexpected Java options", test.name)
+		}
+	}
+
+	testcases := []testcase{
+		{
+			name: "driver and executor metrics",
+			app: &v1beta2.SparkApplication{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "app1",
+					Namespace: "default",
+				},
+				Spec: v1beta2.SparkApplicationSpec{
+					Monitoring: &v1beta2.MonitoringSpec{
+						ExposeDriverMetrics:   true,
+						ExposeExecutorMetrics: true,
+						PrometheusServlet:     &v1beta2.PrometheusServletSpec{},
+					},
+				},
+			},
+			expectedSparkConf: map[string]string{
+				sparkMetricsNamespaceKey:              "default.app1",
+				prometheusServletSinkPrefix + "class": prometheusServletClass,
+				prometheusServletSinkPrefix + "path":  config.DefaultPrometheusServletPath,
+				sparkUIPrometheusEnabledKey:           "true",
+			},
+			expectedAnnotations: map[string]string{
+				prometheusScrapeAnnotation: "true",
+				prometheusPortAnnotation:   "4040",
+				prometheusPathAnnotation:   config.DefaultPrometheusServletPath,
+			},
+		},
+		{
+			name: "driver metrics at a custom path and UI port",
+			app: &v1beta2.SparkApplication{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "app2",
+					Namespace: "default",
+				},
+				Spec: v1beta2.SparkApplicationSpec{
+					SparkConf: map[string]string{
+						sparkUIPortConfigurationKey: "4041",
+						sparkMetricsNamespaceKey:    "custom",
+					},
+					Monitoring: &v1beta2.MonitoringSpec{
+						ExposeDriverMetrics: true,
+						PrometheusServlet: &v1beta2.PrometheusServletSpec{
+							Path: stringptr("/prometheus"),
+						},
+					},
+				},
+			},
+			expectedSparkConf: map[string]string{
+				sparkMetricsNamespaceKey:             "custom",
+				prometheusServletSinkPrefix + "path": "/prometheus",
+			},
+			expectedAnnotations: map[string]string{
+				prometheusScrapeAnnotation: "true",
+				prometheusPortAnnotation:   "4041",
+				prometheusPathAnnotation:   "/prometheus",
+			},
+		},
+		{
+			name: "executor metrics only",
+			app: &v1beta2.SparkApplication{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "app3",
+					Namespace: "default",
+				},
+				Spec: v1beta2.SparkApplicationSpec{
+					Monitoring: &v1beta2.MonitoringSpec{
+						ExposeExecutorMetrics: true,
+						PrometheusServlet:     &v1beta2.PrometheusServletSpec{},
+					},
+				},
+			},
+			expectedSparkConf: map[string]string{
+				sparkUIPrometheusEnabledKey: "true",
+			},
+			expectedAnnotations: map[string]string{
+				prometheusScrapeAnnotation: "true",
+				prometheusPortAnnotation:   "4040",
+				prometheusPathAnnotation:   config.PrometheusServletExecutorsPath,
+			},
+		},
+		{
+			name: "no metrics exposed",
+			app: &v1beta2.SparkApplication{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "app4",
+					Namespace: "default",
+				},
+				Spec: v1beta2.SparkApplicationSpec{
+					Monitoring: &v1beta2.MonitoringSpec{
+						PrometheusServlet: &v1beta2.PrometheusServletSpec{},
+					},
+				},
+			},
+			expectedSparkConf: map[string]string{
+				prometheusServletSinkPrefix + "class": prometheusServletClass,
+			},
+		},
+	}
+
+	for _, test := range testcases {
+		testFn(test, t)
+	}
+}
@@ -0,0 +1,269 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/notification"
+)
+
+// notificationTimeout bounds the time spent on delivering the notifications of a single state transition.
+const notificationTimeout = 5 * time.Minute
+
+// defaultNotifiedStates are the states transitions into which are notified by default, which are the states
+// recorded as events of the applications.
+var defaultNotifiedStates = []v1beta2.ApplicationStateType{
+	v1beta2.PendingSubmissionState,
+	v1beta2.SubmittedState,
+	v1beta2.FailedSubmissionState,
+	v1beta2.CompletedState,
+	v1beta2.FailedState,
+	v1beta2.PendingRerunState,
+}
+
+// cloudEventTypes maps the states transitions into which are emitted as CloudEvents to the types of the events.
+var cloudEventTypes = map[v1beta2.ApplicationStateType]string{
+	v1beta2.SubmittedState: notification.SparkApplicationSubmittedEventType,
+	v1beta2.RunningState:   notification.SparkApplicationRunningEventType,
+	v1beta2.CompletedState: notification.SparkApplicationCompletedEventType,
+	v1beta2.FailedState:    notification.SparkApplicationFailedEventType,
+}
+
+// NotificationConfig holds the settings of notifying HTTP endpoints of the state transitions of applications.
+type NotificationConfig struct {
+	// Sender delivers the notifications.
+	Sender *notification.Sender
+	// NamespaceInformer is the informer of the Namespaces whose annotations configure the endpoints of the
+	// applications in them.
+	NamespaceInformer coreinformers.NamespaceInformer
+	// AllowedURLs are the URL prefixes the URLs configured through the annotations of applications must start with.
+	// The URLs configured through the annotations of namespaces are not restricted.
+	AllowedURLs notification.URLAllowlist
+	// Workers is the number of workers delivering notifications in the background.
+	Workers int
+	// QueueSize is the maximum number of notifications queued per worker.
+	QueueSize int
+}
+
+// appNotificationConfig is the configuration of the notifications of an application, which is merged from the
+// annotations of the application and its namespace.
+type appNotificationConfig struct {
+	urls []string
+	// rejectedURLs are the URLs configured through the annotations of the application that are not allowed.
+	rejectedURLs []string
+	states       []v1beta2.ApplicationStateType
+	secretName   string
+}
+
+// notifyStateTransition queues the notification of the endpoints configured for the application of its transition
+// from the state of the old application to the state of the new one, if it is to be notified. The notifications of
+// an application are delivered in the order of its transitions.
+func (c *Controller) notifyStateTransition(oldApp, newApp *v1beta2.SparkApplication) {
+	if c.notificationConfig == nil || oldApp.Status.AppState.State == newApp.Status.AppState.State {
+		return
+	}
+	payload := notification.Payload{
+		Application:   newApp.Name,
+		Namespace:     newApp.Namespace,
+		UID:           string(newApp.UID),
+		OldState:      getStateMetricLabelValue(oldApp.Status.AppState.State),
+		NewState:      getStateMetricLabelValue(newApp.Status.AppState.State),
+		FailureReason: newApp.Status.AppState.ErrorMessage,
+		UIURL:         getUIURL(newApp),
+		Attempt:       newApp.Status.ExecutionAttempts,
+		SubmissionID:  newApp.Status.SubmissionID,
+		Time:          getTransitionTime(newApp),
+	}
+	app := newApp.DeepCopy()
+	key := fmt.Sprintf("%s/%s", app.Namespace, app.Name)
+	if !c.notificationDispatcher.Dispatch(key, func() { c.sendNotifications(app, payload) }) {
+		glog.Errorf("dropped notification of SparkApplication %s as the notification queue is full or stopped", key)
+	}
+}
+
+// sendNotifications delivers the payload to the endpoints configured for the application.
+func (c *Controller) sendNotifications(app *v1beta2.SparkApplication, payload notification.Payload) {
+	appConfig, err := c.getNotificationConfig(app)
+	if err != nil {
+		glog.Errorf("failed to get the notification configuration of SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+		return
+	}
+	if !isNotifiedState(app.Status.AppState.State, appConfig.states) {
+		return
+	}
+	if len(appConfig.rejectedURLs) > 0 {
+		glog.Errorf("rejected notification URLs %v of SparkApplication %s/%s that are not allowed", appConfig.rejectedURLs,
+			app.Namespace, app.Name)
+		c.recorder.Eventf(
+			app,
+			apiv1.EventTypeWarning,
+			"SparkApplicationNotificationURLRejected",
+			"Notification URLs %s are not allowed",
+			strings.Join(appConfig.rejectedURLs, ","))
+	}
+	if len(appConfig.urls) == 0 {
+		return
+	}
+
+	var signingKey []byte
+	if appConfig.secretName != "" {
+		secret, err := c.kubeClient.CoreV1().Secrets(app.Namespace).Get(appConfig.secretName, metav1.GetOptions{})
+		if err != nil {
+			glog.Errorf("failed to get the notification Secret %s of SparkApplication %s/%s: %v", appConfig.secretName,
+				app.Namespace, app.Name, err)
+			return
+		}
+		signingKey = secret.Data[config.NotificationSigningKeySecretKey]
+		if len(signingKey) == 0 {
+			glog.Errorf("notification Secret %s of SparkApplication %s/%s has no key %s", appConfig.secretName,
+				app.Namespace, app.Name, config.NotificationSigningKeySecretKey)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+	for _, url := range appConfig.urls {
+		endpoint := notification.Endpoint{URL: url, SigningKey: signingKey}
+		if err := c.notificationConfig.Sender.Send(ctx, endpoint, payload); err != nil {
+			glog.Errorf("failed to send notification of SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+		}
+	}
+}
+
+// getNotificationConfig returns the notification configuration of the application. Each annotation of the
+// application takes precedence over the same annotation of its namespace. As anyone allowed to create applications
+// could otherwise make the operator send requests anywhere, the URLs configured through the annotations of the
+// application are only used if they are allowed, while the ones configured through the annotations of its
+// namespace are trusted.
+func (c *Controller) getNotificationConfig(app *v1beta2.SparkApplication) (*appNotificationConfig, error) {
+	annotations := make(map[string]string)
+	namespace, err := c.notificationConfig.NamespaceInformer.Lister().Get(app.Namespace)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		for key, value := range namespace.Annotations {
+			annotations[key] = value
+		}
+	}
+
+	appConfig := &appNotificationConfig{urls: splitAnnotationValue(annotations[config.NotificationURLsAnnotation])}
+	var allowedURLs []string
+	for _, url := range splitAnnotationValue(app.Annotations[config.NotificationURLsAnnotation]) {
+		if c.notificationConfig.AllowedURLs.Allows(url) {
+			allowedURLs = append(allowedURLs, url)
+		} else {
+			appConfig.rejectedURLs = append(appConfig.rejectedURLs, url)
+		}
+	}
+	if len(allowedURLs) > 0 {
+		appConfig.urls = allowedURLs
+	}
+
+	for key, value := range app.Annotations {
+		annotations[key] = value
+	}
+	appConfig.secretName = annotations[config.NotificationSecretAnnotation]
+	for _, state := range splitAnnotationValue(annotations[config.NotificationStatesAnnotation]) {
+		stateType := v1beta2.ApplicationStateType(strings.ToUpper(state))
+		if !isKnownState(stateType) {
+			return nil, fmt.Errorf("unknown state %q in annotation %s", state, config.NotificationStatesAnnotation)
+		}
+		appConfig.states = append(appConfig.states, stateType)
+	}
+	if len(appConfig.states) == 0 {
+		appConfig.states = defaultNotifiedStates
+	}
+	return appConfig, nil
+}
+
+func isNotifiedState(state v1beta2.ApplicationStateType, states []v1beta2.ApplicationStateType) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+func isKnownState(state v1beta2.ApplicationStateType) bool {
+	switch state {
+	case v1beta2.PendingSubmissionState, v1beta2.SubmittedState, v1beta2.FailedSubmissionState, v1beta2.RunningState,
+		v1beta2.CompletedState, v1beta2.FailedState, v1beta2.PendingRerunState, v1beta2.InvalidatingState,
+		v1beta2.SucceedingState, v1beta2.FailingState, v1beta2.UnknownState:
+		return true
+	}
+	return false
+}
+
+func splitAnnotationValue(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// getUIURL returns the URL of the UI of the application, preferring the History Server once it has terminated.
+func getUIURL(app *v1beta2.SparkApplication) string {
+	driverInfo := app.Status.DriverInfo
+	switch {
+	case driverInfo.HistoryServerURL != "":
+		return driverInfo.HistoryServerURL
+	case driverInfo.WebUIIngressAddress != "":
+		return driverInfo.WebUIIngressAddress
+	default:
+		return driverInfo.WebUIAddress
+	}
+}
+
+// emitCloudEvent queues a CloudEvent of the transition of the application from the state of the old application to
+// the state of the new one to be emitted in the background, if it is of one of the emitted types.
+func (c *Controller) emitCloudEvent(oldApp, newApp *v1beta2.SparkApplication) {
+	if c.cloudEventEmitter == nil || oldApp.Status.AppState.State == newApp.Status.AppState.State {
+		return
+	}
+	eventType, ok := cloudEventTypes[newApp.Status.AppState.State]
+	if !ok {
+		return
+	}
+	event := notification.NewSparkApplicationEvent(eventType, newApp.DeepCopy(), getTransitionTime(newApp))
+	key := fmt.Sprintf("%s/%s", newApp.Namespace, newApp.Name)
+	emitted := c.cloudEventEmitter.EmitAsync(key, event, notificationTimeout, func(err error) {
+		if err != nil {
+			glog.Errorf("failed to emit CloudEvent %s of SparkApplication %s: %v", eventType, key, err)
+		}
+	})
+	if !emitted {
+		glog.Errorf("dropped CloudEvent %s of SparkApplication %s as the CloudEvent queue is full or stopped", eventType, key)
+	}
+}
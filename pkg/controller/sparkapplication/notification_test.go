@@ -0,0 +1,216 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/notification"
+)
+
+type notificationRecorder struct {
+	mutex      sync.Mutex
+	payloads   []notification.Payload
+	signatures []string
+}
+
+func (r *notificationRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	var payload notification.Payload
+	json.Unmarshal(body, &payload)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.payloads = append(r.payloads, payload)
+	r.signatures = append(r.signatures, req.Header.Get(notification.SignatureHeader))
+}
+
+func (r *notificationRecorder) getPayloads() []notification.Payload {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]notification.Payload(nil), r.payloads...)
+}
+
+// newNotificationConfig returns the notification settings of tests, with the given namespace in the cache of the
+// namespace informer.
+func newNotificationConfig(ctrl *Controller, namespace *apiv1.Namespace, allowedURLs ...string) *NotificationConfig {
+	namespaceInformer := informers.NewSharedInformerFactory(ctrl.kubeClient, 0*time.Second).Core().V1().Namespaces()
+	if namespace != nil {
+		namespaceInformer.Informer().GetIndexer().Add(namespace)
+	}
+	allowlist, _ := notification.ParseURLAllowlist(strings.Join(allowedURLs, ","))
+	return &NotificationConfig{
+		Sender:            notification.NewSender(time.Second, 0, time.Millisecond),
+		NamespaceInformer: namespaceInformer,
+		AllowedURLs:       allowlist,
+		Workers:           1,
+		QueueSize:         10,
+	}
+}
+
+func TestSendNotifications(t *testing.T) {
+	namespaceRecorder := &notificationRecorder{}
+	namespaceServer := httptest.NewServer(namespaceRecorder)
+	defer namespaceServer.Close()
+	appRecorder := &notificationRecorder{}
+	appServer := httptest.NewServer(appRecorder)
+	defer appServer.Close()
+
+	ctrl, recorder := newFakeController(nil, nil)
+	ctrl.notificationConfig = newNotificationConfig(ctrl, &apiv1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "default",
+			Annotations: map[string]string{
+				config.NotificationURLsAnnotation:   namespaceServer.URL,
+				config.NotificationSecretAnnotation: "notification-key",
+			},
+		},
+	}, appServer.URL)
+	ctrl.kubeClient.CoreV1().Secrets("default").Create(&apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "notification-key", Namespace: "default"},
+		Data:       map[string][]byte{config.NotificationSigningKeySecretKey: []byte("secret")},
+	})
+
+	oldApp := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: v1beta2.FailingState},
+		},
+	}
+	newApp := oldApp.DeepCopy()
+	newApp.Status.AppState = v1beta2.ApplicationState{
+		State:              v1beta2.FailedState,
+		ErrorMessage:       "driver failed",
+		LastTransitionTime: metav1.NewTime(time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)),
+	}
+	newApp.Status.ExecutionAttempts = 2
+	newApp.Status.DriverInfo.WebUIAddress = "10.0.0.1:4040"
+	payload := notification.Payload{
+		Application:   "foo",
+		Namespace:     "default",
+		OldState:      "FAILING",
+		NewState:      "FAILED",
+		FailureReason: "driver failed",
+		UIURL:         "10.0.0.1:4040",
+		Attempt:       2,
+		Time:          newApp.Status.AppState.LastTransitionTime.Time,
+	}
+
+	// The endpoint of the namespace is notified with a signed payload.
+	ctrl.sendNotifications(newApp, payload)
+	assert.Equal(t, []notification.Payload{payload}, namespaceRecorder.payloads)
+	if assert.Len(t, namespaceRecorder.signatures, 1) {
+		body, _ := json.Marshal(payload)
+		assert.Equal(t, "sha256="+notification.Sign([]byte("secret"), body), namespaceRecorder.signatures[0])
+	}
+
+	// URLs of the application that are not allowed are rejected.
+	newApp.Annotations = map[string]string{config.NotificationURLsAnnotation: "http://169.254.169.254/latest"}
+	ctrl.sendNotifications(newApp, payload)
+	assert.Len(t, namespaceRecorder.payloads, 2)
+	assert.Empty(t, appRecorder.payloads)
+	assert.Equal(t, "Warning SparkApplicationNotificationURLRejected Notification URLs http://169.254.169.254/latest are not allowed",
+		<-recorder.Events)
+
+	// The allowed annotations of the application take precedence over the ones of the namespace.
+	newApp.Annotations = map[string]string{config.NotificationURLsAnnotation: appServer.URL + "/hooks"}
+	ctrl.sendNotifications(newApp, payload)
+	assert.Len(t, namespaceRecorder.payloads, 2)
+	assert.Equal(t, []notification.Payload{payload}, appRecorder.payloads)
+
+	// Transitions into states that are not configured are not notified.
+	newApp.Annotations[config.NotificationStatesAnnotation] = "completed"
+	ctrl.sendNotifications(newApp, payload)
+	assert.Len(t, appRecorder.payloads, 1)
+
+	newApp.Annotations[config.NotificationStatesAnnotation] = "COMPLETED, FAILED"
+	ctrl.sendNotifications(newApp, payload)
+	assert.Len(t, appRecorder.payloads, 2)
+
+	// Running is not notified by default.
+	delete(newApp.Annotations, config.NotificationStatesAnnotation)
+	newApp.Status.AppState.State = v1beta2.RunningState
+	ctrl.sendNotifications(newApp, payload)
+	assert.Len(t, appRecorder.payloads, 2)
+}
+
+func TestNotifyStateTransitionsInOrder(t *testing.T) {
+	server := &notificationRecorder{}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	ctrl, _ := newFakeController(nil, nil)
+	ctrl.notificationConfig = newNotificationConfig(ctrl, &apiv1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "default",
+			Annotations: map[string]string{config.NotificationURLsAnnotation: httpServer.URL},
+		},
+	})
+	ctrl.notificationDispatcher = notification.NewDispatcher(ctrl.notificationConfig.Workers, ctrl.notificationConfig.QueueSize)
+
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+	states := []v1beta2.ApplicationStateType{
+		v1beta2.NewState,
+		v1beta2.PendingSubmissionState,
+		v1beta2.SubmittedState,
+		v1beta2.FailedSubmissionState,
+		v1beta2.FailedState,
+	}
+	for i := 1; i < len(states); i++ {
+		oldApp := app.DeepCopy()
+		oldApp.Status.AppState.State = states[i-1]
+		newApp := app.DeepCopy()
+		newApp.Status.AppState.State = states[i]
+		ctrl.notifyStateTransition(oldApp, newApp)
+	}
+	// Stopping the controller delivers the queued notifications.
+	ctrl.Stop()
+
+	var newStates []string
+	for _, payload := range server.getPayloads() {
+		newStates = append(newStates, payload.NewState)
+	}
+	assert.Equal(t, []string{"PENDING_SUBMISSION", "SUBMITTED", "SUBMISSION_FAILED", "FAILED"}, newStates)
+}
+
+func TestGetNotificationConfigRejectsUnknownStates(t *testing.T) {
+	ctrl, _ := newFakeController(nil, nil)
+	ctrl.notificationConfig = newNotificationConfig(ctrl, nil)
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   "default",
+			Annotations: map[string]string{config.NotificationStatesAnnotation: "FAILED,DONE"},
+		},
+	}
+
+	_, err := ctrl.getNotificationConfig(app)
+	assert.NotNil(t, err)
+}
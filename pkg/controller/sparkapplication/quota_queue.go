@@ -0,0 +1,196 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// QueueOrdering is the order in which the queued applications of a namespace are submitted.
+type QueueOrdering string
+
+const (
+	// QueueOrderingFIFO submits queued applications in the order they were queued.
+	QueueOrderingFIFO QueueOrdering = "fifo"
+	// QueueOrderingPriority submits queued applications in the order of their priorities, and in the order they
+	// were queued among applications of the same priority.
+	QueueOrderingPriority QueueOrdering = "priority"
+)
+
+// QuotaChecker checks whether applications fit into what is left of the resource quotas of their namespaces.
+type QuotaChecker interface {
+	// CheckSparkApplication returns the reason why the application does not fit, or an empty string if it fits.
+	CheckSparkApplication(app v1beta2.SparkApplication) (string, error)
+	// ReserveSparkApplication checks the queued application like CheckSparkApplication, and reserves its resources
+	// if it fits, so that the applications dequeued after it are checked against a usage including it.
+	ReserveSparkApplication(app v1beta2.SparkApplication) (string, error)
+}
+
+// QuotaQueueConfig holds the settings of queueing applications exceeding the resource quotas of their namespaces
+// until they fit.
+type QuotaQueueConfig struct {
+	// Checker checks whether applications fit into the resource quotas.
+	Checker QuotaChecker
+	// Ordering is the order in which queued applications are submitted.
+	Ordering QueueOrdering
+	// RecheckInterval is the interval between two checks of whether a queued application fits.
+	RecheckInterval time.Duration
+}
+
+// queueIfOverQuota queues the new application if it does not fit into the resource quotas of its namespace or if
+// other applications are queued ahead of it, and returns whether it was queued.
+func (c *Controller) queueIfOverQuota(app *v1beta2.SparkApplication) bool {
+	if c.quotaQueueConfig == nil {
+		return false
+	}
+	reason, err := c.getQueueReason(app, c.quotaQueueConfig.Checker.CheckSparkApplication)
+	if err != nil {
+		glog.Errorf("failed to check SparkApplication %s/%s against the resource quotas, submitting it: %v", app.Namespace, app.Name, err)
+		return false
+	}
+	if reason == "" {
+		return false
+	}
+
+	app.Status.AppState.State = v1beta2.QueuedState
+	app.Status.QueueStatus = &v1beta2.QueueStatus{QueuedTime: metav1.Now()}
+	c.updateQueueStatus(app, reason)
+	c.recordSparkApplicationEvent(app)
+	return true
+}
+
+// dequeueIfUnderQuota returns whether the queued application is at the head of the queue of its namespace and
+// fits into the resource quotas, in which case its resources are reserved and it is to be submitted. Otherwise its
+// queue status is updated.
+func (c *Controller) dequeueIfUnderQuota(app *v1beta2.SparkApplication) bool {
+	if c.quotaQueueConfig == nil {
+		// Queueing has been turned off since the application was queued.
+		app.Status.QueueStatus = nil
+		return true
+	}
+	reason, err := c.getQueueReason(app, c.quotaQueueConfig.Checker.ReserveSparkApplication)
+	if err != nil {
+		glog.Errorf("failed to check SparkApplication %s/%s against the resource quotas: %v", app.Namespace, app.Name, err)
+		reason = err.Error()
+	}
+	if reason == "" {
+		glog.V(2).Infof("SparkApplication %s/%s fits into the resource quotas, dequeuing it", app.Namespace, app.Name)
+		app.Status.QueueStatus = nil
+		return true
+	}
+
+	if app.Status.QueueStatus == nil {
+		app.Status.QueueStatus = &v1beta2.QueueStatus{QueuedTime: metav1.Now()}
+	}
+	c.updateQueueStatus(app, reason)
+	return false
+}
+
+// updateQueueStatus sets the position of the queued application and the reason it is queued in its status, and
+// checks again whether it fits after the recheck interval.
+func (c *Controller) updateQueueStatus(app *v1beta2.SparkApplication, reason string) {
+	app.Status.QueueStatus.Position = int32(c.getQueuePosition(app))
+	app.Status.QueueStatus.Reason = reason
+	c.queue.AddAfter(createMetaNamespaceKey(app.Namespace, app.Name), c.quotaQueueConfig.RecheckInterval)
+}
+
+// getQueueReason returns the reason the application has to wait in the queue, or an empty string if it can be
+// submitted, which is the case if no other application is queued ahead of it and check finds it fits into the
+// resource quotas.
+func (c *Controller) getQueueReason(app *v1beta2.SparkApplication, check func(v1beta2.SparkApplication) (string, error)) (string, error) {
+	if position := c.getQueuePosition(app); position > 1 {
+		return fmt.Sprintf("%d application(s) are queued ahead", position-1), nil
+	}
+	return check(*app)
+}
+
+// getQueuePosition returns the 1-based position of the application in the queue of its namespace, as if it was
+// queued if it is not yet.
+func (c *Controller) getQueuePosition(app *v1beta2.SparkApplication) int {
+	apps, err := c.applicationLister.SparkApplications(app.Namespace).List(labels.Everything())
+	if err != nil {
+		glog.Errorf("failed to list SparkApplications in namespace %s: %v", app.Namespace, err)
+		return 1
+	}
+
+	queue := []*v1beta2.SparkApplication{app}
+	for _, other := range apps {
+		if other.Name != app.Name && other.Status.AppState.State == v1beta2.QueuedState {
+			queue = append(queue, other)
+		}
+	}
+	sort.SliceStable(queue, func(i, j int) bool {
+		return c.isQueuedAhead(queue[i], queue[j])
+	})
+	for i, queued := range queue {
+		if queued.Name == app.Name {
+			return i + 1
+		}
+	}
+	return 1
+}
+
+// isQueuedAhead returns whether application a is ahead of application b in the queue of their namespace.
+func (c *Controller) isQueuedAhead(a, b *v1beta2.SparkApplication) bool {
+	if c.quotaQueueConfig.Ordering == QueueOrderingPriority {
+		if priorityA, priorityB := getQueuePriority(a), getQueuePriority(b); priorityA != priorityB {
+			return priorityA > priorityB
+		}
+	}
+	queuedA, queuedB := getQueuedTime(a), getQueuedTime(b)
+	if !queuedA.Equal(queuedB) {
+		return queuedA.Before(queuedB)
+	}
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Name < b.Name
+}
+
+// getQueuedTime returns the time the application was queued, or the current time if it is not yet queued, so
+// that it is queued behind all applications queued before.
+func getQueuedTime(app *v1beta2.SparkApplication) time.Time {
+	if app.Status.QueueStatus != nil && !app.Status.QueueStatus.QueuedTime.IsZero() {
+		return app.Status.QueueStatus.QueuedTime.Time
+	}
+	return time.Now()
+}
+
+// getQueuePriority returns the priority of the application in the queue, which is set through an annotation and
+// defaults to 0.
+func getQueuePriority(app *v1beta2.SparkApplication) int64 {
+	value, ok := app.Annotations[config.QueuePriorityAnnotation]
+	if !ok {
+		return 0
+	}
+	priority, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		glog.Warningf("invalid queue priority %q of SparkApplication %s/%s, using 0", value, app.Namespace, app.Name)
+		return 0
+	}
+	return priority
+}
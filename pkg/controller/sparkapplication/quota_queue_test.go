@@ -0,0 +1,172 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdlisters "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/listers/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// fakeQuotaChecker lets the applications whose names are in fits fit into the resource quotas, and records the
+// names of the applications whose resources are reserved.
+type fakeQuotaChecker struct {
+	fits     map[string]bool
+	reserved []string
+}
+
+func (f *fakeQuotaChecker) CheckSparkApplication(app v1beta2.SparkApplication) (string, error) {
+	if f.fits[app.Name] {
+		return "", nil
+	}
+	return "requests too many cores", nil
+}
+
+func (f *fakeQuotaChecker) ReserveSparkApplication(app v1beta2.SparkApplication) (string, error) {
+	reason, err := f.CheckSparkApplication(app)
+	if reason == "" && err == nil {
+		f.reserved = append(f.reserved, app.Name)
+	}
+	return reason, err
+}
+
+// newQueueTestController returns a fake controller queueing applications in the given ordering, and the indexer
+// backing its application lister.
+func newQueueTestController(checker QuotaChecker, ordering QueueOrdering, apps ...*v1beta2.SparkApplication) (
+	*Controller, cache.Indexer, *record.FakeRecorder) {
+	ctrl, recorder := newFakeController(nil, nil)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, app := range apps {
+		indexer.Add(app)
+	}
+	ctrl.applicationLister = crdlisters.NewSparkApplicationLister(indexer)
+	ctrl.quotaQueueConfig = &QuotaQueueConfig{Checker: checker, Ordering: ordering, RecheckInterval: time.Minute}
+	return ctrl, indexer, recorder
+}
+
+func newQueuedApp(name string, queuedTime time.Time, priority string) *v1beta2.SparkApplication {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState:    v1beta2.ApplicationState{State: v1beta2.QueuedState},
+			QueueStatus: &v1beta2.QueueStatus{QueuedTime: metav1.NewTime(queuedTime)},
+		},
+	}
+	if priority != "" {
+		app.Annotations = map[string]string{config.QueuePriorityAnnotation: priority}
+	}
+	return app
+}
+
+func TestQueueIfOverQuota(t *testing.T) {
+	checker := &fakeQuotaChecker{fits: map[string]bool{}}
+	ctrl, indexer, recorder := newQueueTestController(checker, QueueOrderingFIFO)
+
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+	assert.True(t, ctrl.queueIfOverQuota(app))
+	assert.Equal(t, v1beta2.QueuedState, app.Status.AppState.State)
+	assert.Equal(t, int32(1), app.Status.QueueStatus.Position)
+	assert.Equal(t, "requests too many cores", app.Status.QueueStatus.Reason)
+	assert.False(t, app.Status.QueueStatus.QueuedTime.IsZero())
+	assert.Equal(t, 1, len(recorder.Events))
+	event := <-recorder.Events
+	assert.Contains(t, event, "SparkApplicationQueued")
+
+	// Applications fitting into the resource quotas are not queued unless others are queued ahead of them.
+	checker.fits["bar"] = true
+	bar := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "default"}}
+	assert.False(t, ctrl.queueIfOverQuota(bar))
+	assert.Nil(t, bar.Status.QueueStatus)
+	// The resources of new applications are counted by the enforcer without being reserved.
+	assert.Empty(t, checker.reserved)
+
+	indexer.Add(app)
+	assert.True(t, ctrl.queueIfOverQuota(bar))
+	assert.Equal(t, int32(2), bar.Status.QueueStatus.Position)
+	assert.Equal(t, "1 application(s) are queued ahead", bar.Status.QueueStatus.Reason)
+
+	// Applications are not queued if queueing is disabled.
+	ctrl.quotaQueueConfig = nil
+	baz := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "baz", Namespace: "default"}}
+	assert.False(t, ctrl.queueIfOverQuota(baz))
+}
+
+func TestDequeueIfUnderQuota(t *testing.T) {
+	now := time.Now()
+	first := newQueuedApp("first", now.Add(-2*time.Minute), "")
+	second := newQueuedApp("second", now.Add(-time.Minute), "")
+	checker := &fakeQuotaChecker{fits: map[string]bool{"second": true}}
+	ctrl, indexer, _ := newQueueTestController(checker, QueueOrderingFIFO, first, second)
+
+	// The second application waits for the first one even though it fits.
+	secondToUpdate := second.DeepCopy()
+	assert.False(t, ctrl.dequeueIfUnderQuota(secondToUpdate))
+	assert.Equal(t, int32(2), secondToUpdate.Status.QueueStatus.Position)
+	assert.Equal(t, second.Status.QueueStatus.QueuedTime, secondToUpdate.Status.QueueStatus.QueuedTime)
+
+	firstToUpdate := first.DeepCopy()
+	assert.False(t, ctrl.dequeueIfUnderQuota(firstToUpdate))
+	assert.Equal(t, int32(1), firstToUpdate.Status.QueueStatus.Position)
+	assert.Equal(t, "requests too many cores", firstToUpdate.Status.QueueStatus.Reason)
+	assert.Empty(t, checker.reserved)
+
+	// The resources of a dequeued application are reserved before the next application is checked.
+	checker.fits["first"] = true
+	assert.True(t, ctrl.dequeueIfUnderQuota(firstToUpdate))
+	assert.Nil(t, firstToUpdate.Status.QueueStatus)
+	assert.Equal(t, []string{"first"}, checker.reserved)
+
+	// The second application is at the head of the queue once the first one is no longer queued.
+	first.Status.AppState.State = v1beta2.SubmittedState
+	indexer.Update(first)
+	assert.True(t, ctrl.dequeueIfUnderQuota(secondToUpdate))
+}
+
+func TestGetQueuePositionByPriority(t *testing.T) {
+	now := time.Now()
+	low := newQueuedApp("low", now.Add(-3*time.Minute), "")
+	high := newQueuedApp("high", now.Add(-time.Minute), "10")
+	alsoHigh := newQueuedApp("also-high", now.Add(-2*time.Minute), "10")
+	ctrl, _, _ := newQueueTestController(&fakeQuotaChecker{}, QueueOrderingPriority, low, high, alsoHigh)
+
+	assert.Equal(t, 1, ctrl.getQueuePosition(alsoHigh))
+	assert.Equal(t, 2, ctrl.getQueuePosition(high))
+	assert.Equal(t, 3, ctrl.getQueuePosition(low))
+	// A new application of higher priority jumps the queue.
+	urgent := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "urgent",
+			Namespace:   "default",
+			Annotations: map[string]string{config.QueuePriorityAnnotation: "100"},
+		},
+	}
+	assert.Equal(t, 1, ctrl.getQueuePosition(urgent))
+
+	ctrl.quotaQueueConfig.Ordering = QueueOrderingFIFO
+	assert.Equal(t, 1, ctrl.getQueuePosition(low))
+	assert.Equal(t, 2, ctrl.getQueuePosition(alsoHigh))
+	assert.Equal(t, 3, ctrl.getQueuePosition(high))
+	assert.Equal(t, 4, ctrl.getQueuePosition(urgent))
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/golang/glog"
+	apiv1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+const (
+	metricsServicePortName = "metrics"
+	// metricsServiceLabel distinguishes the metrics Service from the other Services of an application.
+	metricsServiceLabel = config.LabelAnnotationPrefix + "metrics-service"
+	jmxExporterPath     = "/metrics"
+)
+
+// serviceMonitorResource is the resource of ServiceMonitors of the Prometheus Operator, which are managed through
+// the dynamic client so that the operator does not depend on the CRD being installed.
+var serviceMonitorResource = schema.GroupVersionResource{
+	Group:    "monitoring.coreos.com",
+	Version:  "v1",
+	Resource: "servicemonitors",
+}
+
+// ServiceMonitorConfig holds the operator-level settings of the ServiceMonitors created for monitored applications.
+type ServiceMonitorConfig struct {
+	// Labels are labels added to the ServiceMonitors, e.g., to match the serviceMonitorSelector of Prometheus.
+	Labels map[string]string
+}
+
+// metricsEndpoints describes where the metrics of an application are served.
+type metricsEndpoints struct {
+	port int32
+	// role is the role of the pods serving the metrics, or empty if both the driver and the executors serve them.
+	role  string
+	paths []string
+}
+
+// getMetricsEndpoints returns where the metrics of the application are served, or nil if no metrics are exposed.
+func getMetricsEndpoints(app *v1beta2.SparkApplication) (*metricsEndpoints, error) {
+	exposeDriver := app.ExposeDriverMetrics()
+	exposeExecutor := app.ExposeExecutorMetrics()
+	if !exposeDriver && !exposeExecutor {
+		return nil, nil
+	}
+
+	if app.PrometheusMonitoringEnabled() {
+		endpoints := &metricsEndpoints{
+			port:  config.DefaultPrometheusJavaAgentPort,
+			paths: []string{jmxExporterPath},
+		}
+		if app.Spec.Monitoring.Prometheus.Port != nil {
+			endpoints.port = *app.Spec.Monitoring.Prometheus.Port
+		}
+		if !exposeExecutor {
+			endpoints.role = config.SparkDriverRole
+		} else if !exposeDriver {
+			endpoints.role = config.SparkExecutorRole
+		}
+		return endpoints, nil
+	}
+
+	if app.PrometheusServletMonitoringEnabled() {
+		port, err := strconv.Atoi(getUITargetPort(app))
+		if err != nil {
+			return nil, fmt.Errorf("invalid Spark UI port: %s", getUITargetPort(app))
+		}
+		// Executor metrics are served by the driver as well.
+		endpoints := &metricsEndpoints{port: int32(port), role: config.SparkDriverRole}
+		if exposeDriver {
+			path := config.DefaultPrometheusServletPath
+			if app.Spec.Monitoring.PrometheusServlet.Path != nil && *app.Spec.Monitoring.PrometheusServlet.Path != "" {
+				path = *app.Spec.Monitoring.PrometheusServlet.Path
+			}
+			endpoints.paths = append(endpoints.paths, path)
+		}
+		if exposeExecutor {
+			endpoints.paths = append(endpoints.paths, config.PrometheusServletExecutorsPath)
+		}
+		return endpoints, nil
+	}
+
+	return nil, nil
+}
+
+// createMetricsServiceMonitor creates or updates a headless Service selecting the pods serving the metrics of the
+// application and a ServiceMonitor scraping them. Both are owned by the application.
+func createMetricsServiceMonitor(
+	app *v1beta2.SparkApplication,
+	serviceMonitorConfig *ServiceMonitorConfig,
+	kubeClient clientset.Interface,
+	dynamicClient dynamic.Interface) error {
+	endpoints, err := getMetricsEndpoints(app)
+	if err != nil || endpoints == nil {
+		return err
+	}
+
+	service := buildMetricsService(app, endpoints)
+	glog.V(2).Infof("Applying metrics Service %s for application %s/%s", service.Name, app.Namespace, app.Name)
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := kubeClient.CoreV1().Services(app.Namespace).Get(service.Name, metav1.GetOptions{})
+		if apiErrors.IsNotFound(err) {
+			_, createErr := kubeClient.CoreV1().Services(app.Namespace).Create(service)
+			return createErr
+		}
+		if err != nil {
+			return err
+		}
+
+		existing.Labels = service.Labels
+		existing.Spec.Ports = service.Spec.Ports
+		existing.Spec.Selector = service.Spec.Selector
+		_, updateErr := kubeClient.CoreV1().Services(app.Namespace).Update(existing)
+		return updateErr
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply metrics Service %s in namespace %s: %v", service.Name, app.Namespace, err)
+	}
+
+	serviceMonitor := buildServiceMonitor(app, endpoints, serviceMonitorConfig)
+	glog.V(2).Infof("Applying ServiceMonitor %s for application %s/%s", serviceMonitor.GetName(), app.Namespace, app.Name)
+	client := dynamicClient.Resource(serviceMonitorResource).Namespace(app.Namespace)
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := client.Get(serviceMonitor.GetName(), metav1.GetOptions{})
+		if apiErrors.IsNotFound(err) {
+			_, createErr := client.Create(serviceMonitor, metav1.CreateOptions{})
+			return createErr
+		}
+		if err != nil {
+			return err
+		}
+
+		serviceMonitor.SetResourceVersion(existing.GetResourceVersion())
+		_, updateErr := client.Update(serviceMonitor, metav1.UpdateOptions{})
+		return updateErr
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply ServiceMonitor %s in namespace %s: %v", serviceMonitor.GetName(), app.Namespace, err)
+	}
+
+	return nil
+}
+
+func getMetricsServiceLabels(app *v1beta2.SparkApplication) map[string]string {
+	return map[string]string{
+		config.SparkAppNameLabel: app.Name,
+		metricsServiceLabel:      "true",
+	}
+}
+
+func buildMetricsService(app *v1beta2.SparkApplication, endpoints *metricsEndpoints) *apiv1.Service {
+	selector := map[string]string{config.SparkAppNameLabel: app.Name}
+	if endpoints.role != "" {
+		selector[config.SparkRoleLabel] = endpoints.role
+	}
+
+	return &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            getMetricsServiceName(app),
+			Namespace:       app.Namespace,
+			Labels:          getMetricsServiceLabels(app),
+			OwnerReferences: []metav1.OwnerReference{*getOwnerReference(app)},
+		},
+		Spec: apiv1.ServiceSpec{
+			// The Service is headless so that each pod is scraped individually.
+			ClusterIP: apiv1.ClusterIPNone,
+			Ports: []apiv1.ServicePort{
+				{
+					Name: metricsServicePortName,
+					Port: endpoints.port,
+				},
+			},
+			Selector: selector,
+		},
+	}
+}
+
+func buildServiceMonitor(
+	app *v1beta2.SparkApplication,
+	endpoints *metricsEndpoints,
+	serviceMonitorConfig *ServiceMonitorConfig) *unstructured.Unstructured {
+	var endpointList []interface{}
+	for _, path := range endpoints.paths {
+		endpointList = append(endpointList, map[string]interface{}{
+			"port": metricsServicePortName,
+			"path": path,
+		})
+	}
+	matchLabels := make(map[string]interface{})
+	for key, value := range getMetricsServiceLabels(app) {
+		matchLabels[key] = value
+	}
+
+	labels := make(map[string]string)
+	for key, value := range serviceMonitorConfig.Labels {
+		labels[key] = value
+	}
+	for key, value := range getResourceLabels(app) {
+		labels[key] = value
+	}
+
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"selector": map[string]interface{}{
+					"matchLabels": matchLabels,
+				},
+				"endpoints":       endpointList,
+				"podTargetLabels": []interface{}{config.SparkAppNameLabel, config.SparkRoleLabel},
+			},
+		},
+	}
+	obj.SetAPIVersion(serviceMonitorResource.GroupVersion().String())
+	obj.SetKind("ServiceMonitor")
+	obj.SetName(getMetricsServiceName(app))
+	obj.SetNamespace(app.Namespace)
+	obj.SetLabels(labels)
+	obj.SetOwnerReferences([]metav1.OwnerReference{*getOwnerReference(app)})
+	return obj
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestGetMetricsEndpoints(t *testing.T) {
+	type testcase struct {
+		name       string
+		monitoring *v1beta2.MonitoringSpec
+		expected   *metricsEndpoints
+	}
+
+	testcases := []testcase{
+		{
+			name:       "no monitoring",
+			monitoring: nil,
+			expected:   nil,
+		},
+		{
+			name: "no metrics exposed",
+			monitoring: &v1beta2.MonitoringSpec{
+				Prometheus: &v1beta2.PrometheusSpec{JmxExporterJar: "/prometheus/exporter.jar"},
+			},
+			expected: nil,
+		},
+		{
+			name: "JMX exporter on driver and executors",
+			monitoring: &v1beta2.MonitoringSpec{
+				ExposeDriverMetrics:   true,
+				ExposeExecutorMetrics: true,
+				Prometheus:            &v1beta2.PrometheusSpec{JmxExporterJar: "/prometheus/exporter.jar", Port: int32ptr(8091)},
+			},
+			expected: &metricsEndpoints{port: 8091, paths: []string{"/metrics"}},
+		},
+		{
+			name: "JMX exporter on executors",
+			monitoring: &v1beta2.MonitoringSpec{
+				ExposeExecutorMetrics: true,
+				Prometheus:            &v1beta2.PrometheusSpec{JmxExporterJar: "/prometheus/exporter.jar"},
+			},
+			expected: &metricsEndpoints{port: 8090, role: config.SparkExecutorRole, paths: []string{"/metrics"}},
+		},
+		{
+			name: "PrometheusServlet",
+			monitoring: &v1beta2.MonitoringSpec{
+				ExposeDriverMetrics:   true,
+				ExposeExecutorMetrics: true,
+				PrometheusServlet:     &v1beta2.PrometheusServletSpec{},
+			},
+			expected: &metricsEndpoints{
+				port:  4040,
+				role:  config.SparkDriverRole,
+				paths: []string{config.DefaultPrometheusServletPath, config.PrometheusServletExecutorsPath},
+			},
+		},
+	}
+
+	for _, test := range testcases {
+		app := &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec:       v1beta2.SparkApplicationSpec{Monitoring: test.monitoring},
+		}
+		endpoints, err := getMetricsEndpoints(app)
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.expected, endpoints, test.name)
+	}
+}
+
+func TestCreateMetricsServiceMonitor(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			UID:       "foo-123",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Monitoring: &v1beta2.MonitoringSpec{
+				ExposeDriverMetrics: true,
+				Prometheus:          &v1beta2.PrometheusSpec{JmxExporterJar: "/prometheus/exporter.jar"},
+			},
+		},
+	}
+	serviceMonitorConfig := &ServiceMonitorConfig{Labels: map[string]string{"release": "prometheus"}}
+	kubeClient := kubeclientfake.NewSimpleClientset()
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+
+	err := createMetricsServiceMonitor(app, serviceMonitorConfig, kubeClient, dynamicClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service, err := kubeClient.CoreV1().Services(app.Namespace).Get(getMetricsServiceName(app), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, apiv1.ClusterIPNone, service.Spec.ClusterIP)
+	assert.Equal(t, map[string]string{config.SparkAppNameLabel: "foo", config.SparkRoleLabel: config.SparkDriverRole}, service.Spec.Selector)
+	assert.Equal(t, config.DefaultPrometheusJavaAgentPort, service.Spec.Ports[0].Port)
+	assert.Equal(t, "foo", service.OwnerReferences[0].Name)
+
+	serviceMonitors := dynamicClient.Resource(serviceMonitorResource).Namespace(app.Namespace)
+	serviceMonitor, err := serviceMonitors.Get(getMetricsServiceName(app), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "prometheus", serviceMonitor.GetLabels()["release"])
+	assert.Equal(t, "foo", serviceMonitor.GetOwnerReferences()[0].Name)
+	matchLabels, _, _ := unstructured.NestedStringMap(serviceMonitor.Object, "spec", "selector", "matchLabels")
+	assert.Equal(t, service.Labels, matchLabels)
+	endpoints, _, _ := unstructured.NestedSlice(serviceMonitor.Object, "spec", "endpoints")
+	assert.Equal(t, []interface{}{map[string]interface{}{"port": metricsServicePortName, "path": "/metrics"}}, endpoints)
+
+	// Exposing executor metrics on resubmission updates the existing objects.
+	app.Spec.Monitoring.ExposeExecutorMetrics = true
+	app.Spec.Monitoring.Prometheus.Port = int32ptr(8091)
+	err = createMetricsServiceMonitor(app, serviceMonitorConfig, kubeClient, dynamicClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service, err = kubeClient.CoreV1().Services(app.Namespace).Get(getMetricsServiceName(app), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]string{config.SparkAppNameLabel: "foo"}, service.Spec.Selector)
+	assert.Equal(t, int32(8091), service.Spec.Ports[0].Port)
+}
+
+func TestCreateMetricsServiceMonitorWithoutMetrics(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+	}
+	kubeClient := kubeclientfake.NewSimpleClientset()
+
+	err := createMetricsServiceMonitor(app, &ServiceMonitorConfig{}, kubeClient, nil)
+	assert.Nil(t, err)
+	services, err := kubeClient.CoreV1().Services(app.Namespace).List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, services.Items)
+}
@@ -0,0 +1,271 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+const (
+	// MinSparkProgressPollInterval is the minimum interval between two queries of the REST API of a driver.
+	MinSparkProgressPollInterval = 5 * time.Second
+	sparkRESTAPIPath             = "/api/v1/applications"
+	sparkProgressPollTimeout     = 10 * time.Second
+	sparkDriverExecutorID        = "driver"
+)
+
+// Statuses of jobs and stages reported by the Spark REST API.
+const (
+	sparkJobRunningStatus    = "RUNNING"
+	sparkJobSucceededStatus  = "SUCCEEDED"
+	sparkJobFailedStatus     = "FAILED"
+	sparkStageActiveStatus   = "ACTIVE"
+	sparkStageCompleteStatus = "COMPLETE"
+	sparkStageFailedStatus   = "FAILED"
+)
+
+// The following types hold the fields of the responses of the Spark REST API the progress is derived from.
+type sparkRESTApplication struct {
+	ID string `json:"id"`
+}
+
+type sparkRESTJob struct {
+	Status            string `json:"status"`
+	NumActiveTasks    int32  `json:"numActiveTasks"`
+	NumCompletedTasks int32  `json:"numCompletedTasks"`
+	NumFailedTasks    int32  `json:"numFailedTasks"`
+}
+
+type sparkRESTStage struct {
+	Status string `json:"status"`
+}
+
+type sparkRESTExecutor struct {
+	ID       string `json:"id"`
+	IsActive bool   `json:"isActive"`
+}
+
+// sparkProgressPoller queries the REST API of drivers in the background, making sure each driver is not queried
+// more often than once per interval, and caches the progress of the applications until it is copied into their
+// statuses by the sync workers.
+type sparkProgressPoller struct {
+	client        *http.Client
+	interval      time.Duration
+	queue         workqueue.Interface
+	mutex         sync.Mutex
+	lastPollTimes map[string]time.Time
+	// baseURLs maps keys of applications due to be polled to the base URLs of the REST API of their drivers.
+	baseURLs map[string]string
+	// results maps keys of applications to the progress last polled from their drivers.
+	results map[string]*v1beta2.SparkProgress
+}
+
+func newSparkProgressPoller(interval time.Duration) *sparkProgressPoller {
+	return &sparkProgressPoller{
+		client:        &http.Client{},
+		interval:      interval,
+		queue:         workqueue.NewNamed("spark-application-progress"),
+		lastPollTimes: make(map[string]time.Time),
+		baseURLs:      make(map[string]string),
+		results:       make(map[string]*v1beta2.SparkProgress),
+	}
+}
+
+// shouldPoll tells if the driver of the application with the given key is due to be queried, and records the
+// given time as the time of the last query if so.
+func (p *sparkProgressPoller) shouldPoll(key string, now time.Time) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if last, ok := p.lastPollTimes[key]; ok && now.Sub(last) < p.interval {
+		return false
+	}
+	p.lastPollTimes[key] = now
+	return true
+}
+
+// request queues the driver of the application with the given key to be queried at the given base URL.
+func (p *sparkProgressPoller) request(key string, baseURL string) {
+	p.mutex.Lock()
+	p.baseURLs[key] = baseURL
+	p.mutex.Unlock()
+	p.queue.Add(key)
+}
+
+// getResult returns the progress last polled from the driver of the application with the given key, if any.
+func (p *sparkProgressPoller) getResult(key string) *v1beta2.SparkProgress {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.results[key]
+}
+
+// forget removes the record of the application with the given key.
+func (p *sparkProgressPoller) forget(key string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.lastPollTimes, key)
+	delete(p.baseURLs, key)
+	delete(p.results, key)
+}
+
+// getSparkProgress queries the REST API of the driver at the given base URL for the progress of the application,
+// giving up on all the queries once the poll timeout has passed.
+func (p *sparkProgressPoller) getSparkProgress(baseURL string) (*v1beta2.SparkProgress, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sparkProgressPollTimeout)
+	defer cancel()
+
+	var applications []sparkRESTApplication
+	if err := p.get(ctx, baseURL+sparkRESTAPIPath, &applications); err != nil {
+		return nil, err
+	}
+	if len(applications) == 0 {
+		return nil, fmt.Errorf("no application reported by the driver at %s", baseURL)
+	}
+	appURL := baseURL + sparkRESTAPIPath + "/" + url.PathEscape(applications[0].ID)
+
+	var jobs []sparkRESTJob
+	if err := p.get(ctx, appURL+"/jobs", &jobs); err != nil {
+		return nil, err
+	}
+	var stages []sparkRESTStage
+	if err := p.get(ctx, appURL+"/stages", &stages); err != nil {
+		return nil, err
+	}
+	var executors []sparkRESTExecutor
+	if err := p.get(ctx, appURL+"/executors", &executors); err != nil {
+		return nil, err
+	}
+
+	progress := &v1beta2.SparkProgress{LastUpdateTime: metav1.Now()}
+	for _, job := range jobs {
+		switch job.Status {
+		case sparkJobRunningStatus:
+			progress.ActiveJobs++
+		case sparkJobSucceededStatus:
+			progress.CompletedJobs++
+		case sparkJobFailedStatus:
+			progress.FailedJobs++
+		}
+		progress.ActiveTasks += job.NumActiveTasks
+		progress.CompletedTasks += job.NumCompletedTasks
+		progress.FailedTasks += job.NumFailedTasks
+	}
+	for _, stage := range stages {
+		switch stage.Status {
+		case sparkStageActiveStatus:
+			progress.ActiveStages++
+		case sparkStageCompleteStatus:
+			progress.CompletedStages++
+		case sparkStageFailedStatus:
+			progress.FailedStages++
+		}
+	}
+	for _, executor := range executors {
+		if executor.IsActive && executor.ID != sparkDriverExecutorID {
+			progress.ActiveExecutors++
+		}
+	}
+	return progress, nil
+}
+
+func (p *sparkProgressPoller) get(ctx context.Context, rawURL string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, rawURL)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// updateSparkProgress copies the progress last polled from the REST API of the driver, which is reached through the
+// UI Service, into the status of the application, and requests the driver to be queried again in the background
+// unless it has been queried less than an interval ago.
+func (c *Controller) updateSparkProgress(app *v1beta2.SparkApplication) {
+	key := createMetaNamespaceKey(app.Namespace, app.Name)
+	if progress := c.progressPoller.getResult(key); progress != nil {
+		app.Status.SparkProgress = progress.DeepCopy()
+	}
+	address := app.Status.DriverInfo.WebUIAddress
+	// The address lacks the IP if the Service has no cluster IP.
+	if address == "" || strings.HasPrefix(address, ":") {
+		return
+	}
+	if !c.progressPoller.shouldPoll(key, time.Now()) {
+		return
+	}
+	// Query the driver again after the interval even if nothing else triggers a sync of the application.
+	c.queue.AddAfter(key, c.progressPoller.interval)
+	c.progressPoller.request(key, "http://"+address)
+}
+
+// runSparkProgressWorker queries the drivers of applications as they are requested, until the queue is shut down.
+func (c *Controller) runSparkProgressWorker() {
+	defer utilruntime.HandleCrash()
+	for c.processNextSparkProgressPoll() {
+	}
+}
+
+func (c *Controller) processNextSparkProgressPoll() bool {
+	key, quit := c.progressPoller.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.progressPoller.queue.Done(key)
+
+	c.progressPoller.mutex.Lock()
+	baseURL, ok := c.progressPoller.baseURLs[key.(string)]
+	delete(c.progressPoller.baseURLs, key.(string))
+	c.progressPoller.mutex.Unlock()
+	if !ok {
+		return true
+	}
+
+	progress, err := c.progressPoller.getSparkProgress(baseURL)
+	if err != nil {
+		glog.V(2).Infof("failed to get the progress of SparkApplication %s: %v", key, err)
+		return true
+	}
+	c.progressPoller.mutex.Lock()
+	// Drop the progress if the application has been forgotten in the meantime.
+	if _, ok := c.progressPoller.lastPollTimes[key.(string)]; ok {
+		c.progressPoller.results[key.(string)] = progress
+	}
+	c.progressPoller.mutex.Unlock()
+	// Sync the application to copy the progress into its status.
+	c.queue.Add(key)
+	return true
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// newFakeSparkRESTServer returns a server serving the responses of the Spark REST API of a driver.
+func newFakeSparkRESTServer() *httptest.Server {
+	responses := map[string]string{
+		"/api/v1/applications": `[{"id": "spark-123", "name": "foo"}]`,
+		"/api/v1/applications/spark-123/jobs": `[
+			{"jobId": 2, "status": "RUNNING", "numActiveTasks": 4, "numCompletedTasks": 6, "numFailedTasks": 1},
+			{"jobId": 1, "status": "FAILED", "numActiveTasks": 0, "numCompletedTasks": 3, "numFailedTasks": 4},
+			{"jobId": 0, "status": "SUCCEEDED", "numActiveTasks": 0, "numCompletedTasks": 10, "numFailedTasks": 0}]`,
+		"/api/v1/applications/spark-123/stages": `[
+			{"stageId": 3, "status": "ACTIVE"},
+			{"stageId": 2, "status": "PENDING"},
+			{"stageId": 1, "status": "FAILED"},
+			{"stageId": 0, "status": "COMPLETE"}]`,
+		"/api/v1/applications/spark-123/executors": `[
+			{"id": "driver", "isActive": true},
+			{"id": "1", "isActive": true},
+			{"id": "2", "isActive": true}]`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+}
+
+func TestGetSparkProgress(t *testing.T) {
+	server := newFakeSparkRESTServer()
+	defer server.Close()
+
+	poller := newSparkProgressPoller(time.Minute)
+	progress, err := poller.getSparkProgress(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int32(1), progress.ActiveJobs)
+	assert.Equal(t, int32(1), progress.CompletedJobs)
+	assert.Equal(t, int32(1), progress.FailedJobs)
+	assert.Equal(t, int32(1), progress.ActiveStages)
+	assert.Equal(t, int32(1), progress.CompletedStages)
+	assert.Equal(t, int32(1), progress.FailedStages)
+	assert.Equal(t, int32(4), progress.ActiveTasks)
+	assert.Equal(t, int32(19), progress.CompletedTasks)
+	assert.Equal(t, int32(5), progress.FailedTasks)
+	assert.Equal(t, int32(2), progress.ActiveExecutors)
+	assert.False(t, progress.LastUpdateTime.IsZero())
+
+	// The driver has not registered the application yet.
+	notReady := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer notReady.Close()
+	_, err = poller.getSparkProgress(notReady.URL)
+	assert.NotNil(t, err)
+}
+
+func TestSparkProgressPollerShouldPoll(t *testing.T) {
+	poller := newSparkProgressPoller(10 * time.Second)
+	now := time.Now()
+
+	assert.True(t, poller.shouldPoll("default/foo", now))
+	assert.False(t, poller.shouldPoll("default/foo", now.Add(5*time.Second)))
+	assert.True(t, poller.shouldPoll("default/bar", now.Add(5*time.Second)))
+	assert.True(t, poller.shouldPoll("default/foo", now.Add(10*time.Second)))
+
+	poller.forget("default/foo")
+	assert.True(t, poller.shouldPoll("default/foo", now.Add(11*time.Second)))
+}
+
+func TestUpdateSparkProgress(t *testing.T) {
+	server := newFakeSparkRESTServer()
+	defer server.Close()
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{
+				State: v1beta2.RunningState,
+			},
+			DriverInfo: v1beta2.DriverInfo{
+				WebUIAddress: strings.TrimPrefix(server.URL, "http://"),
+			},
+		},
+	}
+
+	ctrl, _ := newFakeController(app, nil)
+	ctrl.progressPoller = newSparkProgressPoller(time.Minute)
+	key := createMetaNamespaceKey(app.Namespace, app.Name)
+
+	// The driver is queried in the background, and the application is synced again once it has been.
+	ctrl.updateSparkProgress(app)
+	assert.Nil(t, app.Status.SparkProgress)
+	assert.Equal(t, 1, ctrl.progressPoller.queue.Len())
+	assert.True(t, ctrl.processNextSparkProgressPoll())
+	assert.Equal(t, 1, ctrl.queue.Len())
+	ctrl.updateSparkProgress(app)
+	if assert.NotNil(t, app.Status.SparkProgress) {
+		assert.Equal(t, int32(1), app.Status.SparkProgress.ActiveJobs)
+		assert.Equal(t, int32(2), app.Status.SparkProgress.ActiveExecutors)
+	}
+
+	// The driver is not queried again within the interval.
+	assert.Equal(t, 0, ctrl.progressPoller.queue.Len())
+
+	// The progress is dropped once the application is forgotten.
+	ctrl.progressPoller.forget(key)
+	assert.Nil(t, ctrl.progressPoller.getResult(key))
+}
@@ -21,11 +21,20 @@ import (
 
 	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdlisters "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/listers/sparkoperator.k8s.io/v1beta2"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
 )
 
+const (
+	namespaceMetricLabel = "namespace"
+	stateMetricLabel     = "state"
+	// newStateMetricLabelValue is the value of the state label of applications that have not been handled yet.
+	newStateMetricLabelValue = "NEW"
+)
+
 type sparkAppMetrics struct {
 	labels []string
 	prefix string
@@ -37,10 +46,15 @@ type sparkAppMetrics struct {
 	sparkAppFailedSubmissionCount *prometheus.CounterVec
 	sparkAppRunningCount          *util.PositiveGauge
 
-	sparkAppSuccessExecutionTime  *prometheus.SummaryVec
-	sparkAppFailureExecutionTime  *prometheus.SummaryVec
-	sparkAppStartLatency          *prometheus.SummaryVec
-	sparkAppStartLatencyHistogram *prometheus.HistogramVec
+	sparkAppSuccessExecutionTime           *prometheus.SummaryVec
+	sparkAppFailureExecutionTime           *prometheus.SummaryVec
+	sparkAppStartLatency                   *prometheus.SummaryVec
+	sparkAppStartLatencyHistogram          *prometheus.HistogramVec
+	sparkAppSuccessExecutionTimeHistogram  *prometheus.HistogramVec
+	sparkAppFailureExecutionTimeHistogram  *prometheus.HistogramVec
+	sparkAppSubmissionJobDurationHistogram *prometheus.HistogramVec
+	sparkAppDriverPendingDurationHistogram *prometheus.HistogramVec
+	sparkAppStateDuration                  *prometheus.HistogramVec
 
 	sparkAppExecutorRunningCount *util.PositiveGauge
 	sparkAppExecutorFailureCount *prometheus.CounterVec
@@ -119,6 +133,46 @@ func newSparkAppMetrics(metricsConfig *util.MetricConfig) *sparkAppMetrics {
 		},
 		validLabels,
 	)
+	sparkAppSuccessExecutionTimeHistogram := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    util.CreateValidMetricNameLabel(prefix, "spark_app_success_execution_time_seconds"),
+			Help:    "Spark App Successful Execution Runtime counts in buckets via the Operator",
+			Buckets: metricsConfig.MetricsExecutionTimeBuckets,
+		},
+		validLabels,
+	)
+	sparkAppFailureExecutionTimeHistogram := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    util.CreateValidMetricNameLabel(prefix, "spark_app_failure_execution_time_seconds"),
+			Help:    "Spark App Failed Execution Runtime counts in buckets via the Operator",
+			Buckets: metricsConfig.MetricsExecutionTimeBuckets,
+		},
+		validLabels,
+	)
+	sparkAppSubmissionJobDurationHistogram := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    util.CreateValidMetricNameLabel(prefix, "spark_app_submission_job_duration_seconds"),
+			Help:    "Spark App Submission Job Duration counts in buckets via the Operator",
+			Buckets: metricsConfig.MetricsSubmissionJobDurationBuckets,
+		},
+		validLabels,
+	)
+	sparkAppDriverPendingDurationHistogram := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    util.CreateValidMetricNameLabel(prefix, "spark_app_driver_pending_duration_seconds"),
+			Help:    "Spark App Driver Pending to Running Duration counts in buckets via the Operator",
+			Buckets: metricsConfig.MetricsDriverPendingDurationBuckets,
+		},
+		validLabels,
+	)
+	sparkAppStateDuration := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    util.CreateValidMetricNameLabel(prefix, "spark_app_state_duration_seconds"),
+			Help:    "Time Spark Apps spent in each state before transitioning out of it",
+			Buckets: prometheus.ExponentialBuckets(1, 4, 9),
+		},
+		[]string{namespaceMetricLabel, stateMetricLabel},
+	)
 	sparkAppExecutorSuccessCount := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_executor_success_count"),
@@ -139,21 +193,26 @@ func newSparkAppMetrics(metricsConfig *util.MetricConfig) *sparkAppMetrics {
 		"spark_app_executor_running_count"), "Spark App Running Executor Count via the Operator", validLabels)
 
 	return &sparkAppMetrics{
-		labels:                        validLabels,
-		prefix:                        prefix,
-		sparkAppCount:                 sparkAppCount,
-		sparkAppSubmitCount:           sparkAppSubmitCount,
-		sparkAppRunningCount:          sparkAppRunningCount,
-		sparkAppSuccessCount:          sparkAppSuccessCount,
-		sparkAppFailureCount:          sparkAppFailureCount,
-		sparkAppFailedSubmissionCount: sparkAppFailedSubmissionCount,
-		sparkAppSuccessExecutionTime:  sparkAppSuccessExecutionTime,
-		sparkAppFailureExecutionTime:  sparkAppFailureExecutionTime,
-		sparkAppStartLatency:          sparkAppStartLatency,
-		sparkAppStartLatencyHistogram: sparkAppStartLatencyHistogram,
-		sparkAppExecutorRunningCount:  sparkAppExecutorRunningCount,
-		sparkAppExecutorSuccessCount:  sparkAppExecutorSuccessCount,
-		sparkAppExecutorFailureCount:  sparkAppExecutorFailureCount,
+		labels:                                 validLabels,
+		prefix:                                 prefix,
+		sparkAppCount:                          sparkAppCount,
+		sparkAppSubmitCount:                    sparkAppSubmitCount,
+		sparkAppRunningCount:                   sparkAppRunningCount,
+		sparkAppSuccessCount:                   sparkAppSuccessCount,
+		sparkAppFailureCount:                   sparkAppFailureCount,
+		sparkAppFailedSubmissionCount:          sparkAppFailedSubmissionCount,
+		sparkAppSuccessExecutionTime:           sparkAppSuccessExecutionTime,
+		sparkAppFailureExecutionTime:           sparkAppFailureExecutionTime,
+		sparkAppStartLatency:                   sparkAppStartLatency,
+		sparkAppStartLatencyHistogram:          sparkAppStartLatencyHistogram,
+		sparkAppSuccessExecutionTimeHistogram:  sparkAppSuccessExecutionTimeHistogram,
+		sparkAppFailureExecutionTimeHistogram:  sparkAppFailureExecutionTimeHistogram,
+		sparkAppSubmissionJobDurationHistogram: sparkAppSubmissionJobDurationHistogram,
+		sparkAppDriverPendingDurationHistogram: sparkAppDriverPendingDurationHistogram,
+		sparkAppStateDuration:                  sparkAppStateDuration,
+		sparkAppExecutorRunningCount:           sparkAppExecutorRunningCount,
+		sparkAppExecutorSuccessCount:           sparkAppExecutorSuccessCount,
+		sparkAppExecutorFailureCount:           sparkAppExecutorFailureCount,
 	}
 }
 
@@ -166,6 +225,11 @@ func (sm *sparkAppMetrics) registerMetrics() {
 	util.RegisterMetric(sm.sparkAppFailureExecutionTime)
 	util.RegisterMetric(sm.sparkAppStartLatency)
 	util.RegisterMetric(sm.sparkAppStartLatencyHistogram)
+	util.RegisterMetric(sm.sparkAppSuccessExecutionTimeHistogram)
+	util.RegisterMetric(sm.sparkAppFailureExecutionTimeHistogram)
+	util.RegisterMetric(sm.sparkAppSubmissionJobDurationHistogram)
+	util.RegisterMetric(sm.sparkAppDriverPendingDurationHistogram)
+	util.RegisterMetric(sm.sparkAppStateDuration)
 	util.RegisterMetric(sm.sparkAppExecutorSuccessCount)
 	util.RegisterMetric(sm.sparkAppExecutorFailureCount)
 	sm.sparkAppRunningCount.Register()
@@ -178,6 +242,8 @@ func (sm *sparkAppMetrics) exportMetrics(oldApp, newApp *v1beta2.SparkApplicatio
 	oldState := oldApp.Status.AppState.State
 	newState := newApp.Status.AppState.State
 	if newState != oldState {
+		sm.exportStateDurationMetrics(oldApp, newApp)
+
 		if oldState == v1beta2.NewState {
 			if m, err := sm.sparkAppCount.GetMetricWith(metricLabels); err != nil {
 				glog.Errorf("Error while exporting metrics: %v", err)
@@ -186,6 +252,12 @@ func (sm *sparkAppMetrics) exportMetrics(oldApp, newApp *v1beta2.SparkApplicatio
 			}
 		}
 
+		// Only successful submissions are observed, as client mode applications also leave the PendingSubmission
+		// state to be retried after failing to create the driver pod.
+		if oldState == v1beta2.PendingSubmissionState && newState == v1beta2.SubmittedState {
+			sm.exportSubmissionJobDurationMetrics(oldApp, newApp, metricLabels)
+		}
+
 		switch newState {
 		case v1beta2.SubmittedState:
 			if m, err := sm.sparkAppSubmitCount.GetMetricWith(metricLabels); err != nil {
@@ -196,6 +268,9 @@ func (sm *sparkAppMetrics) exportMetrics(oldApp, newApp *v1beta2.SparkApplicatio
 		case v1beta2.RunningState:
 			sm.sparkAppRunningCount.Inc(metricLabels)
 			sm.exportJobStartLatencyMetrics(newApp, metricLabels)
+			if oldState == v1beta2.SubmittedState {
+				sm.exportDriverPendingDurationMetrics(oldApp, newApp, metricLabels)
+			}
 		case v1beta2.SucceedingState:
 			if !newApp.Status.SubmissionTime.Time.IsZero() && !newApp.Status.TerminationTime.Time.IsZero() {
 				d := newApp.Status.TerminationTime.Time.Sub(newApp.Status.SubmissionTime.Time)
@@ -205,6 +280,7 @@ func (sm *sparkAppMetrics) exportMetrics(oldApp, newApp *v1beta2.SparkApplicatio
 				} else {
 					m.Observe(float64(d / time.Microsecond))
 				}
+				observeHistogram(sm.sparkAppSuccessExecutionTimeHistogram, metricLabels, d)
 			}
 			sm.sparkAppRunningCount.Dec(metricLabels)
 			if m, err := sm.sparkAppSuccessCount.GetMetricWith(metricLabels); err != nil {
@@ -220,6 +296,7 @@ func (sm *sparkAppMetrics) exportMetrics(oldApp, newApp *v1beta2.SparkApplicatio
 				} else {
 					m.Observe(float64(d / time.Microsecond))
 				}
+				observeHistogram(sm.sparkAppFailureExecutionTimeHistogram, metricLabels, d)
 			}
 			sm.sparkAppRunningCount.Dec(metricLabels)
 			if m, err := sm.sparkAppFailureCount.GetMetricWith(metricLabels); err != nil {
@@ -301,6 +378,127 @@ func (sm *sparkAppMetrics) exportJobStartLatencyMetrics(app *v1beta2.SparkApplic
 	}
 }
 
+// exportStateDurationMetrics records the time the application spent in its previous state. The time is derived
+// from the transition times persisted in the status, so it is accurate across restarts of the operator.
+func (sm *sparkAppMetrics) exportStateDurationMetrics(oldApp, newApp *v1beta2.SparkApplication) {
+	enteredAt := getStateEnteredTime(oldApp)
+	if enteredAt.IsZero() {
+		return
+	}
+	labels := map[string]string{
+		namespaceMetricLabel: newApp.Namespace,
+		stateMetricLabel:     getStateMetricLabelValue(oldApp.Status.AppState.State),
+	}
+	observeHistogram(sm.sparkAppStateDuration, labels, getTransitionTime(newApp).Sub(enteredAt))
+}
+
+// exportSubmissionJobDurationMetrics records the time from the creation of the submission Job of the application,
+// which moves it to the PendingSubmission state, to the successful completion of the Job.
+func (sm *sparkAppMetrics) exportSubmissionJobDurationMetrics(oldApp, newApp *v1beta2.SparkApplication, labels map[string]string) {
+	createdAt := getStateEnteredTime(oldApp)
+	if createdAt.IsZero() {
+		return
+	}
+	completedAt := getTransitionTime(newApp)
+	if !newApp.Status.SubmissionTime.IsZero() {
+		completedAt = newApp.Status.SubmissionTime.Time
+	}
+	observeHistogram(sm.sparkAppSubmissionJobDurationHistogram, labels, completedAt.Sub(createdAt))
+}
+
+// exportDriverPendingDurationMetrics records the time from the submission of the application, which creates the
+// driver pod, to the driver running.
+func (sm *sparkAppMetrics) exportDriverPendingDurationMetrics(oldApp, newApp *v1beta2.SparkApplication, labels map[string]string) {
+	submittedAt := getStateEnteredTime(oldApp)
+	if submittedAt.IsZero() {
+		return
+	}
+	observeHistogram(sm.sparkAppDriverPendingDurationHistogram, labels, getTransitionTime(newApp).Sub(submittedAt))
+}
+
+// getStateEnteredTime returns the time the application entered its current state, or the zero time if unknown.
+// Applications whose state was last updated by an operator not recording transition times fall back to the
+// creation and submission times where they apply.
+func getStateEnteredTime(app *v1beta2.SparkApplication) time.Time {
+	if !app.Status.AppState.LastTransitionTime.IsZero() {
+		return app.Status.AppState.LastTransitionTime.Time
+	}
+	switch app.Status.AppState.State {
+	case v1beta2.NewState:
+		return app.CreationTimestamp.Time
+	case v1beta2.SubmittedState:
+		return app.Status.SubmissionTime.Time
+	}
+	return time.Time{}
+}
+
+// getTransitionTime returns the time the application transitioned to its current state, or the current time if the
+// transition has not been recorded.
+func getTransitionTime(app *v1beta2.SparkApplication) time.Time {
+	if !app.Status.AppState.LastTransitionTime.IsZero() {
+		return app.Status.AppState.LastTransitionTime.Time
+	}
+	return time.Now()
+}
+
+func observeHistogram(histogram *prometheus.HistogramVec, labels map[string]string, d time.Duration) {
+	if m, err := histogram.GetMetricWith(labels); err != nil {
+		glog.Errorf("Error while exporting metrics: %v", err)
+	} else {
+		m.Observe(d.Seconds())
+	}
+}
+
+// sparkAppStateCollector exports the number of SparkApplications in each state and namespace. The numbers are
+// computed from the informer cache on each scrape, so they do not depend on the transitions observed by the
+// running operator.
+type sparkAppStateCollector struct {
+	lister crdlisters.SparkApplicationLister
+	desc   *prometheus.Desc
+}
+
+func newSparkAppStateCollector(prefix string, lister crdlisters.SparkApplicationLister) *sparkAppStateCollector {
+	return &sparkAppStateCollector{
+		lister: lister,
+		desc: prometheus.NewDesc(
+			util.CreateValidMetricNameLabel(prefix, "spark_app_state_count"),
+			"Number of Spark Apps in each state",
+			[]string{namespaceMetricLabel, stateMetricLabel},
+			nil),
+	}
+}
+
+func (c *sparkAppStateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *sparkAppStateCollector) Collect(ch chan<- prometheus.Metric) {
+	apps, err := c.lister.List(labels.Everything())
+	if err != nil {
+		glog.Errorf("failed to list SparkApplications: %v", err)
+		return
+	}
+
+	type key struct {
+		namespace string
+		state     string
+	}
+	counts := make(map[key]int)
+	for _, app := range apps {
+		counts[key{app.Namespace, getStateMetricLabelValue(app.Status.AppState.State)}]++
+	}
+	for k, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), k.namespace, k.state)
+	}
+}
+
+func getStateMetricLabelValue(state v1beta2.ApplicationStateType) string {
+	if state == v1beta2.NewState {
+		return newStateMetricLabelValue
+	}
+	return string(state)
+}
+
 func fetchMetricLabels(app *v1beta2.SparkApplication, labels []string) map[string]string {
 	// Convert app labels into ones that can be used as metric labels.
 	validLabels := make(map[string]string)
@@ -313,7 +511,7 @@ func fetchMetricLabels(app *v1beta2.SparkApplication, labels []string) map[strin
 	for _, label := range labels {
 		if value, ok := validLabels[label]; ok {
 			metricLabels[label] = value
-		} else if label == "namespace" { // If the "namespace" label is in the metrics config, use it.
+		} else if label == namespaceMetricLabel { // If the "namespace" label is in the metrics config, use it.
 			metricLabels[label] = app.Namespace
 		} else {
 			metricLabels[label] = "Unknown"
@@ -21,8 +21,16 @@ import (
 	"net/http"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	prometheus_model "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdlisters "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/listers/sparkoperator.k8s.io/v1beta2"
 )
 
 func TestSparkAppMetrics(t *testing.T) {
@@ -72,3 +80,168 @@ func TestSparkAppMetrics(t *testing.T) {
 	assert.Equal(t, float64(10), fetchCounterValue(metrics.sparkAppExecutorFailureCount, app1))
 	assert.Equal(t, float64(10), fetchCounterValue(metrics.sparkAppExecutorSuccessCount, app1))
 }
+
+func TestSparkAppStateDurationMetrics(t *testing.T) {
+	metrics := newSparkAppMetrics(&util.MetricConfig{})
+	now := time.Now()
+	oldApp := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "foo",
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(now.Add(-300 * time.Second)),
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{
+				State:              v1beta2.SubmittedState,
+				LastTransitionTime: metav1.NewTime(now.Add(-100 * time.Second)),
+			},
+		},
+	}
+	newApp := oldApp.DeepCopy()
+	newApp.Status.AppState = v1beta2.ApplicationState{
+		State:              v1beta2.RunningState,
+		LastTransitionTime: metav1.NewTime(now),
+	}
+	metrics.exportMetrics(oldApp, newApp)
+
+	submitted := fetchHistogram(metrics.sparkAppStateDuration,
+		map[string]string{"namespace": "default", "state": string(v1beta2.SubmittedState)})
+	assert.Equal(t, uint64(1), submitted.GetSampleCount())
+	assert.Equal(t, float64(100), submitted.GetSampleSum())
+
+	// The creation time is used for applications leaving the new state.
+	oldApp.Status.AppState = v1beta2.ApplicationState{State: v1beta2.NewState}
+	newApp.Status.AppState = v1beta2.ApplicationState{
+		State:              v1beta2.SubmittedState,
+		LastTransitionTime: metav1.NewTime(now),
+	}
+	metrics.exportMetrics(oldApp, newApp)
+
+	created := fetchHistogram(metrics.sparkAppStateDuration,
+		map[string]string{"namespace": "default", "state": "NEW"})
+	assert.Equal(t, uint64(1), created.GetSampleCount())
+	assert.Equal(t, float64(300), created.GetSampleSum())
+
+	// No time is recorded if the time the application entered its state is unknown.
+	oldApp.Status.AppState = v1beta2.ApplicationState{State: v1beta2.RunningState}
+	newApp.Status.AppState = v1beta2.ApplicationState{State: v1beta2.SucceedingState}
+	metrics.exportMetrics(oldApp, newApp)
+
+	running := fetchHistogram(metrics.sparkAppStateDuration,
+		map[string]string{"namespace": "default", "state": string(v1beta2.RunningState)})
+	assert.Equal(t, uint64(0), running.GetSampleCount())
+}
+
+func TestSparkAppStateCollector(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	apps := []struct {
+		name      string
+		namespace string
+		state     v1beta2.ApplicationStateType
+	}{
+		{"foo", "default", v1beta2.RunningState},
+		{"bar", "default", v1beta2.RunningState},
+		{"baz", "default", v1beta2.NewState},
+		{"qux", "test", v1beta2.CompletedState},
+	}
+	for _, app := range apps {
+		indexer.Add(&v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{Name: app.name, Namespace: app.namespace},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState: v1beta2.ApplicationState{State: app.state},
+			},
+		})
+	}
+
+	collector := newSparkAppStateCollector("", crdlisters.NewSparkApplicationLister(indexer))
+	ch := make(chan prometheus.Metric, len(apps))
+	collector.Collect(ch)
+	close(ch)
+
+	counts := make(map[string]float64)
+	for m := range ch {
+		pb := &prometheus_model.Metric{}
+		m.Write(pb)
+		labels := make(map[string]string)
+		for _, pair := range pb.GetLabel() {
+			labels[pair.GetName()] = pair.GetValue()
+		}
+		counts[labels["namespace"]+"/"+labels["state"]] = pb.GetGauge().GetValue()
+	}
+	assert.Equal(t, map[string]float64{
+		"default/RUNNING": 2,
+		"default/NEW":     1,
+		"test/COMPLETED":  1,
+	}, counts)
+}
+
+func fetchHistogram(m *prometheus.HistogramVec, labels map[string]string) *prometheus_model.Histogram {
+	pb := &prometheus_model.Metric{}
+	m.With(labels).(prometheus.Metric).Write(pb)
+	return pb.GetHistogram()
+}
+
+func TestSparkAppDurationHistograms(t *testing.T) {
+	metrics := newSparkAppMetrics(&util.MetricConfig{
+		MetricsExecutionTimeBuckets:         []float64{60, 600},
+		MetricsSubmissionJobDurationBuckets: []float64{10, 60},
+		MetricsDriverPendingDurationBuckets: []float64{10, 60},
+	})
+	labels := map[string]string{}
+	now := time.Now()
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{
+				State:              v1beta2.PendingSubmissionState,
+				LastTransitionTime: metav1.NewTime(now.Add(-60 * time.Second)),
+			},
+		},
+	}
+
+	// Leaving the PendingSubmission state for a retry is not a submission.
+	retried := app.DeepCopy()
+	retried.Status.AppState = v1beta2.ApplicationState{
+		State:              v1beta2.PendingRerunState,
+		LastTransitionTime: metav1.NewTime(now.Add(-50 * time.Second)),
+	}
+	metrics.exportMetrics(app, retried)
+	assert.Equal(t, uint64(0), fetchHistogram(metrics.sparkAppSubmissionJobDurationHistogram, labels).GetSampleCount())
+
+	// The submission Job completes 20 seconds after it is created.
+	submitted := app.DeepCopy()
+	submitted.Status.AppState = v1beta2.ApplicationState{
+		State:              v1beta2.SubmittedState,
+		LastTransitionTime: metav1.NewTime(now.Add(-30 * time.Second)),
+	}
+	submitted.Status.SubmissionTime = metav1.NewTime(now.Add(-40 * time.Second))
+	metrics.exportMetrics(app, submitted)
+
+	submissionJobDuration := fetchHistogram(metrics.sparkAppSubmissionJobDurationHistogram, labels)
+	assert.Equal(t, uint64(1), submissionJobDuration.GetSampleCount())
+	assert.Equal(t, float64(20), submissionJobDuration.GetSampleSum())
+
+	// The driver runs 30 seconds after the application is submitted.
+	running := submitted.DeepCopy()
+	running.Status.AppState = v1beta2.ApplicationState{
+		State:              v1beta2.RunningState,
+		LastTransitionTime: metav1.NewTime(now),
+	}
+	metrics.exportMetrics(submitted, running)
+
+	driverPendingDuration := fetchHistogram(metrics.sparkAppDriverPendingDurationHistogram, labels)
+	assert.Equal(t, uint64(1), driverPendingDuration.GetSampleCount())
+	assert.Equal(t, float64(30), driverPendingDuration.GetSampleSum())
+
+	succeeding := running.DeepCopy()
+	succeeding.Status.AppState = v1beta2.ApplicationState{State: v1beta2.SucceedingState}
+	succeeding.Status.TerminationTime = metav1.NewTime(now.Add(260 * time.Second))
+	metrics.exportMetrics(running, succeeding)
+
+	executionTime := fetchHistogram(metrics.sparkAppSuccessExecutionTimeHistogram, labels)
+	assert.Equal(t, uint64(1), executionTime.GetSampleCount())
+	assert.Equal(t, float64(300), executionTime.GetSampleSum())
+	assert.Equal(t, uint64(0), executionTime.GetBucket()[0].GetCumulativeCount())
+	assert.Equal(t, uint64(1), executionTime.GetBucket()[1].GetCumulativeCount())
+	assert.Equal(t, uint64(0), fetchHistogram(metrics.sparkAppFailureExecutionTimeHistogram, labels).GetSampleCount())
+}
@@ -64,6 +64,14 @@ func getDefaultUIIngressName(app *v1beta2.SparkApplication) string {
 	return fmt.Sprintf("%s-ui-ingress", app.Name)
 }
 
+func getDriverFailureConfigMapName(app *v1beta2.SparkApplication) string {
+	return fmt.Sprintf("%s-driver-failure", app.Name)
+}
+
+func getMetricsServiceName(app *v1beta2.SparkApplication) string {
+	return fmt.Sprintf("%s-metrics", app.Name)
+}
+
 func getResourceLabels(app *v1beta2.SparkApplication) map[string]string {
 	labels := map[string]string{config.SparkAppNameLabel: app.Name}
 	if app.Status.SubmissionID != "" {
@@ -25,11 +25,13 @@ import (
 var expectedStatusString = `{
   "sparkApplicationId": "test-app",
   "submissionID": "test-app-submission",
+  "lastSubmissionAttemptTime": null,
   "submissionTime": null,
   "terminationTime": null,
   "driverInfo": {},
   "applicationState": {
-    "state": "COMPLETED"
+    "state": "COMPLETED",
+    "lastTransitionTime": null
   },
   "executorState": {
     "executor-1": "COMPLETED"
@@ -20,28 +20,106 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/golang/glog"
 
 	apiv1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/dynamic"
 	clientset "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	extensionslisters "k8s.io/client-go/listers/extensions/v1beta1"
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
 )
 
 const (
-	sparkUIPortConfigurationKey = "spark.ui.port"
-	defaultSparkWebUIPort       = "4040"
+	sparkUIPortConfigurationKey  = "spark.ui.port"
+	sparkUIProxyBaseKey          = "spark.ui.proxyBase"
+	sparkUIProxyRedirectURIKey   = "spark.ui.proxyRedirectUri"
+	defaultSparkWebUIPort        = "4040"
+	ingressClassAnnotation       = "kubernetes.io/ingress.class"
+	nginxRewriteTargetAnnotation = "nginx.ingress.kubernetes.io/rewrite-target"
 )
 
-var ingressURLRegex = regexp.MustCompile("{{\\s*[$]appName\\s*}}")
+// Supported API versions of the Ingress exposing the Spark UI.
+const (
+	IngressAPIVersionExtensionsV1beta1 = "extensions/v1beta1"
+	IngressAPIVersionNetworkingV1      = "networking.k8s.io/v1"
+)
+
+// networkingV1IngressResource is the resource of networking.k8s.io/v1 Ingresses, which are managed through the
+// dynamic client as the version of client-go in use predates the API.
+var networkingV1IngressResource = schema.GroupVersionResource{
+	Group:    "networking.k8s.io",
+	Version:  "v1",
+	Resource: "ingresses",
+}
+
+var (
+	ingressURLRegex          = regexp.MustCompile("{{\\s*[$]appName\\s*}}")
+	ingressURLNamespaceRegex = regexp.MustCompile("{{\\s*[$]appNamespace\\s*}}")
+)
+
+// SparkUIConfig holds the operator-level settings of the Service and the Ingress exposing the Spark UI. They
+// can be overridden per application through the sparkUIOptions section of the application spec.
+type SparkUIConfig struct {
+	// ServiceType is the type of the Service. Defaults to ClusterIP.
+	ServiceType apiv1.ServiceType
+	// ServiceAnnotations are annotations added to the Service.
+	ServiceAnnotations map[string]string
+	// ServiceLabels are labels added to the Service.
+	ServiceLabels map[string]string
+	// IngressClassName is the class of the Ingress.
+	IngressClassName string
+	// IngressAnnotations are annotations added to the Ingress.
+	IngressAnnotations map[string]string
+	// IngressTLSSecretName is the name of the Secret holding the TLS certificate of the Ingress, if any.
+	IngressTLSSecretName string
+	// IngressAPIVersion is the API version of the Ingress. Defaults to extensions/v1beta1.
+	IngressAPIVersion string
+}
+
+func getSparkUIingressURL(ingressURLFormat string, app *v1beta2.SparkApplication) string {
+	ingressURL := ingressURLRegex.ReplaceAllString(ingressURLFormat, app.Name)
+	return ingressURLNamespaceRegex.ReplaceAllString(ingressURL, app.Namespace)
+}
 
-func getSparkUIingressURL(ingressURLFormat string, appName string) string {
-	return ingressURLRegex.ReplaceAllString(ingressURLFormat, appName)
+// splitIngressURL splits an ingress URL into the host and the path, if the URL has one.
+func splitIngressURL(ingressURL string) (string, string) {
+	index := strings.Index(ingressURL, "/")
+	if index < 0 {
+		return ingressURL, ""
+	}
+	return ingressURL[:index], strings.TrimSuffix(ingressURL[index:], "/")
+}
+
+// configSparkUIProxyBase sets spark.ui.proxyBase so that links in the driver UI point to the given path the UI
+// is served under by a proxy, unless it is explicitly set in the sparkConf.
+func configSparkUIProxyBase(app *v1beta2.SparkApplication, proxyBase string) {
+	if app.Spec.SparkConf == nil {
+		app.Spec.SparkConf = make(map[string]string)
+	}
+	setSparkConfIfAbsent(app, sparkUIProxyBaseKey, proxyBase)
+}
+
+// configSparkUIIngressPath configures the Spark UI to be served under the path of the ingress URL, if it has one.
+func configSparkUIIngressPath(app *v1beta2.SparkApplication, ingressURLFormat string) {
+	_, path := splitIngressURL(getSparkUIingressURL(ingressURLFormat, app))
+	if path == "" {
+		return
+	}
+	configSparkUIProxyBase(app, path)
+	// The Ingress strips the path before forwarding requests, so redirects must not include the path again.
+	setSparkConfIfAbsent(app, sparkUIProxyRedirectURIKey, "/")
 }
 
 // SparkService encapsulates information about the driver UI service.
@@ -57,26 +135,172 @@ type SparkIngress struct {
 	ingressURL  string
 }
 
-func createSparkUIIngress(app *v1beta2.SparkApplication, service SparkService, ingressURLFormat string, kubeClient clientset.Interface) (*SparkIngress, error) {
-	ingressURL := getSparkUIingressURL(ingressURLFormat, app.GetName())
-	ingress := extensions.Ingress{
+// sparkUIIngress holds the settings of the Ingress exposing the Spark UI independently of the Ingress API version.
+type sparkUIIngress struct {
+	name        string
+	host        string
+	path        string
+	className   string
+	annotations map[string]string
+	tls         []v1beta2.IngressTLS
+	service     SparkService
+}
+
+func createSparkUIIngress(
+	app *v1beta2.SparkApplication,
+	service SparkService,
+	ingressURLFormat string,
+	uiConfig *SparkUIConfig,
+	kubeClient clientset.Interface,
+	dynamicClient dynamic.Interface) (*SparkIngress, error) {
+	ingressURL := getSparkUIingressURL(ingressURLFormat, app)
+	ingress := buildSparkUIIngress(app, service, ingressURL, uiConfig)
+
+	glog.Infof("Creating an Ingress %s for the Spark UI for application %s", ingress.name, app.Name)
+	var err error
+	switch uiConfig.IngressAPIVersion {
+	case IngressAPIVersionNetworkingV1:
+		_, err = dynamicClient.Resource(networkingV1IngressResource).Namespace(app.Namespace).Create(
+			buildNetworkingV1Ingress(app, ingress), metav1.CreateOptions{})
+	default:
+		_, err = kubeClient.ExtensionsV1beta1().Ingresses(app.Namespace).Create(buildExtensionsIngress(app, ingress))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &SparkIngress{
+		ingressName: ingress.name,
+		ingressURL:  ingressURL,
+	}, nil
+}
+
+func deleteSparkUIIngress(
+	app *v1beta2.SparkApplication,
+	ingressName string,
+	uiConfig *SparkUIConfig,
+	kubeClient clientset.Interface,
+	dynamicClient dynamic.Interface) error {
+	switch uiConfig.IngressAPIVersion {
+	case IngressAPIVersionNetworkingV1:
+		return dynamicClient.Resource(networkingV1IngressResource).Namespace(app.Namespace).Delete(ingressName, metav1.NewDeleteOptions(0))
+	default:
+		return kubeClient.ExtensionsV1beta1().Ingresses(app.Namespace).Delete(ingressName, metav1.NewDeleteOptions(0))
+	}
+}
+
+// getSparkUIIngress gets the Ingress with the given name and returns the error encountered, if any.
+func getSparkUIIngress(
+	app *v1beta2.SparkApplication,
+	ingressName string,
+	uiConfig *SparkUIConfig,
+	kubeClient clientset.Interface,
+	dynamicClient dynamic.Interface) error {
+	var err error
+	switch uiConfig.IngressAPIVersion {
+	case IngressAPIVersionNetworkingV1:
+		_, err = dynamicClient.Resource(networkingV1IngressResource).Namespace(app.Namespace).Get(ingressName, metav1.GetOptions{})
+	default:
+		_, err = kubeClient.ExtensionsV1beta1().Ingresses(app.Namespace).Get(ingressName, metav1.GetOptions{})
+	}
+	return err
+}
+
+// buildSparkUIIngress merges the operator-level settings with the sparkUIOptions of the application.
+func buildSparkUIIngress(app *v1beta2.SparkApplication, service SparkService, ingressURL string, uiConfig *SparkUIConfig) sparkUIIngress {
+	host, path := splitIngressURL(ingressURL)
+	ingress := sparkUIIngress{
+		name:        getDefaultUIIngressName(app),
+		host:        host,
+		path:        path,
+		className:   uiConfig.IngressClassName,
+		annotations: make(map[string]string),
+		service:     service,
+	}
+	for key, value := range uiConfig.IngressAnnotations {
+		ingress.annotations[key] = value
+	}
+	if uiConfig.IngressTLSSecretName != "" {
+		ingress.tls = []v1beta2.IngressTLS{{SecretName: uiConfig.IngressTLSSecretName}}
+	}
+
+	if options := app.Spec.SparkUIOptions; options != nil {
+		if options.IngressClassName != nil {
+			ingress.className = *options.IngressClassName
+		}
+		for key, value := range options.IngressAnnotations {
+			ingress.annotations[key] = value
+		}
+		if len(options.IngressTLS) > 0 {
+			ingress.tls = options.IngressTLS
+		}
+	}
+
+	if path != "" {
+		// The Spark UI is served at the root path, so the path of the ingress URL is stripped using a capture
+		// group in the path, which is supported by the NGINX ingress controller. The annotation can be
+		// overridden for other ingress controllers.
+		ingress.path = path + "(/|$)(.*)"
+		if _, ok := ingress.annotations[nginxRewriteTargetAnnotation]; !ok {
+			ingress.annotations[nginxRewriteTargetAnnotation] = "/$2"
+		}
+	}
+	if len(ingress.annotations) == 0 {
+		ingress.annotations = nil
+	}
+
+	return ingress
+}
+
+// getIngressTLSHosts returns the hosts of the given TLS configuration, which default to the host of the Ingress.
+func getIngressTLSHosts(tls v1beta2.IngressTLS, host string) []string {
+	if len(tls.Hosts) > 0 || host == "" {
+		return tls.Hosts
+	}
+	return []string{host}
+}
+
+func buildExtensionsIngress(app *v1beta2.SparkApplication, ingress sparkUIIngress) *extensions.Ingress {
+	annotations := ingress.annotations
+	if ingress.className != "" {
+		// The extensions/v1beta1 API only supports specifying the class through an annotation.
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		if _, ok := annotations[ingressClassAnnotation]; !ok {
+			annotations[ingressClassAnnotation] = ingress.className
+		}
+	}
+
+	var tls []extensions.IngressTLS
+	for _, t := range ingress.tls {
+		tls = append(tls, extensions.IngressTLS{
+			Hosts:      getIngressTLSHosts(t, ingress.host),
+			SecretName: t.SecretName,
+		})
+	}
+
+	return &extensions.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:            getDefaultUIIngressName(app),
+			Name:            ingress.name,
 			Namespace:       app.Namespace,
-			Labels:          getResourceLabels(app),
+			Labels:          getUIResourceLabels(app),
+			Annotations:     annotations,
 			OwnerReferences: []metav1.OwnerReference{*getOwnerReference(app)},
 		},
 		Spec: extensions.IngressSpec{
+			TLS: tls,
 			Rules: []extensions.IngressRule{{
-				Host: ingressURL,
+				Host: ingress.host,
 				IngressRuleValue: extensions.IngressRuleValue{
 					HTTP: &extensions.HTTPIngressRuleValue{
 						Paths: []extensions.HTTPIngressPath{{
+							Path: ingress.path,
 							Backend: extensions.IngressBackend{
-								ServiceName: service.serviceName,
+								ServiceName: ingress.service.serviceName,
 								ServicePort: intstr.IntOrString{
 									Type:   intstr.Int,
-									IntVal: service.servicePort,
+									IntVal: ingress.service.servicePort,
 								},
 							},
 						}},
@@ -85,21 +309,101 @@ func createSparkUIIngress(app *v1beta2.SparkApplication, service SparkService, i
 			}},
 		},
 	}
-	glog.Infof("Creating an Ingress %s for the Spark UI for application %s", ingress.Name, app.Name)
-	_, err := kubeClient.ExtensionsV1beta1().Ingresses(ingress.Namespace).Create(&ingress)
+}
 
-	if err != nil {
-		return nil, err
+func buildNetworkingV1Ingress(app *v1beta2.SparkApplication, ingress sparkUIIngress) *unstructured.Unstructured {
+	path := map[string]interface{}{
+		"path":     "/",
+		"pathType": "Prefix",
+		"backend": map[string]interface{}{
+			"service": map[string]interface{}{
+				"name": ingress.service.serviceName,
+				"port": map[string]interface{}{
+					"number": int64(ingress.service.servicePort),
+				},
+			},
+		},
 	}
-	return &SparkIngress{
-		ingressName: ingress.Name,
-		ingressURL:  ingressURL,
-	}, nil
+	if ingress.path != "" {
+		path["path"] = ingress.path
+		path["pathType"] = "ImplementationSpecific"
+	}
+	rule := map[string]interface{}{
+		"http": map[string]interface{}{
+			"paths": []interface{}{path},
+		},
+	}
+	if ingress.host != "" {
+		rule["host"] = ingress.host
+	}
+	spec := map[string]interface{}{
+		"rules": []interface{}{rule},
+	}
+	if ingress.className != "" {
+		spec["ingressClassName"] = ingress.className
+	}
+	if len(ingress.tls) > 0 {
+		var tls []interface{}
+		for _, t := range ingress.tls {
+			entry := map[string]interface{}{}
+			if hosts := getIngressTLSHosts(t, ingress.host); len(hosts) > 0 {
+				var hostList []interface{}
+				for _, host := range hosts {
+					hostList = append(hostList, host)
+				}
+				entry["hosts"] = hostList
+			}
+			if t.SecretName != "" {
+				entry["secretName"] = t.SecretName
+			}
+			tls = append(tls, entry)
+		}
+		spec["tls"] = tls
+	}
+
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": spec,
+		},
+	}
+	obj.SetAPIVersion(IngressAPIVersionNetworkingV1)
+	obj.SetKind("Ingress")
+	obj.SetName(ingress.name)
+	obj.SetNamespace(app.Namespace)
+	obj.SetLabels(getUIResourceLabels(app))
+	obj.SetAnnotations(ingress.annotations)
+	obj.SetOwnerReferences([]metav1.OwnerReference{*getOwnerReference(app)})
+	return obj
 }
 
 func createSparkUIService(
 	app *v1beta2.SparkApplication,
+	uiConfig *SparkUIConfig,
 	kubeClient clientset.Interface) (*SparkService, error) {
+	service, err := buildSparkUIService(app, uiConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	glog.Infof("Creating a service %s for the Spark UI for application %s", service.Name, app.Name)
+	service, err = kubeClient.CoreV1().Services(app.Namespace).Create(service)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSparkService(service), nil
+}
+
+func newSparkService(service *apiv1.Service) *SparkService {
+	return &SparkService{
+		serviceName: service.Name,
+		servicePort: service.Spec.Ports[0].Port,
+		serviceIP:   service.Spec.ClusterIP,
+	}
+}
+
+// buildSparkUIService merges the operator-level settings with the sparkUIOptions of the application.
+func buildSparkUIService(app *v1beta2.SparkApplication, uiConfig *SparkUIConfig) (*apiv1.Service, error) {
 	portStr := getUITargetPort(app)
 	port, err := strconv.Atoi(portStr)
 	roleSelector := config.SparkDriverRole
@@ -110,11 +414,43 @@ func createSparkUIService(
 		return nil, fmt.Errorf("invalid Spark UI port: %s", portStr)
 	}
 
-	service := &apiv1.Service{
+	serviceType := apiv1.ServiceTypeClusterIP
+	if uiConfig.ServiceType != "" {
+		serviceType = uiConfig.ServiceType
+	}
+	labels := make(map[string]string)
+	annotations := make(map[string]string)
+	for key, value := range uiConfig.ServiceLabels {
+		labels[key] = value
+	}
+	for key, value := range uiConfig.ServiceAnnotations {
+		annotations[key] = value
+	}
+	if options := app.Spec.SparkUIOptions; options != nil {
+		if options.ServiceType != nil {
+			serviceType = *options.ServiceType
+		}
+		for key, value := range options.ServiceLabels {
+			labels[key] = value
+		}
+		for key, value := range options.ServiceAnnotations {
+			annotations[key] = value
+		}
+	}
+	// The labels identifying the application cannot be overridden.
+	for key, value := range getUIResourceLabels(app) {
+		labels[key] = value
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+
+	return &apiv1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            getDefaultUIServiceName(app),
 			Namespace:       app.Namespace,
-			Labels:          getResourceLabels(app),
+			Labels:          labels,
+			Annotations:     annotations,
 			OwnerReferences: []metav1.OwnerReference{*getOwnerReference(app)},
 		},
 		Spec: apiv1.ServiceSpec{
@@ -128,23 +464,189 @@ func createSparkUIService(
 				config.SparkAppNameLabel: app.Name,
 				config.SparkRoleLabel:    roleSelector,
 			},
-			Type: apiv1.ServiceTypeClusterIP,
+			Type: serviceType,
 		},
+	}, nil
+}
+
+// getUIResourceLabels returns the labels of the Service and the Ingress exposing the Spark UI. Besides the labels
+// identifying the application, they carry the label the informers of the operator select objects by, so changes
+// to them are watched.
+func getUIResourceLabels(app *v1beta2.SparkApplication) map[string]string {
+	labels := getResourceLabels(app)
+	labels[config.LaunchedBySparkOperatorLabel] = "true"
+	return labels
+}
+
+// reconcileSparkUIService recreates the Service exposing the Spark UI if it is missing, or updates it if it has
+// drifted from the desired state, and returns information about the resulting Service.
+func reconcileSparkUIService(
+	app *v1beta2.SparkApplication,
+	uiConfig *SparkUIConfig,
+	kubeClient clientset.Interface,
+	serviceLister corelisters.ServiceLister) (*SparkService, error) {
+	desired, err := buildSparkUIService(app, uiConfig)
+	if err != nil {
+		return nil, err
 	}
 
-	glog.Infof("Creating a service %s for the Spark UI for application %s", service.Name, app.Name)
-	service, err = kubeClient.CoreV1().Services(app.Namespace).Create(service)
+	existing, err := serviceLister.Services(app.Namespace).Get(desired.Name)
+	if errors.IsNotFound(err) {
+		glog.Infof("Spark UI Service %s of application %s/%s is missing, recreating it", desired.Name, app.Namespace, app.Name)
+		return createSparkUIService(app, uiConfig, kubeClient)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if !hasSparkUIServiceDrifted(existing, desired) {
+		return newSparkService(existing), nil
+	}
 
-	return &SparkService{
-		serviceName: service.Name,
-		servicePort: service.Spec.Ports[0].Port,
-		serviceIP:   service.Spec.ClusterIP,
+	glog.Infof("Spark UI Service %s of application %s/%s has drifted, updating it", desired.Name, app.Namespace, app.Name)
+	updated := existing.DeepCopy()
+	updated.Labels = mergeStringMaps(updated.Labels, desired.Labels)
+	updated.Annotations = mergeStringMaps(updated.Annotations, desired.Annotations)
+	updated.Spec.Type = desired.Spec.Type
+	updated.Spec.Selector = desired.Spec.Selector
+	ports := desired.Spec.Ports
+	if desired.Spec.Type != apiv1.ServiceTypeClusterIP && len(existing.Spec.Ports) > 0 {
+		// Keep the allocated node port, if any, to avoid changing the address the UI is reachable at.
+		ports[0].NodePort = existing.Spec.Ports[0].NodePort
+	}
+	updated.Spec.Ports = ports
+	updated, err = kubeClient.CoreV1().Services(app.Namespace).Update(updated)
+	if err != nil {
+		return nil, err
+	}
+	return newSparkService(updated), nil
+}
+
+// hasSparkUIServiceDrifted tells if the fields of the existing Service managed by the operator differ from the
+// desired ones. Fields set by Kubernetes and labels or annotations added by others are ignored.
+func hasSparkUIServiceDrifted(existing, desired *apiv1.Service) bool {
+	if existing.Spec.Type != desired.Spec.Type ||
+		!equality.Semantic.DeepEqual(existing.Spec.Selector, desired.Spec.Selector) ||
+		len(existing.Spec.Ports) != len(desired.Spec.Ports) {
+		return true
+	}
+	for i := range desired.Spec.Ports {
+		if existing.Spec.Ports[i].Name != desired.Spec.Ports[i].Name || existing.Spec.Ports[i].Port != desired.Spec.Ports[i].Port {
+			return true
+		}
+	}
+	return !containsStringMap(existing.Labels, desired.Labels) || !containsStringMap(existing.Annotations, desired.Annotations)
+}
+
+// reconcileSparkUIIngress recreates the Ingress exposing the Spark UI if it is missing, or updates it if it has
+// drifted from the desired state, and returns information about the resulting Ingress. Ingresses of the
+// networking.k8s.io/v1 API are checked through the dynamic client.
+func reconcileSparkUIIngress(
+	app *v1beta2.SparkApplication,
+	service SparkService,
+	ingressURLFormat string,
+	uiConfig *SparkUIConfig,
+	kubeClient clientset.Interface,
+	dynamicClient dynamic.Interface,
+	ingressLister extensionslisters.IngressLister) (*SparkIngress, error) {
+	ingressURL := getSparkUIingressURL(ingressURLFormat, app)
+	ingress := buildSparkUIIngress(app, service, ingressURL, uiConfig)
+
+	var err error
+	switch uiConfig.IngressAPIVersion {
+	case IngressAPIVersionNetworkingV1:
+		err = reconcileNetworkingV1Ingress(app, ingress, dynamicClient)
+	default:
+		err = reconcileExtensionsIngress(app, ingress, kubeClient, ingressLister)
+	}
+	if errors.IsNotFound(err) {
+		glog.Infof("Spark UI Ingress %s of application %s/%s is missing, recreating it", ingress.name, app.Namespace, app.Name)
+		return createSparkUIIngress(app, service, ingressURLFormat, uiConfig, kubeClient, dynamicClient)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &SparkIngress{
+		ingressName: ingress.name,
+		ingressURL:  ingressURL,
 	}, nil
 }
 
+func reconcileExtensionsIngress(
+	app *v1beta2.SparkApplication,
+	ingress sparkUIIngress,
+	kubeClient clientset.Interface,
+	ingressLister extensionslisters.IngressLister) error {
+	existing, err := ingressLister.Ingresses(app.Namespace).Get(ingress.name)
+	if err != nil {
+		return err
+	}
+	desired := buildExtensionsIngress(app, ingress)
+	if equality.Semantic.DeepEqual(existing.Spec, desired.Spec) &&
+		containsStringMap(existing.Labels, desired.Labels) &&
+		containsStringMap(existing.Annotations, desired.Annotations) {
+		return nil
+	}
+
+	glog.Infof("Spark UI Ingress %s of application %s/%s has drifted, updating it", ingress.name, app.Namespace, app.Name)
+	updated := existing.DeepCopy()
+	updated.Labels = mergeStringMaps(updated.Labels, desired.Labels)
+	updated.Annotations = mergeStringMaps(updated.Annotations, desired.Annotations)
+	updated.Spec = desired.Spec
+	_, err = kubeClient.ExtensionsV1beta1().Ingresses(app.Namespace).Update(updated)
+	return err
+}
+
+func reconcileNetworkingV1Ingress(
+	app *v1beta2.SparkApplication,
+	ingress sparkUIIngress,
+	dynamicClient dynamic.Interface) error {
+	client := dynamicClient.Resource(networkingV1IngressResource).Namespace(app.Namespace)
+	existing, err := client.Get(ingress.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	desired := buildNetworkingV1Ingress(app, ingress)
+	if equality.Semantic.DeepEqual(existing.Object["spec"], desired.Object["spec"]) &&
+		containsStringMap(existing.GetLabels(), desired.GetLabels()) &&
+		containsStringMap(existing.GetAnnotations(), desired.GetAnnotations()) {
+		return nil
+	}
+
+	glog.Infof("Spark UI Ingress %s of application %s/%s has drifted, updating it", ingress.name, app.Namespace, app.Name)
+	updated := existing.DeepCopy()
+	updated.SetLabels(mergeStringMaps(updated.GetLabels(), desired.GetLabels()))
+	updated.SetAnnotations(mergeStringMaps(updated.GetAnnotations(), desired.GetAnnotations()))
+	updated.Object["spec"] = desired.Object["spec"]
+	_, err = client.Update(updated, metav1.UpdateOptions{})
+	return err
+}
+
+// containsStringMap tells if all the entries of subset are in m.
+func containsStringMap(m map[string]string, subset map[string]string) bool {
+	for key, value := range subset {
+		if v, ok := m[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeStringMaps returns a copy of m with the entries of overrides added.
+func mergeStringMaps(m map[string]string, overrides map[string]string) map[string]string {
+	if len(m) == 0 && len(overrides) == 0 {
+		return m
+	}
+	merged := make(map[string]string)
+	for key, value := range m {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
+
 // getWebUITargetPort attempts to get the Spark web UI port from configuration property spark.ui.port
 // in Spec.SparkConf if it is present, otherwise the default port is returned.
 // Note that we don't attempt to get the port from Spec.SparkConfigMap.
@@ -23,7 +23,12 @@ import (
 	"testing"
 
 	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes/fake"
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
@@ -40,7 +45,7 @@ func TestCreateSparkUIService(t *testing.T) {
 	}
 	testFn := func(test testcase, t *testing.T) {
 		fakeClient := fake.NewSimpleClientset()
-		sparkService, err := createSparkUIService(test.app, fakeClient)
+		sparkService, err := createSparkUIService(test.app, &SparkUIConfig{}, fakeClient)
 		if err != nil {
 			if test.expectError {
 				return
@@ -191,7 +196,7 @@ func TestCreateSparkUIIngress(t *testing.T) {
 		ingressURL:  app.GetName() + ".ingress.clusterName.com",
 	}
 	fakeClient := fake.NewSimpleClientset()
-	sparkIngress, err := createSparkUIIngress(app, service, ingressFormat, fakeClient)
+	sparkIngress, err := createSparkUIIngress(app, service, ingressFormat, &SparkUIConfig{}, fakeClient, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -232,3 +237,383 @@ func TestCreateSparkUIIngress(t *testing.T) {
 		t.Errorf("Service port wanted %v got %v", service.servicePort, ingressPath.Backend.ServicePort)
 	}
 }
+
+func TestConfigSparkUIProxyBase(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+	}
+	configSparkUIProxyBase(app, "/default/foo")
+	if app.Spec.SparkConf[sparkUIProxyBaseKey] != "/default/foo" {
+		t.Errorf("%s wanted %s got %s", sparkUIProxyBaseKey, "/default/foo", app.Spec.SparkConf[sparkUIProxyBaseKey])
+	}
+
+	// An explicitly configured proxy base is left untouched.
+	app.Spec.SparkConf[sparkUIProxyBaseKey] = "/custom"
+	configSparkUIProxyBase(app, "/default/foo")
+	if app.Spec.SparkConf[sparkUIProxyBaseKey] != "/custom" {
+		t.Errorf("%s wanted %s got %s", sparkUIProxyBaseKey, "/custom", app.Spec.SparkConf[sparkUIProxyBaseKey])
+	}
+}
+
+func TestCreateSparkUIServiceWithOptions(t *testing.T) {
+	loadBalancer := apiv1.ServiceTypeLoadBalancer
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			UID:       "foo-123",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkUIOptions: &v1beta2.SparkUIConfiguration{
+				ServiceType:        &loadBalancer,
+				ServiceAnnotations: map[string]string{"team": "spark", "owner": "app"},
+				ServiceLabels:      map[string]string{config.SparkAppNameLabel: "bar", "tier": "ui"},
+			},
+		},
+	}
+	uiConfig := &SparkUIConfig{
+		ServiceType:        apiv1.ServiceTypeNodePort,
+		ServiceAnnotations: map[string]string{"owner": "operator"},
+		ServiceLabels:      map[string]string{"env": "test"},
+	}
+
+	fakeClient := fake.NewSimpleClientset()
+	sparkService, err := createSparkUIService(app, uiConfig, fakeClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service, err := fakeClient.CoreV1().Services(app.Namespace).Get(sparkService.serviceName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if service.Spec.Type != apiv1.ServiceTypeLoadBalancer {
+		t.Errorf("for service type wanted %s got %s", apiv1.ServiceTypeLoadBalancer, service.Spec.Type)
+	}
+	expectedAnnotations := map[string]string{"team": "spark", "owner": "app"}
+	if !reflect.DeepEqual(expectedAnnotations, service.Annotations) {
+		t.Errorf("for annotations wanted %v got %v", expectedAnnotations, service.Annotations)
+	}
+	expectedLabels := map[string]string{config.SparkAppNameLabel: "foo", config.LaunchedBySparkOperatorLabel: "true", "tier": "ui", "env": "test"}
+	if !reflect.DeepEqual(expectedLabels, service.Labels) {
+		t.Errorf("for labels wanted %v got %v", expectedLabels, service.Labels)
+	}
+}
+
+func TestCreateSparkUIIngressWithPathAndTLS(t *testing.T) {
+	className := "nginx"
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			UID:       "foo-123",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkUIOptions: &v1beta2.SparkUIConfiguration{
+				IngressClassName:   &className,
+				IngressAnnotations: map[string]string{"cert-manager.io/cluster-issuer": "letsencrypt"},
+			},
+		},
+	}
+	service := SparkService{
+		serviceName: app.GetName() + "-ui-svc",
+		servicePort: 4040,
+	}
+	uiConfig := &SparkUIConfig{
+		IngressClassName:     "traefik",
+		IngressTLSSecretName: "ui-tls",
+	}
+	ingressFormat := "ingress.example.com/{{$appNamespace}}/{{$appName}}"
+
+	fakeClient := fake.NewSimpleClientset()
+	sparkIngress, err := createSparkUIIngress(app, service, ingressFormat, uiConfig, fakeClient, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sparkIngress.ingressURL != "ingress.example.com/default/foo" {
+		t.Errorf("Ingress URL wanted %s got %s", "ingress.example.com/default/foo", sparkIngress.ingressURL)
+	}
+
+	ingress, err := fakeClient.ExtensionsV1beta1().Ingresses(app.Namespace).Get(sparkIngress.ingressName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedAnnotations := map[string]string{
+		"cert-manager.io/cluster-issuer": "letsencrypt",
+		ingressClassAnnotation:           "nginx",
+		nginxRewriteTargetAnnotation:     "/$2",
+	}
+	if !reflect.DeepEqual(expectedAnnotations, ingress.Annotations) {
+		t.Errorf("for annotations wanted %v got %v", expectedAnnotations, ingress.Annotations)
+	}
+	expectedTLS := []extensions.IngressTLS{{Hosts: []string{"ingress.example.com"}, SecretName: "ui-tls"}}
+	if !reflect.DeepEqual(expectedTLS, ingress.Spec.TLS) {
+		t.Errorf("for TLS wanted %v got %v", expectedTLS, ingress.Spec.TLS)
+	}
+	ingressRule := ingress.Spec.Rules[0]
+	if ingressRule.Host != "ingress.example.com" {
+		t.Errorf("Ingress host wanted %s got %s", "ingress.example.com", ingressRule.Host)
+	}
+	if path := ingressRule.IngressRuleValue.HTTP.Paths[0].Path; path != "/default/foo(/|$)(.*)" {
+		t.Errorf("Ingress path wanted %s got %s", "/default/foo(/|$)(.*)", path)
+	}
+}
+
+func TestCreateSparkUINetworkingV1Ingress(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			UID:       "foo-123",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkUIOptions: &v1beta2.SparkUIConfiguration{
+				IngressTLS: []v1beta2.IngressTLS{{Hosts: []string{"*.example.com"}, SecretName: "wildcard-tls"}},
+			},
+		},
+	}
+	service := SparkService{
+		serviceName: app.GetName() + "-ui-svc",
+		servicePort: 4040,
+	}
+	uiConfig := &SparkUIConfig{
+		IngressClassName:  "nginx",
+		IngressAPIVersion: IngressAPIVersionNetworkingV1,
+	}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	sparkIngress, err := createSparkUIIngress(app, service, "{{$appName}}.example.com", uiConfig, nil, dynamicClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := getSparkUIIngress(app, sparkIngress.ingressName, uiConfig, nil, dynamicClient); err != nil {
+		t.Fatal(err)
+	}
+
+	ingress, err := dynamicClient.Resource(networkingV1IngressResource).Namespace(app.Namespace).
+		Get(sparkIngress.ingressName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ingress.GetLabels()[config.SparkAppNameLabel] != app.Name {
+		t.Errorf("Ingress of app %s has the wrong labels", app.Name)
+	}
+	if className, _, _ := unstructured.NestedString(ingress.Object, "spec", "ingressClassName"); className != "nginx" {
+		t.Errorf("Ingress class wanted %s got %s", "nginx", className)
+	}
+	rules, _, _ := unstructured.NestedSlice(ingress.Object, "spec", "rules")
+	if len(rules) != 1 {
+		t.Fatalf("wanted a single Ingress rule got %d", len(rules))
+	}
+	rule := rules[0].(map[string]interface{})
+	if host, _, _ := unstructured.NestedString(rule, "host"); host != "foo.example.com" {
+		t.Errorf("Ingress host wanted %s got %s", "foo.example.com", host)
+	}
+	paths, _, _ := unstructured.NestedSlice(rule, "http", "paths")
+	path := paths[0].(map[string]interface{})
+	if serviceName, _, _ := unstructured.NestedString(path, "backend", "service", "name"); serviceName != service.serviceName {
+		t.Errorf("Service name wanted %s got %s", service.serviceName, serviceName)
+	}
+	if port, _, _ := unstructured.NestedInt64(path, "backend", "service", "port", "number"); port != int64(service.servicePort) {
+		t.Errorf("Service port wanted %d got %d", service.servicePort, port)
+	}
+	tls, _, _ := unstructured.NestedSlice(ingress.Object, "spec", "tls")
+	if secretName, _, _ := unstructured.NestedString(tls[0].(map[string]interface{}), "secretName"); secretName != "wildcard-tls" {
+		t.Errorf("TLS secret wanted %s got %s", "wildcard-tls", secretName)
+	}
+
+	if err := deleteSparkUIIngress(app, sparkIngress.ingressName, uiConfig, nil, dynamicClient); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigSparkUIIngressPath(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+	}
+	configSparkUIIngressPath(app, "{{$appName}}.ingress.example.com")
+	if len(app.Spec.SparkConf) != 0 {
+		t.Errorf("unexpected Spark configuration %v", app.Spec.SparkConf)
+	}
+
+	configSparkUIIngressPath(app, "ingress.example.com/spark/{{$appNamespace}}/{{$appName}}/")
+	expectedConf := map[string]string{
+		sparkUIProxyBaseKey:        "/spark/default/foo",
+		sparkUIProxyRedirectURIKey: "/",
+	}
+	if !reflect.DeepEqual(expectedConf, app.Spec.SparkConf) {
+		t.Errorf("for Spark configuration wanted %v got %v", expectedConf, app.Spec.SparkConf)
+	}
+}
+
+func TestReconcileSparkUIService(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			UID:       "foo-123",
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			SubmissionID: "s1",
+		},
+	}
+	uiConfig := &SparkUIConfig{}
+	fakeClient := fake.NewSimpleClientset()
+	serviceInformer := informers.NewSharedInformerFactory(fakeClient, 0).Core().V1().Services()
+
+	// A missing Service is recreated.
+	sparkService, err := reconcileSparkUIService(app, uiConfig, fakeClient, serviceInformer.Lister())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service, err := fakeClient.CoreV1().Services(app.Namespace).Get(sparkService.serviceName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if service.Labels[config.LaunchedBySparkOperatorLabel] != "true" {
+		t.Errorf("Service of app %s is missing label %s", app.Name, config.LaunchedBySparkOperatorLabel)
+	}
+
+	// A Service matching the desired state is left untouched.
+	service.Spec.ClusterIP = "10.0.0.1"
+	serviceInformer.Informer().GetIndexer().Add(service)
+	sparkService, err = reconcileSparkUIService(app, uiConfig, fakeClient, serviceInformer.Lister())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sparkService.serviceIP != "10.0.0.1" {
+		t.Errorf("Service IP wanted %s got %s", "10.0.0.1", sparkService.serviceIP)
+	}
+
+	// A drifted Service is updated.
+	drifted := service.DeepCopy()
+	drifted.Spec.Type = apiv1.ServiceTypeNodePort
+	drifted.Spec.Ports[0].Port = 8080
+	drifted.Spec.Selector = map[string]string{"app": "other"}
+	drifted.Labels["extra"] = "label"
+	if _, err := fakeClient.CoreV1().Services(app.Namespace).Update(drifted); err != nil {
+		t.Fatal(err)
+	}
+	serviceInformer.Informer().GetIndexer().Update(drifted)
+	sparkService, err = reconcileSparkUIService(app, uiConfig, fakeClient, serviceInformer.Lister())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sparkService.servicePort != 4040 {
+		t.Errorf("Service port wanted %d got %d", 4040, sparkService.servicePort)
+	}
+	service, err = fakeClient.CoreV1().Services(app.Namespace).Get(sparkService.serviceName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if service.Spec.Type != apiv1.ServiceTypeClusterIP {
+		t.Errorf("for service type wanted %s got %s", apiv1.ServiceTypeClusterIP, service.Spec.Type)
+	}
+	expectedSelector := map[string]string{config.SparkAppNameLabel: "foo", config.SparkRoleLabel: config.SparkDriverRole}
+	if !reflect.DeepEqual(expectedSelector, service.Spec.Selector) {
+		t.Errorf("for selector wanted %v got %v", expectedSelector, service.Spec.Selector)
+	}
+	if service.Labels["extra"] != "label" {
+		t.Errorf("labels added to the Service were not preserved: %v", service.Labels)
+	}
+}
+
+func TestReconcileSparkUIIngress(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			UID:       "foo-123",
+		},
+	}
+	service := SparkService{
+		serviceName: app.GetName() + "-ui-svc",
+		servicePort: 4040,
+	}
+	uiConfig := &SparkUIConfig{}
+	ingressFormat := "{{$appName}}.example.com"
+	fakeClient := fake.NewSimpleClientset()
+	ingressInformer := informers.NewSharedInformerFactory(fakeClient, 0).Extensions().V1beta1().Ingresses()
+
+	// A missing Ingress is recreated.
+	sparkIngress, err := reconcileSparkUIIngress(app, service, ingressFormat, uiConfig, fakeClient, nil, ingressInformer.Lister())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sparkIngress.ingressURL != "foo.example.com" {
+		t.Errorf("Ingress URL wanted %s got %s", "foo.example.com", sparkIngress.ingressURL)
+	}
+	ingress, err := fakeClient.ExtensionsV1beta1().Ingresses(app.Namespace).Get(sparkIngress.ingressName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An Ingress pointing to another backend is updated.
+	drifted := ingress.DeepCopy()
+	drifted.Spec.Rules[0].IngressRuleValue.HTTP.Paths[0].Backend.ServiceName = "other"
+	if _, err := fakeClient.ExtensionsV1beta1().Ingresses(app.Namespace).Update(drifted); err != nil {
+		t.Fatal(err)
+	}
+	ingressInformer.Informer().GetIndexer().Add(drifted)
+	if _, err := reconcileSparkUIIngress(app, service, ingressFormat, uiConfig, fakeClient, nil, ingressInformer.Lister()); err != nil {
+		t.Fatal(err)
+	}
+	ingress, err = fakeClient.ExtensionsV1beta1().Ingresses(app.Namespace).Get(sparkIngress.ingressName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serviceName := ingress.Spec.Rules[0].IngressRuleValue.HTTP.Paths[0].Backend.ServiceName; serviceName != service.serviceName {
+		t.Errorf("Service name wanted %s got %s", service.serviceName, serviceName)
+	}
+}
+
+func TestReconcileSparkUINetworkingV1Ingress(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			UID:       "foo-123",
+		},
+	}
+	service := SparkService{
+		serviceName: app.GetName() + "-ui-svc",
+		servicePort: 4040,
+	}
+	uiConfig := &SparkUIConfig{IngressAPIVersion: IngressAPIVersionNetworkingV1}
+	ingressFormat := "{{$appName}}.example.com"
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	ingressClient := dynamicClient.Resource(networkingV1IngressResource).Namespace(app.Namespace)
+
+	// A missing Ingress is recreated.
+	sparkIngress, err := reconcileSparkUIIngress(app, service, ingressFormat, uiConfig, nil, dynamicClient, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ingress, err := ingressClient.Get(sparkIngress.ingressName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An Ingress with a modified host is updated.
+	unstructured.SetNestedSlice(ingress.Object, []interface{}{map[string]interface{}{"host": "other.example.com"}}, "spec", "rules")
+	if _, err := ingressClient.Update(ingress, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reconcileSparkUIIngress(app, service, ingressFormat, uiConfig, nil, dynamicClient, nil); err != nil {
+		t.Fatal(err)
+	}
+	ingress, err = ingressClient.Get(sparkIngress.ingressName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, _, _ := unstructured.NestedSlice(ingress.Object, "spec", "rules")
+	if host, _, _ := unstructured.NestedString(rules[0].(map[string]interface{}), "host"); host != "foo.example.com" {
+		t.Errorf("Ingress host wanted %s got %s", "foo.example.com", host)
+	}
+}
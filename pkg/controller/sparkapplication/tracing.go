@@ -0,0 +1,123 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"errors"
+	"time"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/tracing"
+)
+
+// recordValidationSpan records the span of the validation of the application. An application failing validation
+// is never submitted, so the run it would have started is recorded as well, starting with the validation.
+func (c *Controller) recordValidationSpan(app *v1beta2.SparkApplication, start time.Time, end time.Time, err error) {
+	if c.tracer == nil {
+		return
+	}
+	runStart := app.Status.LastSubmissionAttemptTime.Time
+	if err != nil {
+		runStart = start
+		c.tracer.RecordRun(app, runStart, end, err)
+	}
+	if runStart.IsZero() {
+		return
+	}
+	c.tracer.RecordSpan(app, runStart, tracing.ValidationSpanName, start, end, err)
+}
+
+// recordSubmissionSpan records the span of the creation of the submission Job, or the driver pod in client mode,
+// of the application.
+func (c *Controller) recordSubmissionSpan(app *v1beta2.SparkApplication, start time.Time, err error) {
+	if c.tracer == nil || app.Status.LastSubmissionAttemptTime.IsZero() {
+		return
+	}
+	c.tracer.RecordSpan(app, app.Status.LastSubmissionAttemptTime.Time, tracing.SubmissionSpanName, start, time.Now(),
+		err, tracing.ModeKey.String(string(app.Spec.Mode)), tracing.SubmissionIDKey.String(app.Status.SubmissionID))
+}
+
+// recordTransitionSpans records the spans of the phases of the current run of the application that completed with
+// the update of its status from the old to the new one, and the run itself if it completed.
+func (c *Controller) recordTransitionSpans(oldApp, newApp *v1beta2.SparkApplication) {
+	runStart := newApp.Status.LastSubmissionAttemptTime.Time
+	if c.tracer == nil || runStart.IsZero() {
+		return
+	}
+
+	oldState := oldApp.Status.AppState.State
+	newState := newApp.Status.AppState.State
+	if newState == v1beta2.RunningState {
+		// The executors are ramped up once as many as requested run for the first time.
+		target := getRequestedExecutors(newApp)
+		if countRunningExecutors(oldApp) < target && countRunningExecutors(newApp) >= target {
+			c.tracer.RecordSpan(newApp, runStart, tracing.ExecutorRampUpSpanName, getStateEnteredTime(newApp),
+				time.Now(), nil, tracing.ExecutorsKey.Int(target))
+		}
+	}
+	if newState == oldState {
+		return
+	}
+
+	enteredAt := getStateEnteredTime(oldApp)
+	leftAt := getTransitionTime(newApp)
+	var err error
+	if newApp.Status.AppState.ErrorMessage != "" {
+		err = errors.New(newApp.Status.AppState.ErrorMessage)
+	}
+	if !enteredAt.IsZero() {
+		switch oldState {
+		case v1beta2.PendingSubmissionState:
+			completedAt := leftAt
+			if newState == v1beta2.SubmittedState && !newApp.Status.SubmissionTime.IsZero() {
+				completedAt = newApp.Status.SubmissionTime.Time
+			}
+			c.tracer.RecordSpan(newApp, runStart, tracing.SubmitterSpanName, enteredAt, completedAt, err)
+		case v1beta2.SubmittedState:
+			c.tracer.RecordSpan(newApp, runStart, tracing.DriverPendingSpanName, enteredAt, leftAt, nil)
+		case v1beta2.RunningState:
+			c.tracer.RecordSpan(newApp, runStart, tracing.DriverRunningSpanName, enteredAt, leftAt, err)
+		}
+	}
+
+	switch newState {
+	case v1beta2.SucceedingState:
+		c.tracer.RecordRun(newApp, runStart, leftAt, nil)
+	case v1beta2.FailingState, v1beta2.FailedSubmissionState:
+		if err == nil {
+			err = errors.New(string(newState))
+		}
+		c.tracer.RecordRun(newApp, runStart, leftAt, err)
+	}
+}
+
+func getRequestedExecutors(app *v1beta2.SparkApplication) int {
+	if app.Spec.Executor.Instances != nil {
+		return int(*app.Spec.Executor.Instances)
+	}
+	return 1
+}
+
+func countRunningExecutors(app *v1beta2.SparkApplication) int {
+	count := 0
+	for _, state := range app.Status.ExecutorState {
+		if state == v1beta2.ExecutorRunningState {
+			count++
+		}
+	}
+	return count
+}
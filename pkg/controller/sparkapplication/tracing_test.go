@@ -0,0 +1,132 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/tracing"
+)
+
+func newTestTracer() (*tracing.Tracer, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewTracerProvider(sdktrace.NewSimpleSpanProcessor(exporter), tracing.Config{SampleRatio: 1})
+	return tracing.NewTracer(provider), exporter
+}
+
+func getSpanNames(spans []*sdktrace.SpanSnapshot) []string {
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+	return names
+}
+
+func TestRecordTransitionSpans(t *testing.T) {
+	ctrl, _ := newFakeController(nil, nil)
+	tracer, exporter := newTestTracer()
+	ctrl.tracer = tracer
+
+	now := time.Now()
+	runStart := metav1.NewTime(now.Add(-time.Minute))
+	submitted := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "uid-1"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Executor: v1beta2.ExecutorSpec{Instances: int32ptr(2)},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			LastSubmissionAttemptTime: runStart,
+			AppState: v1beta2.ApplicationState{
+				State:              v1beta2.SubmittedState,
+				LastTransitionTime: metav1.NewTime(now.Add(-30 * time.Second)),
+			},
+		},
+	}
+
+	running := submitted.DeepCopy()
+	running.Status.AppState = v1beta2.ApplicationState{
+		State:              v1beta2.RunningState,
+		LastTransitionTime: metav1.NewTime(now.Add(-20 * time.Second)),
+	}
+	running.Status.ExecutorState = map[string]v1beta2.ExecutorState{"exec-1": v1beta2.ExecutorRunningState}
+	ctrl.recordTransitionSpans(submitted, running)
+	assert.Equal(t, []string{tracing.DriverPendingSpanName}, getSpanNames(exporter.GetSpans()))
+
+	rampedUp := running.DeepCopy()
+	rampedUp.Status.ExecutorState["exec-2"] = v1beta2.ExecutorRunningState
+	ctrl.recordTransitionSpans(running, rampedUp)
+	// Executors running again later are not recorded.
+	ctrl.recordTransitionSpans(rampedUp, rampedUp.DeepCopy())
+	assert.Equal(t, []string{tracing.DriverPendingSpanName, tracing.ExecutorRampUpSpanName},
+		getSpanNames(exporter.GetSpans()))
+
+	succeeding := rampedUp.DeepCopy()
+	succeeding.Status.AppState = v1beta2.ApplicationState{
+		State:              v1beta2.SucceedingState,
+		LastTransitionTime: metav1.NewTime(now),
+	}
+	ctrl.recordTransitionSpans(rampedUp, succeeding)
+
+	spans := exporter.GetSpans()
+	assert.Equal(t, []string{tracing.DriverPendingSpanName, tracing.ExecutorRampUpSpanName,
+		tracing.DriverRunningSpanName, tracing.RunSpanName}, getSpanNames(spans))
+	runSpanContext := tracing.RunSpanContext(succeeding, runStart.Time)
+	for _, span := range spans {
+		assert.Equal(t, runSpanContext.TraceID(), span.SpanContext.TraceID())
+	}
+	run := spans[3]
+	assert.Equal(t, runSpanContext.SpanID(), run.SpanContext.SpanID())
+	assert.Equal(t, runStart.Time, run.StartTime)
+	assert.Equal(t, now, run.EndTime)
+	driverRunning := spans[2]
+	assert.Equal(t, 20*time.Second, driverRunning.EndTime.Sub(driverRunning.StartTime))
+}
+
+func TestSyncSparkApplicationRecordsValidationFailure(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "uid-1"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Monitoring: &v1beta2.MonitoringSpec{
+				Prometheus:        &v1beta2.PrometheusSpec{},
+				PrometheusServlet: &v1beta2.PrometheusServletSpec{},
+			},
+		},
+	}
+	ctrl, _ := newFakeController(app, nil)
+	tracer, exporter := newTestTracer()
+	ctrl.tracer = tracer
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(app); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ctrl.syncSparkApplication("default/foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	assert.Equal(t, []string{tracing.RunSpanName, tracing.ValidationSpanName}, getSpanNames(spans))
+	if len(spans) == 2 {
+		assert.Equal(t, spans[0].SpanContext.SpanID(), spans[1].Parent.SpanID())
+	}
+}
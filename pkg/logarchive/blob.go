@@ -0,0 +1,92 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logarchive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/google/go-cloud/blob"
+	"github.com/google/go-cloud/blob/gcsblob"
+	"github.com/google/go-cloud/blob/s3blob"
+	"github.com/google/go-cloud/gcp"
+)
+
+// blobSink archives logs as objects in a bucket of an object store.
+type blobSink struct {
+	scheme string
+	bucket string
+	prefix string
+	b      *blob.Bucket
+}
+
+func newGCSSink(ctx context.Context, bucket string, prefix string) (Sink, error) {
+	creds, err := gcp.DefaultCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := gcp.NewHTTPClient(gcp.DefaultTransport(), gcp.CredentialsTokenSource(creds))
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := gcsblob.OpenBucket(ctx, bucket, c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GCS bucket %s: %v", bucket, err)
+	}
+	return &blobSink{scheme: "gs", bucket: bucket, prefix: prefix, b: b}, nil
+}
+
+func newS3Sink(ctx context.Context, bucket string, prefix string) (Sink, error) {
+	// The region and credentials are taken from the standard AWS environment variables and shared config.
+	sess, err := session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable})
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := s3blob.OpenBucket(ctx, sess, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open S3 bucket %s: %v", bucket, err)
+	}
+	return &blobSink{scheme: "s3", bucket: bucket, prefix: prefix, b: b}, nil
+}
+
+func (s *blobSink) Name() string {
+	return s.scheme
+}
+
+func (s *blobSink) Archive(ctx context.Context, key string, r io.Reader) (string, error) {
+	objectKey := path.Join(s.prefix, key)
+	w, err := s.b.NewWriter(ctx, objectKey, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain bucket writer: %v", err)
+	}
+
+	_, copyErr := io.Copy(w, r)
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to close bucket writer: %v", err)
+	}
+	if copyErr != nil {
+		return "", fmt.Errorf("failed to write to bucket: %v", copyErr)
+	}
+
+	return fmt.Sprintf("%s://%s/%s", s.scheme, s.bucket, objectKey), nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logarchive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FileSystemSink archives logs as files under a root directory.
+type FileSystemSink struct {
+	root string
+}
+
+// NewFileSystemSink creates a FileSystemSink that writes files under the given root directory.
+func NewFileSystemSink(root string) *FileSystemSink {
+	return &FileSystemSink{root: root}
+}
+
+func (s *FileSystemSink) Name() string {
+	return "filesystem"
+}
+
+func (s *FileSystemSink) Archive(ctx context.Context, key string, r io.Reader) (string, error) {
+	path := filepath.Join(s.root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory for %s: %v", path, err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file %s: %v", path, err)
+	}
+
+	_, copyErr := io.Copy(file, &contextReader{ctx: ctx, r: r})
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to close file %s: %v", path, err)
+	}
+	if copyErr != nil {
+		return "", fmt.Errorf("failed to write file %s: %v", path, copyErr)
+	}
+
+	return path, nil
+}
+
+// contextReader stops reading from the underlying reader once its context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logarchive
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSystemSinkArchive(t *testing.T) {
+	root, err := ioutil.TempDir("", "logarchive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	sink := NewFileSystemSink(root)
+	location, err := sink.Archive(context.TODO(), "default/foo/1234/foo-driver.log", strings.NewReader("driver logs"))
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(root, "default", "foo", "1234", "foo-driver.log"), location)
+
+	content, err := ioutil.ReadFile(location)
+	assert.Nil(t, err)
+	assert.Equal(t, "driver logs", string(content))
+
+	// Archiving again under the same key overwrites the previous content.
+	_, err = sink.Archive(context.TODO(), "default/foo/1234/foo-driver.log", strings.NewReader("new"))
+	assert.Nil(t, err)
+	content, err = ioutil.ReadFile(location)
+	assert.Nil(t, err)
+	assert.Equal(t, "new", string(content))
+
+	// Archiving stops once the context is done.
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, err = sink.Archive(ctx, "default/foo/1234/foo-exec-1.log", strings.NewReader("executor logs"))
+	assert.NotNil(t, err)
+}
+
+func TestNewSink(t *testing.T) {
+	sink, err := NewSink(context.TODO(), "file:///var/log/spark")
+	assert.Nil(t, err)
+	assert.Equal(t, "filesystem", sink.Name())
+	assert.Equal(t, "/var/log/spark", sink.(*FileSystemSink).root)
+
+	sink, err = NewSink(context.TODO(), "/var/log/spark")
+	assert.Nil(t, err)
+	assert.Equal(t, "filesystem", sink.Name())
+
+	_, err = NewSink(context.TODO(), "file://")
+	assert.NotNil(t, err)
+
+	_, err = NewSink(context.TODO(), "hdfs://namenode/logs")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logarchive archives logs of Spark driver and executor pods to a pluggable sink.
+package logarchive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Sink is a destination for archived pod logs.
+type Sink interface {
+	// Name returns the name of the sink.
+	Name() string
+	// Archive stores the content read from r under the given key and returns the location of the archived content.
+	Archive(ctx context.Context, key string, r io.Reader) (string, error)
+}
+
+// NewSink creates a Sink from an archive URL. Supported URL schemes are file (a local directory, e.g., on a
+// mounted PersistentVolume), gs (Google Cloud Storage), and s3 (Amazon S3). The path of the URL, if any, is
+// used as a prefix of the archived object keys.
+func NewSink(ctx context.Context, archiveURL string) (Sink, error) {
+	u, err := url.Parse(archiveURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse log archive URL %s: %v", archiveURL, err)
+	}
+
+	switch u.Scheme {
+	case "file", "":
+		if u.Path == "" {
+			return nil, fmt.Errorf("log archive URL %s has no directory path", archiveURL)
+		}
+		return NewFileSystemSink(u.Path), nil
+	case "gs":
+		return newGCSSink(ctx, u.Host, strings.Trim(u.Path, "/"))
+	case "s3":
+		return newS3Sink(ctx, u.Host, strings.Trim(u.Path, "/"))
+	default:
+		return nil, fmt.Errorf("unsupported log archive URL scheme %q", u.Scheme)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// URLAllowlist is a list of URL prefixes the URLs of endpoints configured by users who are not trusted to make the
+// operator send requests anywhere must start with.
+type URLAllowlist []*url.URL
+
+// ParseURLAllowlist parses a comma-separated list of absolute http or https URL prefixes.
+func ParseURLAllowlist(value string) (URLAllowlist, error) {
+	var allowlist URLAllowlist
+	for _, prefix := range strings.Split(value, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix == "" {
+			continue
+		}
+		u, err := parseEndpointURL(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL prefix %q: %v", prefix, err)
+		}
+		allowlist = append(allowlist, u)
+	}
+	return allowlist, nil
+}
+
+// Allows tells if the URL has the scheme and host of one of the prefixes, and a path starting with its path at a
+// path segment boundary once dot segments are resolved. URLs with user information are never allowed.
+func (a URLAllowlist) Allows(rawURL string) bool {
+	u, err := parseEndpointURL(rawURL)
+	if err != nil || u.User != nil {
+		return false
+	}
+	urlPath := path.Clean("/" + u.Path)
+	for _, prefix := range a {
+		if u.Scheme != prefix.Scheme || !strings.EqualFold(u.Host, prefix.Host) {
+			continue
+		}
+		prefixPath := strings.TrimSuffix(prefix.Path, "/")
+		if urlPath == prefixPath || strings.HasPrefix(urlPath, prefixPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func parseEndpointURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("no host")
+	}
+	return u, nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+const (
+	// CloudEventsSpecVersion is the version of the CloudEvents specification the emitted events conform to.
+	CloudEventsSpecVersion = "1.0"
+	// CloudEventsContentType is the content type of CloudEvents in the structured mode of the HTTP binding.
+	CloudEventsContentType = "application/cloudevents+json"
+)
+
+// Types of the emitted CloudEvents.
+const (
+	SparkApplicationSubmittedEventType           = "sparkapplication.submitted"
+	SparkApplicationRunningEventType             = "sparkapplication.running"
+	SparkApplicationCompletedEventType           = "sparkapplication.completed"
+	SparkApplicationFailedEventType              = "sparkapplication.failed"
+	ScheduledSparkApplicationRunCreatedEventType = "scheduledsparkapplication.run-created"
+)
+
+// CloudEvent is a CloudEvent in the structured mode of the HTTP binding.
+type CloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject,omitempty"`
+	Time            time.Time   `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            interface{} `json:"data"`
+}
+
+// CloudEventData is the data of the emitted CloudEvents.
+type CloudEventData struct {
+	// SpecHash is the hash of the spec of the application, which tells apart events of different versions of it.
+	SpecHash string      `json:"specHash"`
+	Status   interface{} `json:"status"`
+	// RunName is the name of the SparkApplication created for a run of a ScheduledSparkApplication.
+	RunName string `json:"runName,omitempty"`
+}
+
+// CloudEventEmitter emits CloudEvents to a sink.
+type CloudEventEmitter struct {
+	sender     *Sender
+	sinkURL    string
+	dispatcher *Dispatcher
+}
+
+// NewCloudEventEmitter creates a CloudEventEmitter delivering events to the given sink URL through the given Sender,
+// in the background with the given Dispatcher.
+func NewCloudEventEmitter(sender *Sender, sinkURL string, dispatcher *Dispatcher) *CloudEventEmitter {
+	return &CloudEventEmitter{sender: sender, sinkURL: sinkURL, dispatcher: dispatcher}
+}
+
+// EmitAsync queues the event to be delivered to the sink in the background, after the events queued before with the
+// same key, giving up after the given timeout. The result of the delivery is passed to done. It returns false if the
+// event was dropped because the queue is full or the emitter has been stopped.
+func (e *CloudEventEmitter) EmitAsync(key string, event CloudEvent, timeout time.Duration, done func(error)) bool {
+	return e.dispatcher.Dispatch(key, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		done(e.Emit(ctx, event))
+	})
+}
+
+// Stop stops accepting events and waits until the queued ones have been delivered.
+func (e *CloudEventEmitter) Stop() {
+	e.dispatcher.Stop()
+}
+
+// Emit delivers the event to the sink.
+func (e *CloudEventEmitter) Emit(ctx context.Context, event CloudEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal CloudEvent: %v", err)
+	}
+	header := http.Header{}
+	header.Set("Content-Type", CloudEventsContentType)
+	return e.sender.deliver(ctx, e.sinkURL, header, body)
+}
+
+// NewSparkApplicationEvent returns a CloudEvent of the given type of the application. Events of the same type of
+// the same run of the application have the same ID, so that consumers can drop duplicates.
+func NewSparkApplicationEvent(eventType string, app *v1beta2.SparkApplication, eventTime time.Time) CloudEvent {
+	return newCloudEvent(
+		eventType,
+		fmt.Sprintf("%s/%s/%d", app.UID, eventType, app.Status.ExecutionAttempts),
+		fmt.Sprintf("/apis/%s/namespaces/%s/sparkapplications/%s", v1beta2.SchemeGroupVersion, app.Namespace, app.Name),
+		eventTime,
+		CloudEventData{SpecHash: hashSpec(app.Spec), Status: app.Status})
+}
+
+// NewScheduledSparkApplicationEvent returns a CloudEvent of the given type of the scheduled application, which
+// created the run with the given name.
+func NewScheduledSparkApplicationEvent(
+	eventType string,
+	app *v1beta2.ScheduledSparkApplication,
+	status *v1beta2.ScheduledSparkApplicationStatus,
+	runName string,
+	eventTime time.Time) CloudEvent {
+	return newCloudEvent(
+		eventType,
+		fmt.Sprintf("%s/%s/%s", app.UID, eventType, runName),
+		fmt.Sprintf("/apis/%s/namespaces/%s/scheduledsparkapplications/%s", v1beta2.SchemeGroupVersion, app.Namespace, app.Name),
+		eventTime,
+		CloudEventData{SpecHash: hashSpec(app.Spec), Status: status, RunName: runName})
+}
+
+func newCloudEvent(eventType string, id string, source string, eventTime time.Time, data CloudEventData) CloudEvent {
+	return CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              id,
+		Source:          source,
+		Type:            eventType,
+		Time:            eventTime,
+		DataContentType: "application/json",
+		Data:            data,
+	}
+}
+
+func hashSpec(spec interface{}) string {
+	// Specs always marshal successfully as they only hold JSON serializable fields.
+	specBytes, _ := json.Marshal(spec)
+	hasher := util.NewHash32()
+	hasher.Write(specBytes)
+	return hex.EncodeToString(hasher.Sum(nil))
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func TestEmitSparkApplicationEvent(t *testing.T) {
+	var contentType string
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "uid-1"},
+		Spec:       v1beta2.SparkApplicationSpec{Image: stringptr("spark:3.0.0")},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState:          v1beta2.ApplicationState{State: v1beta2.FailedState, ErrorMessage: "driver failed"},
+			ExecutionAttempts: 2,
+		},
+	}
+	eventTime := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	event := NewSparkApplicationEvent(SparkApplicationFailedEventType, app, eventTime)
+	emitter := NewCloudEventEmitter(NewSender(time.Second, 0, time.Millisecond), server.URL, NewDispatcher(1, 1))
+	defer emitter.Stop()
+	assert.Nil(t, emitter.Emit(context.TODO(), event))
+
+	assert.Equal(t, CloudEventsContentType, contentType)
+	assert.Equal(t, "1.0", received["specversion"])
+	assert.Equal(t, "uid-1/sparkapplication.failed/2", received["id"])
+	assert.Equal(t, "/apis/sparkoperator.k8s.io/v1beta2/namespaces/default/sparkapplications/foo", received["source"])
+	assert.Equal(t, "sparkapplication.failed", received["type"])
+	assert.Equal(t, "2020-06-01T12:00:00Z", received["time"])
+	assert.Equal(t, "application/json", received["datacontenttype"])
+	data := received["data"].(map[string]interface{})
+	assert.Equal(t, hashSpec(app.Spec), data["specHash"])
+	status := data["status"].(map[string]interface{})
+	assert.Equal(t, "FAILED", status["applicationState"].(map[string]interface{})["state"])
+}
+
+func TestEmitAsync(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]interface{}
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &event)
+		received = append(received, event["type"].(string))
+	}))
+	defer server.Close()
+
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+	emitter := NewCloudEventEmitter(NewSender(time.Second, 0, time.Millisecond), server.URL, NewDispatcher(1, 2))
+	var errs []error
+	done := func(err error) { errs = append(errs, err) }
+	assert.True(t, emitter.EmitAsync("default/foo", NewSparkApplicationEvent(SparkApplicationRunningEventType, app, time.Now()), time.Second, done))
+	assert.True(t, emitter.EmitAsync("default/foo", NewSparkApplicationEvent(SparkApplicationFailedEventType, app, time.Now()), time.Second, done))
+	emitter.Stop()
+	assert.False(t, emitter.EmitAsync("default/foo", NewSparkApplicationEvent(SparkApplicationCompletedEventType, app, time.Now()), time.Second, done))
+
+	// Events with the same key are delivered in order.
+	assert.Equal(t, []string{SparkApplicationRunningEventType, SparkApplicationFailedEventType}, received)
+	assert.Equal(t, []error{nil, nil}, errs)
+}
+
+func TestHashSpec(t *testing.T) {
+	spec := v1beta2.SparkApplicationSpec{Image: stringptr("spark:3.0.0")}
+	hash := hashSpec(spec)
+	assert.Len(t, hash, 8)
+	assert.Equal(t, hash, hashSpec(*spec.DeepCopy()))
+
+	spec.Image = stringptr("spark:3.0.1")
+	assert.NotEqual(t, hash, hashSpec(spec))
+}
+
+func TestNewScheduledSparkApplicationEvent(t *testing.T) {
+	app := &v1beta2.ScheduledSparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "uid-1"},
+	}
+	status := &v1beta2.ScheduledSparkApplicationStatus{LastRunName: "foo-1"}
+
+	event := NewScheduledSparkApplicationEvent(ScheduledSparkApplicationRunCreatedEventType, app, status, "foo-1", time.Now())
+	assert.Equal(t, "uid-1/scheduledsparkapplication.run-created/foo-1", event.ID)
+	assert.Equal(t, "/apis/sparkoperator.k8s.io/v1beta2/namespaces/default/scheduledsparkapplications/foo", event.Source)
+	assert.Equal(t, "foo-1", event.Data.(CloudEventData).RunName)
+	assert.Equal(t, status, event.Data.(CloudEventData).Status)
+}
+
+func stringptr(s string) *string {
+	return &s
+}
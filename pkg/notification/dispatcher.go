@@ -0,0 +1,84 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// Dispatcher runs deliveries in the background with a fixed number of workers, each with a bounded queue.
+// Deliveries dispatched with the same key are run by the same worker in the order they were dispatched, so the
+// notifications of an application are delivered in the order of its state transitions.
+type Dispatcher struct {
+	queues  []chan func()
+	wg      sync.WaitGroup
+	mutex   sync.RWMutex
+	stopped bool
+}
+
+// NewDispatcher creates a Dispatcher and starts its workers, each of which queues up to queueSize deliveries.
+func NewDispatcher(workers int, queueSize int) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	d := &Dispatcher{queues: make([]chan func(), workers)}
+	for i := range d.queues {
+		queue := make(chan func(), queueSize)
+		d.queues[i] = queue
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for delivery := range queue {
+				delivery()
+			}
+		}()
+	}
+	return d
+}
+
+// Dispatch queues the delivery to be run by the worker of the given key. It returns false if the delivery was
+// dropped because the queue of the worker is full or the Dispatcher has been stopped.
+func (d *Dispatcher) Dispatch(key string, delivery func()) bool {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	if d.stopped {
+		return false
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	select {
+	case d.queues[h.Sum32()%uint32(len(d.queues))] <- delivery:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop stops accepting deliveries and waits until the queued ones have been run.
+func (d *Dispatcher) Stop() {
+	d.mutex.Lock()
+	if !d.stopped {
+		d.stopped = true
+		for _, queue := range d.queues {
+			close(queue)
+		}
+	}
+	d.mutex.Unlock()
+	d.wg.Wait()
+}
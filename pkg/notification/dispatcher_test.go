@@ -0,0 +1,68 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatcherPreservesOrderPerKey(t *testing.T) {
+	dispatcher := NewDispatcher(4, 100)
+	var mutex sync.Mutex
+	delivered := make(map[string][]int)
+	for i := 0; i < 50; i++ {
+		for _, key := range []string{"default/foo", "default/bar", "other/foo"} {
+			key, i := key, i
+			assert.True(t, dispatcher.Dispatch(key, func() {
+				mutex.Lock()
+				defer mutex.Unlock()
+				delivered[key] = append(delivered[key], i)
+			}))
+		}
+	}
+	// Stopping the dispatcher runs the queued deliveries.
+	dispatcher.Stop()
+
+	for _, key := range []string{"default/foo", "default/bar", "other/foo"} {
+		if assert.Len(t, delivered[key], 50, key) {
+			for i, value := range delivered[key] {
+				assert.Equal(t, i, value, fmt.Sprintf("delivery %d of %s", i, key))
+			}
+		}
+	}
+	assert.False(t, dispatcher.Dispatch("default/foo", func() {}))
+}
+
+func TestDispatcherDropsDeliveriesBeyondQueueSize(t *testing.T) {
+	dispatcher := NewDispatcher(1, 1)
+	blocked := make(chan struct{})
+	started := make(chan struct{})
+	assert.True(t, dispatcher.Dispatch("default/foo", func() {
+		close(started)
+		<-blocked
+	}))
+	<-started
+
+	assert.True(t, dispatcher.Dispatch("default/foo", func() {}))
+	assert.False(t, dispatcher.Dispatch("default/foo", func() {}))
+	close(blocked)
+	dispatcher.Stop()
+}
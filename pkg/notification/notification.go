@@ -0,0 +1,142 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package notification notifies HTTP endpoints of the state transitions of SparkApplications, either with plain JSON
+// payloads or as CloudEvents.
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// SignatureHeader is the header holding the HMAC-SHA256 signature of the body of a notification, in the form of
+// sha256=<hex encoded signature>. It is only set if the endpoint has a signing key.
+const SignatureHeader = "X-Spark-Operator-Signature"
+
+// Payload is the JSON body of a notification of a state transition of a SparkApplication.
+type Payload struct {
+	Application   string    `json:"application"`
+	Namespace     string    `json:"namespace"`
+	UID           string    `json:"uid"`
+	OldState      string    `json:"oldState"`
+	NewState      string    `json:"newState"`
+	FailureReason string    `json:"failureReason,omitempty"`
+	UIURL         string    `json:"uiURL,omitempty"`
+	Attempt       int32     `json:"attempt"`
+	SubmissionID  string    `json:"submissionID,omitempty"`
+	Time          time.Time `json:"time"`
+}
+
+// Endpoint is an HTTP endpoint notifications are POSTed to.
+type Endpoint struct {
+	URL string
+	// SigningKey is the key of the HMAC signature of the notifications. Notifications are not signed if it is empty.
+	SigningKey []byte
+}
+
+// Sender POSTs notifications to endpoints, retrying failed deliveries with an exponential backoff.
+type Sender struct {
+	client        *http.Client
+	maxRetries    int
+	retryInterval time.Duration
+}
+
+// NewSender creates a Sender with the given timeout of a single delivery attempt, maximum number of retries of a
+// failed delivery, and interval before the first retry, which is doubled for every subsequent retry.
+func NewSender(timeout time.Duration, maxRetries int, retryInterval time.Duration) *Sender {
+	return &Sender{
+		client:        &http.Client{Timeout: timeout},
+		maxRetries:    maxRetries,
+		retryInterval: retryInterval,
+	}
+}
+
+// Send delivers the payload to the endpoint. Deliveries failing with a network error, a 5xx status, or a 429 status
+// are retried, while other error statuses are considered permanent failures.
+func (s *Sender) Send(ctx context.Context, endpoint Endpoint, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %v", err)
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	if len(endpoint.SigningKey) > 0 {
+		header.Set(SignatureHeader, "sha256="+Sign(endpoint.SigningKey, body))
+	}
+	return s.deliver(ctx, endpoint.URL, header, body)
+}
+
+// deliver POSTs the body with the given header to the URL, retrying failed attempts.
+func (s *Sender) deliver(ctx context.Context, url string, header http.Header, body []byte) error {
+	interval := s.retryInterval
+	for attempt := 0; ; attempt++ {
+		retryable, err := s.post(ctx, url, header, body)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= s.maxRetries {
+			return fmt.Errorf("failed to notify %s after %d attempt(s): %v", url, attempt+1, err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to notify %s: %v", url, ctx.Err())
+		case <-time.After(interval):
+		}
+		interval *= 2
+	}
+}
+
+// post makes a single delivery attempt, returning whether it is worth retrying if it fails.
+func (s *Sender) post(ctx context.Context, url string, header http.Header, body []byte) (bool, error) {
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request = request.WithContext(ctx)
+	for key, values := range header {
+		request.Header[key] = values
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return true, err
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+	retryable := response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+	return retryable, fmt.Errorf("unexpected status %s", response.Status)
+}
+
+// Sign returns the hex encoded HMAC-SHA256 signature of the body with the given key.
+func Sign(key []byte, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
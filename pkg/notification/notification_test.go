@@ -0,0 +1,101 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendSignsPayload(t *testing.T) {
+	var body []byte
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, _ = ioutil.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+	}))
+	defer server.Close()
+
+	payload := Payload{
+		Application:   "foo",
+		Namespace:     "default",
+		OldState:      "RUNNING",
+		NewState:      "FAILING",
+		FailureReason: "driver failed",
+		Attempt:       2,
+		Time:          time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+	sender := NewSender(time.Second, 0, time.Millisecond)
+	err := sender.Send(context.TODO(), Endpoint{URL: server.URL, SigningKey: []byte("secret")}, payload)
+	assert.Nil(t, err)
+
+	var received Payload
+	assert.Nil(t, json.Unmarshal(body, &received))
+	assert.Equal(t, payload, received)
+	assert.Equal(t, "sha256="+Sign([]byte("secret"), body), signature)
+
+	// Notifications to endpoints without a signing key are not signed.
+	err = sender.Send(context.TODO(), Endpoint{URL: server.URL}, payload)
+	assert.Nil(t, err)
+	assert.Equal(t, "", signature)
+}
+
+func TestSendRetries(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	sender := NewSender(time.Second, 2, time.Millisecond)
+	err := sender.Send(context.TODO(), Endpoint{URL: server.URL}, Payload{Application: "foo"})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+
+	// Retries are exhausted.
+	atomic.StoreInt32(&attempts, 0)
+	sender = NewSender(time.Second, 1, time.Millisecond)
+	err = sender.Send(context.TODO(), Endpoint{URL: server.URL}, Payload{Application: "foo"})
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestSendDoesNotRetryClientErrors(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sender := NewSender(time.Second, 3, time.Millisecond)
+	err := sender.Send(context.TODO(), Endpoint{URL: server.URL}, Payload{Application: "foo"})
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	mathrand "math/rand"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// runIDGenerator generates random IDs, except for the root spans of runs, whose IDs are taken from the context.
+type runIDGenerator struct {
+	mutex  sync.Mutex
+	random *mathrand.Rand
+}
+
+func newRunIDGenerator() *runIDGenerator {
+	var seed int64
+	binary.Read(rand.Reader, binary.LittleEndian, &seed)
+	return &runIDGenerator{random: mathrand.New(mathrand.NewSource(seed))}
+}
+
+// NewIDs implements sdktrace.IDGenerator.
+func (g *runIDGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	if ids, ok := ctx.Value(runIDsKey{}).(runIDs); ok {
+		return ids.traceID, ids.spanID
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	var traceID trace.TraceID
+	var spanID trace.SpanID
+	g.random.Read(traceID[:])
+	g.random.Read(spanID[:])
+	return traceID, spanID
+}
+
+// NewSpanID implements sdktrace.IDGenerator.
+func (g *runIDGenerator) NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	var spanID trace.SpanID
+	g.random.Read(spanID[:])
+	return spanID
+}
@@ -0,0 +1,280 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OTLPTracesPath is the path OTLP/HTTP receivers serve traces on.
+const OTLPTracesPath = "/v1/traces"
+
+// OTLPExporter exports spans to an OTLP/HTTP receiver with the JSON encoding of OTLP. Unlike the OTLP exporter of
+// OpenTelemetry, it needs neither gRPC nor protobuf, whose releases it requires conflict with the ones the Kubernetes
+// client of the operator is locked to.
+type OTLPExporter struct {
+	url    string
+	client *http.Client
+}
+
+// NewOTLPExporter returns an OTLPExporter sending the spans to the receiver at the given host and port, over HTTPS
+// unless insecure is set, giving up on a request after the given timeout.
+func NewOTLPExporter(endpoint string, insecure bool, timeout time.Duration) *OTLPExporter {
+	scheme := "https"
+	if insecure {
+		scheme = "http"
+	}
+	return &OTLPExporter{
+		url:    fmt.Sprintf("%s://%s%s", scheme, endpoint, OTLPTracesPath),
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// ExportSpans sends the spans to the receiver in one request.
+func (e *OTLPExporter) ExportSpans(ctx context.Context, spans []*sdktrace.SpanSnapshot) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(newExportTraceServiceRequest(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode %d spans: %v", len(spans), err)
+	}
+	request, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := e.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to export %d spans to %s: %v", len(spans), e.url, err)
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("failed to export %d spans to %s: %s", len(spans), e.url, response.Status)
+	}
+	return nil
+}
+
+// Shutdown closes the idle connections to the receiver.
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// The types below are the messages of the OTLP trace service, encoded as JSON as specified by OTLP/HTTP: fields
+// are named in lower camel case, trace and span IDs are hex-encoded, enums are encoded as integers, and 64-bit
+// integers as strings.
+type exportTraceServiceRequest struct {
+	ResourceSpans []*resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   otlpResource  `json:"resource"`
+	ScopeSpans []*scopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []keyValue `json:"attributes,omitempty"`
+}
+
+type scopeSpans struct {
+	Scope instrumentationScope `json:"scope"`
+	Spans []span               `json:"spans"`
+}
+
+type instrumentationScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type span struct {
+	TraceID                string     `json:"traceId"`
+	SpanID                 string     `json:"spanId"`
+	ParentSpanID           string     `json:"parentSpanId,omitempty"`
+	Name                   string     `json:"name"`
+	Kind                   int        `json:"kind"`
+	StartTimeUnixNano      uint64     `json:"startTimeUnixNano,string"`
+	EndTimeUnixNano        uint64     `json:"endTimeUnixNano,string"`
+	Attributes             []keyValue `json:"attributes,omitempty"`
+	DroppedAttributesCount int        `json:"droppedAttributesCount,omitempty"`
+	Events                 []event    `json:"events,omitempty"`
+	DroppedEventsCount     int        `json:"droppedEventsCount,omitempty"`
+	Links                  []link     `json:"links,omitempty"`
+	DroppedLinksCount      int        `json:"droppedLinksCount,omitempty"`
+	Status                 status     `json:"status"`
+}
+
+type event struct {
+	TimeUnixNano           uint64     `json:"timeUnixNano,string"`
+	Name                   string     `json:"name"`
+	Attributes             []keyValue `json:"attributes,omitempty"`
+	DroppedAttributesCount int        `json:"droppedAttributesCount,omitempty"`
+}
+
+type link struct {
+	TraceID                string     `json:"traceId"`
+	SpanID                 string     `json:"spanId"`
+	Attributes             []keyValue `json:"attributes,omitempty"`
+	DroppedAttributesCount int        `json:"droppedAttributesCount,omitempty"`
+}
+
+type status struct {
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *int64   `json:"intValue,omitempty,string"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// Status codes of OTLP, which are numbered differently from the ones of OpenTelemetry.
+const (
+	otlpStatusCodeUnset = 0
+	otlpStatusCodeOK    = 1
+	otlpStatusCodeError = 2
+)
+
+// newExportTraceServiceRequest groups the spans by resource and instrumentation library, in the order they come.
+func newExportTraceServiceRequest(spans []*sdktrace.SpanSnapshot) *exportTraceServiceRequest {
+	request := &exportTraceServiceRequest{}
+	resources := make(map[attribute.Distinct]*resourceSpans)
+	type scopeKey struct {
+		resource attribute.Distinct
+		name     string
+		version  string
+	}
+	scopes := make(map[scopeKey]*scopeSpans)
+	for _, s := range spans {
+		resourceKey := s.Resource.Equivalent()
+		rs, ok := resources[resourceKey]
+		if !ok {
+			rs = &resourceSpans{Resource: otlpResource{Attributes: newKeyValues(s.Resource.Attributes())}}
+			resources[resourceKey] = rs
+			request.ResourceSpans = append(request.ResourceSpans, rs)
+		}
+		key := scopeKey{resourceKey, s.InstrumentationLibrary.Name, s.InstrumentationLibrary.Version}
+		ss, ok := scopes[key]
+		if !ok {
+			ss = &scopeSpans{Scope: instrumentationScope{Name: key.name, Version: key.version}}
+			scopes[key] = ss
+			rs.ScopeSpans = append(rs.ScopeSpans, ss)
+		}
+		ss.Spans = append(ss.Spans, newSpan(s))
+	}
+	return request
+}
+
+func newSpan(s *sdktrace.SpanSnapshot) span {
+	result := span{
+		TraceID:                s.SpanContext.TraceID().String(),
+		SpanID:                 s.SpanContext.SpanID().String(),
+		Name:                   s.Name,
+		Kind:                   int(s.SpanKind),
+		StartTimeUnixNano:      toUnixNano(s.StartTime),
+		EndTimeUnixNano:        toUnixNano(s.EndTime),
+		Attributes:             newKeyValues(s.Attributes),
+		DroppedAttributesCount: s.DroppedAttributeCount,
+		DroppedEventsCount:     s.DroppedMessageEventCount,
+		DroppedLinksCount:      s.DroppedLinkCount,
+		Status:                 status{Message: s.StatusMessage, Code: otlpStatusCodeUnset},
+	}
+	if s.Parent.SpanID().IsValid() {
+		result.ParentSpanID = s.Parent.SpanID().String()
+	}
+	switch s.StatusCode {
+	case codes.Ok:
+		result.Status.Code = otlpStatusCodeOK
+	case codes.Error:
+		result.Status.Code = otlpStatusCodeError
+	}
+	for _, e := range s.MessageEvents {
+		result.Events = append(result.Events, event{
+			TimeUnixNano:           toUnixNano(e.Time),
+			Name:                   e.Name,
+			Attributes:             newKeyValues(e.Attributes),
+			DroppedAttributesCount: e.DroppedAttributeCount,
+		})
+	}
+	for _, l := range s.Links {
+		result.Links = append(result.Links, newLink(l))
+	}
+	return result
+}
+
+func newLink(l trace.Link) link {
+	return link{
+		TraceID:                l.TraceID().String(),
+		SpanID:                 l.SpanID().String(),
+		Attributes:             newKeyValues(l.Attributes),
+		DroppedAttributesCount: l.DroppedAttributeCount,
+	}
+}
+
+func newKeyValues(attributes []attribute.KeyValue) []keyValue {
+	var result []keyValue
+	for _, a := range attributes {
+		var value anyValue
+		switch a.Value.Type() {
+		case attribute.BOOL:
+			v := a.Value.AsBool()
+			value.BoolValue = &v
+		case attribute.INT64:
+			v := a.Value.AsInt64()
+			value.IntValue = &v
+		case attribute.FLOAT64:
+			v := a.Value.AsFloat64()
+			value.DoubleValue = &v
+		case attribute.STRING:
+			v := a.Value.AsString()
+			value.StringValue = &v
+		default:
+			// Arrays are reported in their textual form, as the operator records none.
+			v := a.Value.Emit()
+			value.StringValue = &v
+		}
+		result = append(result, keyValue{Key: string(a.Key), Value: value})
+	}
+	return result
+}
+
+func toUnixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	var requests []exportTraceServiceRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, OTLPTracesPath, r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var request exportTraceServiceRequest
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPExporter(strings.TrimPrefix(server.URL, "http://"), true, time.Second)
+	provider := NewTracerProvider(sdktrace.NewSimpleSpanProcessor(exporter), Config{ServiceName: "spark-operator", SampleRatio: 1})
+	tracer := NewTracer(provider)
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "uid-1"},
+	}
+	runStart := time.Unix(1600000000, 0)
+	tracer.RecordSpan(app, runStart, SubmissionSpanName, runStart, runStart.Add(time.Second), nil, ExecutorsKey.Int(2))
+	tracer.RecordRun(app, runStart, runStart.Add(time.Minute), errors.New("driver failed"))
+
+	if !assert.Len(t, requests, 2) {
+		return
+	}
+	submission := requests[0].ResourceSpans[0].ScopeSpans[0].Spans[0]
+	run := requests[1].ResourceSpans[0].ScopeSpans[0].Spans[0]
+	runSpanContext := RunSpanContext(app, runStart)
+
+	assert.Equal(t, []keyValue{{Key: "service.name", Value: anyValue{StringValue: stringptr("spark-operator")}}},
+		requests[0].ResourceSpans[0].Resource.Attributes)
+	assert.Equal(t, tracerName, requests[0].ResourceSpans[0].ScopeSpans[0].Scope.Name)
+
+	assert.Equal(t, RunSpanName, run.Name)
+	assert.Equal(t, runSpanContext.TraceID().String(), run.TraceID)
+	assert.Equal(t, runSpanContext.SpanID().String(), run.SpanID)
+	assert.Empty(t, run.ParentSpanID)
+	assert.Equal(t, uint64(runStart.UnixNano()), run.StartTimeUnixNano)
+	assert.Equal(t, uint64(runStart.Add(time.Minute).UnixNano()), run.EndTimeUnixNano)
+	assert.Equal(t, status{Message: "driver failed", Code: otlpStatusCodeError}, run.Status)
+	assert.Len(t, run.Events, 1)
+
+	assert.Equal(t, SubmissionSpanName, submission.Name)
+	assert.Equal(t, runSpanContext.TraceID().String(), submission.TraceID)
+	assert.Equal(t, runSpanContext.SpanID().String(), submission.ParentSpanID)
+	assert.Equal(t, otlpStatusCodeUnset, submission.Status.Code)
+	assert.Contains(t, submission.Attributes, keyValue{Key: string(ExecutorsKey), Value: anyValue{IntValue: int64ptr(2)}})
+}
+
+func TestOTLPExporterEncoding(t *testing.T) {
+	body, err := json.Marshal(keyValue{Key: "count", Value: anyValue{IntValue: int64ptr(3)}})
+	assert.Nil(t, err)
+	// 64-bit integers are encoded as strings.
+	assert.Equal(t, `{"key":"count","value":{"intValue":"3"}}`, string(body))
+
+	body, err = json.Marshal(event{TimeUnixNano: 1600000000000000000, Name: "exception"})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"timeUnixNano":"1600000000000000000","name":"exception"}`, string(body))
+}
+
+func TestOTLPExporterFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPExporter(strings.TrimPrefix(server.URL, "http://"), true, time.Second)
+	err := exporter.ExportSpans(context.Background(), []*sdktrace.SpanSnapshot{{Name: "span"}})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), strconv.Itoa(http.StatusServiceUnavailable))
+	assert.Nil(t, exporter.ExportSpans(context.Background(), nil))
+}
+
+func stringptr(v string) *string {
+	return &v
+}
+
+func int64ptr(v int64) *int64 {
+	return &v
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing records the lifecycle of SparkApplications as OpenTelemetry traces.
+//
+// Each run of an application, which starts with an attempt to submit it, is recorded as one trace. The spans of a
+// trace are recorded once the phases they describe have completed, based on the transition times persisted in the
+// status of the application. The IDs of a trace and of its root span are derived from the UID of the application
+// and the start time of the run, so the spans of a run are part of the same trace even if they are recorded by
+// different instances of the operator.
+package tracing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+const (
+	tracerName = "github.com/GoogleCloudPlatform/spark-on-k8s-operator"
+	// RunSpanName is the name of the root span of the trace of a run.
+	RunSpanName = "SparkApplication"
+)
+
+// Names of the spans of the phases of a run.
+const (
+	ValidationSpanName       = "validation"
+	SubmissionSpanName       = "submission"
+	SubmitterSpanName        = "submitter"
+	DriverPendingSpanName    = "driver-pending"
+	DriverRunningSpanName    = "driver-running"
+	ExecutorRampUpSpanName   = "executor-ramp-up"
+	WebhookAdmissionSpanName = "webhook-admission"
+)
+
+// Attributes of the spans.
+const (
+	NamespaceKey    = attribute.Key("sparkoperator.namespace")
+	NameKey         = attribute.Key("sparkoperator.name")
+	UIDKey          = attribute.Key("sparkoperator.uid")
+	ModeKey         = attribute.Key("sparkoperator.mode")
+	StateKey        = attribute.Key("sparkoperator.state")
+	SubmissionIDKey = attribute.Key("sparkoperator.submission_id")
+	ExecutorsKey    = attribute.Key("sparkoperator.executors")
+	ResourceKey     = attribute.Key("sparkoperator.admission.resource")
+	OperationKey    = attribute.Key("sparkoperator.admission.operation")
+	OutcomeKey      = attribute.Key("sparkoperator.admission.outcome")
+)
+
+// otlpExportTimeout is how long exporting a batch of spans to an OTLP receiver may take.
+const otlpExportTimeout = 10 * time.Second
+
+// Config holds the settings of recording traces and exporting them over OTLP.
+type Config struct {
+	// Endpoint is the host and port of the OTLP/HTTP receiver.
+	Endpoint string
+	// Insecure disables TLS for the connection to the receiver.
+	Insecure bool
+	// ServiceName is the name of the service the traces are reported as coming from.
+	ServiceName string
+	// SampleRatio is the ratio of runs that are traced.
+	SampleRatio float64
+}
+
+// NewOTLPTracerProvider returns a TracerProvider exporting traces to the OTLP receiver of the given config.
+func NewOTLPTracerProvider(config Config) *sdktrace.TracerProvider {
+	exporter := NewOTLPExporter(config.Endpoint, config.Insecure, otlpExportTimeout)
+	return NewTracerProvider(sdktrace.NewBatchSpanProcessor(exporter), config)
+}
+
+// NewTracerProvider returns a TracerProvider passing the spans to the given processor. Only TracerProviders
+// returned by this function record the root spans of runs with the IDs their other spans refer to.
+func NewTracerProvider(processor sdktrace.SpanProcessor, config Config) *sdktrace.TracerProvider {
+	sampler := sdktrace.TraceIDRatioBased(config.SampleRatio)
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithIDGenerator(newRunIDGenerator()),
+		// The spans of a run are sampled based on the ID of its trace, so either all or none of them are.
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler, sdktrace.WithRemoteParentSampled(sampler))),
+		sdktrace.WithResource(resource.NewWithAttributes(attribute.String("service.name", config.ServiceName))),
+	)
+}
+
+// Tracer records the spans of the runs of SparkApplications.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns a Tracer recording spans through the given TracerProvider.
+func NewTracer(provider trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: provider.Tracer(tracerName)}
+}
+
+// RecordRun records the root span of the run of the application started at the given time.
+func (t *Tracer) RecordRun(app *v1beta2.SparkApplication, runStart time.Time, end time.Time, err error) {
+	ctx := context.WithValue(context.Background(), runIDsKey{}, getRunIDs(app, runStart))
+	_, span := t.tracer.Start(ctx, RunSpanName,
+		trace.WithNewRoot(),
+		trace.WithTimestamp(runStart),
+		trace.WithAttributes(getAppAttributes(app)...))
+	endSpan(span, end, err)
+}
+
+// RecordSpan records a span of a phase of the run of the application started at the given time.
+func (t *Tracer) RecordSpan(
+	app *v1beta2.SparkApplication,
+	runStart time.Time,
+	name string,
+	start time.Time,
+	end time.Time,
+	err error,
+	attributes ...attribute.KeyValue) {
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), RunSpanContext(app, runStart))
+	_, span := t.tracer.Start(ctx, name,
+		trace.WithTimestamp(start),
+		trace.WithAttributes(append(getAppAttributes(app), attributes...)...))
+	endSpan(span, end, err)
+}
+
+// RecordAdmission records the span of an admission request served by the webhook, linked to the current run of
+// the given application if any.
+func (t *Tracer) RecordAdmission(
+	app *v1beta2.SparkApplication,
+	start time.Time,
+	end time.Time,
+	attributes ...attribute.KeyValue) {
+	options := []trace.SpanOption{
+		trace.WithNewRoot(),
+		trace.WithTimestamp(start),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attributes...),
+	}
+	if app != nil && !app.Status.LastSubmissionAttemptTime.IsZero() {
+		options = append(options,
+			trace.WithLinks(trace.Link{SpanContext: RunSpanContext(app, app.Status.LastSubmissionAttemptTime.Time)}),
+			trace.WithAttributes(getAppAttributes(app)...))
+	}
+	_, span := t.tracer.Start(context.Background(), WebhookAdmissionSpanName, options...)
+	span.End(trace.WithTimestamp(end))
+}
+
+// RunSpanContext returns the context of the root span of the run of the application started at the given time.
+func RunSpanContext(app *v1beta2.SparkApplication, runStart time.Time) trace.SpanContext {
+	ids := getRunIDs(app, runStart)
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    ids.traceID,
+		SpanID:     ids.spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+}
+
+func endSpan(span trace.Span, end time.Time, err error) {
+	if err != nil {
+		span.RecordError(err, trace.WithTimestamp(end))
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+}
+
+func getAppAttributes(app *v1beta2.SparkApplication) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		NamespaceKey.String(app.Namespace),
+		NameKey.String(app.Name),
+		UIDKey.String(string(app.UID)),
+	}
+}
+
+type runIDs struct {
+	traceID trace.TraceID
+	spanID  trace.SpanID
+}
+
+type runIDsKey struct{}
+
+// getRunIDs derives the IDs of the trace and root span of a run from the UID of the application and the start
+// time of the run. The start time is truncated to seconds as it is persisted in the status with that precision.
+func getRunIDs(app *v1beta2.SparkApplication, runStart time.Time) runIDs {
+	h := sha256.New()
+	h.Write([]byte(app.UID))
+	binary.Write(h, binary.BigEndian, runStart.Unix())
+	sum := h.Sum(nil)
+
+	var ids runIDs
+	copy(ids.traceID[:], sum[:16])
+	copy(ids.spanID[:], sum[16:24])
+	return ids
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func newTestTracer(sampleRatio float64) (*Tracer, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewTracerProvider(sdktrace.NewSimpleSpanProcessor(exporter), Config{SampleRatio: sampleRatio})
+	return NewTracer(provider), exporter
+}
+
+func TestRecordRun(t *testing.T) {
+	tracer, exporter := newTestTracer(1)
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "uid-1"},
+	}
+	runStart := time.Now()
+
+	tracer.RecordSpan(app, runStart, SubmissionSpanName, runStart, runStart.Add(time.Second), nil)
+	// The start time of a run is read back from the status with the precision of seconds.
+	tracer.RecordSpan(app, runStart.Truncate(time.Second), DriverPendingSpanName, runStart.Add(time.Second),
+		runStart.Add(3*time.Second), nil)
+	tracer.RecordRun(app, runStart, runStart.Add(time.Minute), errors.New("driver failed"))
+
+	spans := exporter.GetSpans()
+	if !assert.Len(t, spans, 3) {
+		return
+	}
+	submission, driverPending, run := spans[0], spans[1], spans[2]
+	assert.Equal(t, RunSpanName, run.Name)
+	assert.False(t, run.Parent.IsValid())
+	assert.Equal(t, codes.Error, run.StatusCode)
+	assert.Equal(t, runStart, run.StartTime)
+	assert.Equal(t, runStart.Add(time.Minute), run.EndTime)
+	assert.Equal(t, RunSpanContext(app, runStart).TraceID(), run.SpanContext.TraceID())
+	assert.Equal(t, RunSpanContext(app, runStart).SpanID(), run.SpanContext.SpanID())
+
+	for _, span := range []*sdktrace.SpanSnapshot{submission, driverPending} {
+		assert.Equal(t, run.SpanContext.TraceID(), span.SpanContext.TraceID())
+		assert.Equal(t, run.SpanContext.SpanID(), span.Parent.SpanID())
+		assert.Equal(t, codes.Unset, span.StatusCode)
+	}
+	assert.Equal(t, SubmissionSpanName, submission.Name)
+	assert.Equal(t, DriverPendingSpanName, driverPending.Name)
+	assert.Equal(t, 2*time.Second, driverPending.EndTime.Sub(driverPending.StartTime))
+
+	// Another run of the application is recorded as another trace.
+	assert.NotEqual(t, run.SpanContext.TraceID(), RunSpanContext(app, runStart.Add(time.Hour)).TraceID())
+}
+
+func TestRecordAdmission(t *testing.T) {
+	tracer, exporter := newTestTracer(1)
+	runStart := time.Now()
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "uid-1"},
+		Status: v1beta2.SparkApplicationStatus{
+			LastSubmissionAttemptTime: metav1.NewTime(runStart),
+		},
+	}
+
+	tracer.RecordAdmission(app, runStart, runStart.Add(time.Millisecond), ResourceKey.String("pods"))
+	tracer.RecordAdmission(nil, runStart, runStart.Add(time.Millisecond), ResourceKey.String("pods"))
+
+	spans := exporter.GetSpans()
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+	assert.Equal(t, WebhookAdmissionSpanName, spans[0].Name)
+	if assert.Len(t, spans[0].Links, 1) {
+		assert.Equal(t, RunSpanContext(app, runStart).SpanID(), spans[0].Links[0].SpanContext.SpanID())
+	}
+	assert.NotEqual(t, RunSpanContext(app, runStart).TraceID(), spans[0].SpanContext.TraceID())
+	assert.Empty(t, spans[1].Links)
+}
+
+func TestRecordRunNotSampled(t *testing.T) {
+	tracer, exporter := newTestTracer(0)
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "uid-1"},
+	}
+	runStart := time.Now()
+
+	tracer.RecordSpan(app, runStart, SubmissionSpanName, runStart, runStart.Add(time.Second), nil)
+	tracer.RecordRun(app, runStart, runStart.Add(time.Minute), nil)
+	assert.Empty(t, exporter.GetSpans())
+}
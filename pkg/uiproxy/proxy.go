@@ -0,0 +1,152 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package uiproxy implements an HTTP reverse proxy that serves the UIs of running Spark drivers under a single endpoint.
+package uiproxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	crdinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
+	crdlisters "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/listers/sparkoperator.k8s.io/v1beta2"
+)
+
+// Proxy routes requests for /{namespace}/{name}/ to the UI of the driver of the SparkApplication with the
+// given namespace and name.
+type Proxy struct {
+	server    *http.Server
+	lister    crdlisters.SparkApplicationLister
+	transport http.RoundTripper
+}
+
+// New creates a new Proxy listening on the given port.
+func New(crdInformerFactory crdinformers.SharedInformerFactory, port string) *Proxy {
+	proxy := newProxy(crdInformerFactory.Sparkoperator().V1beta2().SparkApplications().Lister())
+	proxy.server = &http.Server{
+		Addr:    fmt.Sprintf(":%s", port),
+		Handler: proxy,
+	}
+	return proxy
+}
+
+func newProxy(lister crdlisters.SparkApplicationLister) *Proxy {
+	return &Proxy{lister: lister}
+}
+
+// GetProxyBase returns the path prefix under which the UI of the given SparkApplication is served by the proxy.
+// It is meant to be used as the value of spark.ui.proxyBase so that links in the UI point to the proxy.
+func GetProxyBase(namespace string, name string) string {
+	return fmt.Sprintf("/%s/%s", namespace, name)
+}
+
+// Start starts serving requests in the background.
+func (p *Proxy) Start() {
+	go func() {
+		glog.Infof("Starting the Spark UI proxy server at %s", p.server.Addr)
+		if err := p.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			glog.Errorf("error while serving the Spark UI proxy: %v", err)
+		}
+	}()
+}
+
+// Stop stops the proxy server.
+func (p *Proxy) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	glog.Info("Stopping the Spark UI proxy server")
+	return p.server.Shutdown(ctx)
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	namespace, name := parts[0], parts[1]
+	proxyBase := GetProxyBase(namespace, name)
+	if len(parts) == 2 {
+		// Make sure relative links in the UI are resolved against the proxy base.
+		http.Redirect(w, r, proxyBase+"/", http.StatusFound)
+		return
+	}
+
+	app, err := p.lister.SparkApplications(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			http.Error(w, fmt.Sprintf("SparkApplication %s/%s not found", namespace, name), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if app.Status.DriverInfo.WebUIAddress == "" {
+		http.Error(w, fmt.Sprintf("UI of SparkApplication %s/%s is not available", namespace, name), http.StatusServiceUnavailable)
+		return
+	}
+
+	target := &url.URL{Scheme: "http", Host: app.Status.DriverInfo.WebUIAddress}
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.URL.Path = "/" + parts[2]
+			req.URL.RawPath = ""
+			req.Host = target.Host
+			req.Header.Set("X-Forwarded-Prefix", proxyBase)
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			if location := resp.Header.Get("Location"); location != "" {
+				resp.Header.Set("Location", rewriteLocation(location, target, proxyBase))
+			}
+			return nil
+		},
+		Transport: p.transport,
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// rewriteLocation rewrites a redirect location pointing to the driver UI so that it points to the proxy instead.
+func rewriteLocation(location string, target *url.URL, proxyBase string) string {
+	locationURL, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+	if locationURL.IsAbs() {
+		if locationURL.Host != target.Host {
+			// Redirects to other hosts are passed through as is.
+			return location
+		}
+		locationURL.Scheme = ""
+		locationURL.Host = ""
+	}
+	if !strings.HasPrefix(locationURL.Path, "/") {
+		return locationURL.String()
+	}
+	if locationURL.Path != proxyBase && !strings.HasPrefix(locationURL.Path, proxyBase+"/") {
+		locationURL.Path = proxyBase + locationURL.Path
+	}
+	return locationURL.String()
+}
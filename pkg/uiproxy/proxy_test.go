@@ -0,0 +1,117 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package uiproxy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
+	crdinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
+)
+
+func newTestProxy(apps ...*v1beta2.SparkApplication) *Proxy {
+	informerFactory := crdinformers.NewSharedInformerFactory(crdclientfake.NewSimpleClientset(), 0)
+	informer := informerFactory.Sparkoperator().V1beta2().SparkApplications()
+	for _, app := range apps {
+		informer.Informer().GetIndexer().Add(app)
+	}
+	return newProxy(informer.Lister())
+}
+
+func TestProxyServeHTTP(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/jobs/", http.StatusFound)
+		case "/proxied":
+			http.Redirect(w, r, fmt.Sprintf("http://%s/default/foo/stages/", r.Host), http.StatusFound)
+		default:
+			fmt.Fprintf(w, "%s?%s %s", r.URL.Path, r.URL.RawQuery, r.Header.Get("X-Forwarded-Prefix"))
+		}
+	}))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+
+	running := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status: v1beta2.SparkApplicationStatus{
+			DriverInfo: v1beta2.DriverInfo{WebUIAddress: backendURL.Host},
+		},
+	}
+	pending := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "default"},
+	}
+	proxy := httptest.NewServer(newTestProxy(running, pending))
+	defer proxy.Close()
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	type testcase struct {
+		path             string
+		expectedStatus   int
+		expectedBody     string
+		expectedLocation string
+	}
+	testcases := []testcase{
+		{path: "/default/foo/jobs/job/?id=1", expectedStatus: http.StatusOK, expectedBody: "/jobs/job/?id=1 /default/foo"},
+		{path: "/default/foo", expectedStatus: http.StatusFound, expectedLocation: "/default/foo/"},
+		{path: "/default/foo/", expectedStatus: http.StatusFound, expectedLocation: "/default/foo/jobs/"},
+		{path: "/default/foo/proxied", expectedStatus: http.StatusFound, expectedLocation: "/default/foo/stages/"},
+		{path: "/default/bar/", expectedStatus: http.StatusServiceUnavailable},
+		{path: "/default/baz/", expectedStatus: http.StatusNotFound},
+		{path: "/default", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, test := range testcases {
+		resp, err := client.Get(proxy.URL + test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assert.Equal(t, test.expectedStatus, resp.StatusCode, test.path)
+		if test.expectedBody != "" {
+			assert.Equal(t, test.expectedBody, string(body), test.path)
+		}
+		if test.expectedLocation != "" {
+			assert.Equal(t, test.expectedLocation, resp.Header.Get("Location"), test.path)
+		}
+	}
+}
+
+func TestRewriteLocation(t *testing.T) {
+	target := &url.URL{Scheme: "http", Host: "10.0.0.1:4040"}
+	assert.Equal(t, "/default/foo/jobs/", rewriteLocation("/jobs/", target, "/default/foo"))
+	assert.Equal(t, "/default/foo/jobs/", rewriteLocation("/default/foo/jobs/", target, "/default/foo"))
+	assert.Equal(t, "/default/foo/jobs/?id=1", rewriteLocation("http://10.0.0.1:4040/jobs/?id=1", target, "/default/foo"))
+	assert.Equal(t, "https://example.com/login", rewriteLocation("https://example.com/login", target, "/default/foo"))
+	assert.Equal(t, "jobs/", rewriteLocation("jobs/", target, "/default/foo"))
+}
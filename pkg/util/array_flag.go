@@ -16,7 +16,10 @@ limitations under the License.
 
 package util
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type ArrayFlags []string
 
@@ -28,3 +31,16 @@ func (a *ArrayFlags) Set(value string) error {
 	*a = append(*a, value)
 	return nil
 }
+
+// KeyValuePairs parses the values as key=value pairs.
+func (a *ArrayFlags) KeyValuePairs() (map[string]string, error) {
+	pairs := make(map[string]string)
+	for _, value := range *a {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid key=value pair %q", value)
+		}
+		pairs[parts[0]] = parts[1]
+	}
+	return pairs, nil
+}
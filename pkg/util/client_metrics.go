@@ -0,0 +1,178 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	unknownResource = "unknown"
+	// requestErrorCode is the code of requests that failed without a response, e.g., on connection errors.
+	requestErrorCode = "<error>"
+)
+
+// activeKubeClientMetrics holds the *kubeClientMetrics requests are recorded in once metrics are initialized.
+var activeKubeClientMetrics atomic.Value
+
+// kubeClientMetrics exports the requests made by the Kubernetes clients of the operator.
+type kubeClientMetrics struct {
+	requestLatency *prometheus.HistogramVec
+	requestResult  *prometheus.CounterVec
+}
+
+func newKubeClientMetrics(prefix string) *kubeClientMetrics {
+	return &kubeClientMetrics{
+		requestLatency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    CreateValidMetricNameLabel(prefix, "kube_api_request_duration_seconds"),
+				Help:    "Latency of the requests to the Kubernetes API server by verb and resource",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"verb", "resource"},
+		),
+		requestResult: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: CreateValidMetricNameLabel(prefix, "kube_api_request_count"),
+				Help: "Number of requests to the Kubernetes API server by verb, resource and status code",
+			},
+			[]string{"verb", "resource", "code"},
+		),
+	}
+}
+
+func (m *kubeClientMetrics) register() {
+	RegisterMetric(m.requestLatency)
+	RegisterMetric(m.requestResult)
+	activeKubeClientMetrics.Store(m)
+}
+
+func (m *kubeClientMetrics) observe(req *http.Request, resp *http.Response, err error, latency time.Duration) {
+	path := parseRequestPath(req.URL.Path)
+	verb := getRequestVerb(req, path)
+	m.requestLatency.WithLabelValues(verb, path.resource).Observe(latency.Seconds())
+	code := requestErrorCode
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	m.requestResult.WithLabelValues(verb, path.resource, code).Inc()
+}
+
+// InstrumentKubeClientConfig makes the clients built from the given config record their requests in the Kubernetes
+// API request metrics once metrics are initialized. The metrics hooks of client-go are not used as they do not
+// expose the path of requests along with their status code.
+func InstrumentKubeClientConfig(config *rest.Config) {
+	wrapTransport := config.WrapTransport
+	config.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		if wrapTransport != nil {
+			rt = wrapTransport(rt)
+		}
+		return &kubeClientMetricsRoundTripper{delegate: rt}
+	}
+}
+
+type kubeClientMetricsRoundTripper struct {
+	delegate http.RoundTripper
+}
+
+func (t *kubeClientMetricsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.delegate.RoundTrip(req)
+	if m, ok := activeKubeClientMetrics.Load().(*kubeClientMetrics); ok {
+		m.observe(req, resp, err, time.Since(start))
+	}
+	return resp, err
+}
+
+// requestPath is what the metrics are labelled by of the path of a request to the Kubernetes API server.
+type requestPath struct {
+	// resource is the resource, along with the subresource if any, e.g., "pods" or "sparkapplications/status".
+	resource string
+	// named tells if the path is of a single object rather than of a collection.
+	named bool
+	// watch tells if the path is of the deprecated watch endpoints, e.g., "/api/v1/watch/pods".
+	watch bool
+}
+
+// parseRequestPath parses the given path of a request to the Kubernetes API server, e.g., the resource is "pods"
+// for "/api/v1/namespaces/default/pods/foo" and "sparkapplications/status" for
+// "/apis/sparkoperator.k8s.io/v1beta2/namespaces/default/sparkapplications/foo/status".
+func parseRequestPath(path string) requestPath {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var i int
+	switch {
+	case len(segments) >= 2 && segments[0] == "api":
+		i = 2
+	case len(segments) >= 3 && segments[0] == "apis":
+		i = 3
+	default:
+		return requestPath{resource: unknownResource}
+	}
+	var result requestPath
+	if i < len(segments) && segments[i] == "watch" {
+		result.watch = true
+		i++
+	}
+	if i+2 < len(segments) && segments[i] == "namespaces" {
+		i += 2
+	}
+	if i >= len(segments) {
+		return requestPath{resource: unknownResource}
+	}
+
+	result.resource = segments[i]
+	result.named = i+1 < len(segments)
+	if i+2 < len(segments) {
+		result.resource += "/" + segments[i+2]
+	}
+	return result
+}
+
+// getRequestVerb returns the Kubernetes API verb of the request with the given parsed path, e.g., "list" for a GET
+// of a collection and "watch" for a GET with the watch parameter.
+func getRequestVerb(req *http.Request, path requestPath) string {
+	switch req.Method {
+	case http.MethodGet:
+		if watch := req.URL.Query().Get("watch"); path.watch || watch == "true" || watch == "1" {
+			return "watch"
+		}
+		if path.named {
+			return "get"
+		}
+		return "list"
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		if path.named {
+			return "delete"
+		}
+		return "deletecollection"
+	default:
+		return strings.ToLower(req.Method)
+	}
+}
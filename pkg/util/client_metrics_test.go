@@ -0,0 +1,117 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	prometheusmodel "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/rest"
+)
+
+func TestParseRequestPath(t *testing.T) {
+	testcases := []struct {
+		path     string
+		expected requestPath
+	}{
+		{"/api/v1/namespaces/default/pods", requestPath{resource: "pods"}},
+		{"/api/v1/namespaces/default/pods/foo", requestPath{resource: "pods", named: true}},
+		{"/api/v1/namespaces/default/pods/foo/log", requestPath{resource: "pods/log", named: true}},
+		{"/api/v1/namespaces", requestPath{resource: "namespaces"}},
+		{"/api/v1/namespaces/default", requestPath{resource: "namespaces", named: true}},
+		{"/api/v1/nodes/foo", requestPath{resource: "nodes", named: true}},
+		{"/api/v1/watch/namespaces/default/services", requestPath{resource: "services", watch: true}},
+		{"/apis/sparkoperator.k8s.io/v1beta2/sparkapplications", requestPath{resource: "sparkapplications"}},
+		{"/apis/sparkoperator.k8s.io/v1beta2/namespaces/default/sparkapplications/foo/status", requestPath{resource: "sparkapplications/status", named: true}},
+		{"/apis/batch/v1/namespaces/default/jobs/foo", requestPath{resource: "jobs", named: true}},
+		{"/version", requestPath{resource: unknownResource}},
+		{"/apis/batch/v1", requestPath{resource: unknownResource}},
+	}
+	for _, tc := range testcases {
+		assert.Equal(t, tc.expected, parseRequestPath(tc.path), tc.path)
+	}
+}
+
+func TestGetRequestVerb(t *testing.T) {
+	testcases := []struct {
+		method   string
+		url      string
+		expected string
+	}{
+		{"GET", "https://kubernetes/api/v1/namespaces/default/pods/foo", "get"},
+		{"GET", "https://kubernetes/api/v1/namespaces/default/pods", "list"},
+		{"GET", "https://kubernetes/api/v1/namespaces/default/pods?watch=true&resourceVersion=1", "watch"},
+		{"GET", "https://kubernetes/api/v1/watch/namespaces/default/pods", "watch"},
+		{"POST", "https://kubernetes/api/v1/namespaces/default/pods", "create"},
+		{"PUT", "https://kubernetes/apis/sparkoperator.k8s.io/v1beta2/namespaces/default/sparkapplications/foo/status", "update"},
+		{"PATCH", "https://kubernetes/api/v1/namespaces/default/pods/foo", "patch"},
+		{"DELETE", "https://kubernetes/api/v1/namespaces/default/pods/foo", "delete"},
+		{"DELETE", "https://kubernetes/api/v1/namespaces/default/pods", "deletecollection"},
+		{"OPTIONS", "https://kubernetes/api/v1/namespaces/default/pods", "options"},
+	}
+	for _, tc := range testcases {
+		req, _ := http.NewRequest(tc.method, tc.url, nil)
+		assert.Equal(t, tc.expected, getRequestVerb(req, parseRequestPath(req.URL.Path)), tc.method+" "+tc.url)
+	}
+}
+
+type fakeRoundTripper struct {
+	statusCode int
+	err        error
+}
+
+func (f fakeRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &http.Response{StatusCode: f.statusCode}, nil
+}
+
+func TestKubeClientMetrics(t *testing.T) {
+	m := newKubeClientMetrics("")
+	activeKubeClientMetrics.Store(m)
+
+	config := &rest.Config{}
+	InstrumentKubeClientConfig(config)
+	ok := config.WrapTransport(fakeRoundTripper{statusCode: 200})
+	failing := config.WrapTransport(fakeRoundTripper{err: errors.New("connection refused")})
+
+	req, _ := http.NewRequest("GET", "https://kubernetes/api/v1/namespaces/default/pods/foo", nil)
+	ok.RoundTrip(req)
+	ok.RoundTrip(req)
+	failing.RoundTrip(req)
+	req, _ = http.NewRequest("PUT", "https://kubernetes/apis/sparkoperator.k8s.io/v1beta2/namespaces/default/sparkapplications/foo/status", nil)
+	ok.RoundTrip(req)
+
+	pb := &prometheusmodel.Metric{}
+	m.requestLatency.WithLabelValues("get", "pods").(prometheus.Metric).Write(pb)
+	assert.Equal(t, uint64(3), pb.GetHistogram().GetSampleCount())
+
+	pb = &prometheusmodel.Metric{}
+	m.requestResult.WithLabelValues("get", "pods", "200").Write(pb)
+	assert.Equal(t, float64(2), pb.GetCounter().GetValue())
+	pb = &prometheusmodel.Metric{}
+	m.requestResult.WithLabelValues("get", "pods", requestErrorCode).Write(pb)
+	assert.Equal(t, float64(1), pb.GetCounter().GetValue())
+	pb = &prometheusmodel.Metric{}
+	m.requestResult.WithLabelValues("update", "sparkapplications/status", "200").Write(pb)
+	assert.Equal(t, float64(1), pb.GetCounter().GetValue())
+}
@@ -24,6 +24,12 @@ import (
 
 var DefaultJobStartLatencyBuckets = []float64{30, 60, 90, 120, 150, 180, 210, 240, 270, 300}
 
+var DefaultExecutionTimeBuckets = []float64{60, 300, 600, 1200, 1800, 3600, 7200, 14400, 28800, 86400}
+
+var DefaultSubmissionJobDurationBuckets = []float64{5, 10, 15, 20, 30, 45, 60, 90, 120, 300}
+
+var DefaultDriverPendingDurationBuckets = []float64{5, 10, 30, 60, 120, 180, 300, 600, 1200, 1800}
+
 type HistogramBuckets []float64
 
 func (hb *HistogramBuckets) String() string {
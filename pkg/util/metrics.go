@@ -54,6 +54,12 @@ type MetricConfig struct {
 	MetricsPrefix                 string
 	MetricsLabels                 []string
 	MetricsJobStartLatencyBuckets []float64
+	// MetricsExecutionTimeBuckets are the buckets of the execution time histograms of successful and failed apps.
+	MetricsExecutionTimeBuckets []float64
+	// MetricsSubmissionJobDurationBuckets are the buckets of the histogram of the duration of submission Jobs.
+	MetricsSubmissionJobDurationBuckets []float64
+	// MetricsDriverPendingDurationBuckets are the buckets of the histogram of the time drivers take to run.
+	MetricsDriverPendingDurationBuckets []float64
 }
 
 // A variant of Prometheus Gauge that only holds non-negative values.
@@ -143,6 +149,8 @@ func InitializeMetrics(metricsConfig *MetricConfig) {
 
 	workQueueMetrics := WorkQueueMetrics{prefix: metricsConfig.MetricsPrefix}
 	workqueue.SetProvider(&workQueueMetrics)
+
+	newKubeClientMetrics(metricsConfig.MetricsPrefix).register()
 }
 
 // Depth Metric for the kubernetes workqueue.
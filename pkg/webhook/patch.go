@@ -463,14 +463,16 @@ func addDNSConfig(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperati
 func addSchedulerName(pod *corev1.Pod, app *v1beta2.SparkApplication) *patchOperation {
 	var schedulerName *string
 
-	//NOTE: Preferred to use `BatchScheduler` if application spec has it configured.
-	if app.Spec.BatchScheduler != nil {
-		schedulerName = app.Spec.BatchScheduler
-	} else if util.IsDriverPod(pod) {
+	//NOTE: The scheduler name of the driver or executor takes precedence over `BatchScheduler`, so that the
+	//scheduler running a batch scheduler plugin can have another name, e.g. a scheduler profile.
+	if util.IsDriverPod(pod) {
 		schedulerName = app.Spec.Driver.SchedulerName
 	} else if util.IsExecutorPod(pod) {
 		schedulerName = app.Spec.Executor.SchedulerName
 	}
+	if (schedulerName == nil || *schedulerName == "") && app.Spec.BatchScheduler != nil {
+		schedulerName = app.Spec.BatchScheduler
+	}
 	if schedulerName == nil || *schedulerName == "" {
 		return nil
 	}
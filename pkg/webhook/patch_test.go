@@ -748,6 +748,20 @@ func TestPatchSparkPod_SchedulerName(t *testing.T) {
 	}
 	//Executor scheduler name should remain the same as before when not specified in SparkApplicationSpec
 	assert.Equal(t, defaultScheduler, modifiedExecutorPod.Spec.SchedulerName)
+
+	//The batch scheduler is used unless the scheduler name of the pod is specified.
+	batchScheduler := "coscheduling"
+	app.Spec.BatchScheduler = &batchScheduler
+	modifiedDriverPod, err = getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, schedulerName, modifiedDriverPod.Spec.SchedulerName)
+	modifiedExecutorPod, err = getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, batchScheduler, modifiedExecutorPod.Spec.SchedulerName)
 }
 
 func TestPatchSparkPod_Sidecars(t *testing.T) {
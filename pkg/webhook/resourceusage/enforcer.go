@@ -2,94 +2,279 @@ package resourceusage
 
 import (
 	"fmt"
+	"sort"
+	"sync"
+
 	so "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
 	crdinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
 	"github.com/golang/glog"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/informers"
 	corev1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/tools/cache"
 )
 
+// EnforcementMode tells what happens to SparkApplications exceeding the resource quotas of their namespaces.
+type EnforcementMode string
+
+const (
+	// EnforcementModeReject denies the creation of SparkApplications exceeding the resource quotas.
+	EnforcementModeReject EnforcementMode = "reject"
+	// EnforcementModeQueue admits SparkApplications exceeding the resource quotas, which are then queued by the
+	// controller until they fit.
+	EnforcementModeQueue EnforcementMode = "queue"
+	// EnforcementModeWarn admits objects exceeding the resource quotas with a warning.
+	EnforcementModeWarn EnforcementMode = "warn"
+	// EnforcementModeDisabled does not check objects against the resource quotas at all.
+	EnforcementModeDisabled EnforcementMode = "disabled"
+)
+
+// IsValid returns whether the enforcement mode is one of the supported modes.
+func (m EnforcementMode) IsValid() bool {
+	switch m {
+	case EnforcementModeReject, EnforcementModeQueue, EnforcementModeWarn, EnforcementModeDisabled:
+		return true
+	}
+	return false
+}
+
+// QuotaViolation describes how an object exceeds a ResourceQuota of its namespace.
+type QuotaViolation struct {
+	// Quota is the name of the exceeded ResourceQuota.
+	Quota string
+	// Resource is the exceeded resource, e.g. requests.cpu, limits.memory or pods.
+	Resource corev1.ResourceName
+	// Requested is the quantity of the resource requested by the object.
+	Requested resource.Quantity
+	// Available is the quantity of the resource left in the ResourceQuota.
+	Available resource.Quantity
+	// Message describes the violation.
+	Message string
+}
+
+// Shortfall returns by how much the requested quantity of the resource exceeds the available quantity.
+func (v QuotaViolation) Shortfall() resource.Quantity {
+	shortfall := v.Requested.DeepCopy()
+	shortfall.Sub(v.Available)
+	return shortfall
+}
+
+// Admission is the outcome of checking an object against the resource quotas of its namespace on admission.
+type Admission struct {
+	// Mode is the enforcement mode of the namespace of the object.
+	Mode EnforcementMode
+	// Violation describes how the object exceeds the resource quotas, or is nil if it fits.
+	Violation *QuotaViolation
+}
+
+// Allowed returns whether the object is admitted, which is the case unless it exceeds the resource quotas of a
+// namespace in reject mode.
+func (a Admission) Allowed() bool {
+	return a.Violation == nil || a.Mode != EnforcementModeReject
+}
+
 type ResourceQuotaEnforcer struct {
 	watcher               ResourceUsageWatcher
 	resourceQuotaInformer corev1informers.ResourceQuotaInformer
+	namespaceInformer     corev1informers.NamespaceInformer
+	mode                  EnforcementMode
+	// reservationLock serializes checking queued SparkApplications and reserving their resources, so that each
+	// of them is checked against a usage including the ones released before it.
+	reservationLock *sync.Mutex
 }
 
-func NewResourceQuotaEnforcer(crdInformerFactory crdinformers.SharedInformerFactory, coreV1InformerFactory informers.SharedInformerFactory) ResourceQuotaEnforcer {
+func NewResourceQuotaEnforcer(crdInformerFactory crdinformers.SharedInformerFactory, coreV1InformerFactory informers.SharedInformerFactory, mode EnforcementMode) ResourceQuotaEnforcer {
 	resourceUsageWatcher := newResourceUsageWatcher(crdInformerFactory, coreV1InformerFactory)
 	informer := coreV1InformerFactory.Core().V1().ResourceQuotas()
 	informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{})
+	namespaceInformer := coreV1InformerFactory.Core().V1().Namespaces()
+	namespaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{})
 	return ResourceQuotaEnforcer{
 		watcher:               resourceUsageWatcher,
 		resourceQuotaInformer: informer,
+		namespaceInformer:     namespaceInformer,
+		mode:                  mode,
+		reservationLock:       &sync.Mutex{},
 	}
 }
 
+// Mode returns the enforcement mode of namespaces that do not select one.
+func (r ResourceQuotaEnforcer) Mode() EnforcementMode {
+	return r.mode
+}
+
+// ModeForNamespace returns the enforcement mode of the namespace, which is selected by the namespace label
+// sparkoperator.k8s.io/quota-enforcement and defaults to the mode of the enforcer.
+func (r ResourceQuotaEnforcer) ModeForNamespace(namespace string) EnforcementMode {
+	ns, err := r.namespaceInformer.Lister().Get(namespace)
+	if err != nil {
+		return r.mode
+	}
+	value, present := ns.Labels[config.QuotaEnforcementLabel]
+	if !present {
+		return r.mode
+	}
+	if mode := EnforcementMode(value); mode.IsValid() {
+		return mode
+	}
+	glog.Warningf("Ignoring invalid quota enforcement mode %q of namespace %s", value, namespace)
+	return r.mode
+}
+
 func (r ResourceQuotaEnforcer) WaitForCacheSync(stopCh <-chan struct{}) error {
 	if !cache.WaitForCacheSync(stopCh, func() bool {
-		return r.resourceQuotaInformer.Informer().HasSynced()
+		return r.resourceQuotaInformer.Informer().HasSynced() && r.namespaceInformer.Informer().HasSynced()
 	}) {
 		return fmt.Errorf("cache sync canceled")
 	}
 	return nil
 }
 
-func (r *ResourceQuotaEnforcer) admitResource(kind, namespace, name string, requestedResources ResourceList) (string, error) {
+func (r *ResourceQuotaEnforcer) admitResource(kind, namespace, name string, requestedResources ResourceList) (Admission, error) {
 	glog.V(2).Infof("Processing admission request for %s %s/%s, requesting: %s", kind, namespace, name, requestedResources)
+	admission := Admission{Mode: r.ModeForNamespace(namespace)}
+	if admission.Mode == EnforcementModeDisabled {
+		return admission, nil
+	}
+	violation, err := r.checkResource(kind, namespace, name, requestedResources, true)
+	if err != nil {
+		return Admission{}, err
+	}
+	admission.Violation = violation
+	return admission, nil
+}
+
+// checkResource returns how the requested resources of an object exceed the resource quotas of its namespace, or
+// nil if they fit. If onlyIncrease is true, the requested resources are only checked if they exceed
+// the current usage of the object, so that existing objects whose usage hasn't increased are always allowed.
+func (r *ResourceQuotaEnforcer) checkResource(kind, namespace, name string, requestedResources ResourceList, onlyIncrease bool) (*QuotaViolation, error) {
 	resourceQuotas, err := r.resourceQuotaInformer.Lister().ResourceQuotas(namespace).List(labels.Everything())
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if (requestedResources.cpu.IsZero() && requestedResources.memory.IsZero()) || len(resourceQuotas) == 0 {
-		return "", nil
+	if len(requestedResources.quantities) == 0 || len(resourceQuotas) == 0 {
+		return nil, nil
 	}
-
-	currentNamespaceUsage, currentApplicationUsage := r.watcher.GetCurrentResourceUsageWithApplication(namespace, kind, name)
+	sort.Slice(resourceQuotas, func(i, j int) bool { return resourceQuotas[i].Name < resourceQuotas[j].Name })
 
 	for _, quota := range resourceQuotas {
-		// Scope selectors not currently supported, ignore any ResourceQuota that does not match everything.
-		if quota.Spec.ScopeSelector != nil || len(quota.Spec.Scopes) > 0 {
+		matches, err := requestedResources.scope.matchesQuota(quota)
+		if err != nil {
+			glog.Warningf("Ignoring ResourceQuota %s/%s: %v", quota.Namespace, quota.Name, err)
 			continue
 		}
-
-		// If an existing application has increased its usage, check it against the quota again. If its usage hasn't increased, always allow it.
-		if requestedResources.cpu.Cmp(currentApplicationUsage.cpu) == 1 {
-			if cpuLimit, present := quota.Spec.Hard[corev1.ResourceCPU]; present {
-				availableCpu := cpuLimit
-				availableCpu.Sub(currentNamespaceUsage.cpu)
-				if requestedResources.cpu.Cmp(availableCpu) == 1 {
-					return fmt.Sprintf("%s %s/%s requests too many cores (%.3f cores requested, %.3f available).", kind, namespace, name, float64(requestedResources.cpu.MilliValue())/1000.0, float64(availableCpu.MilliValue())/1000.0), nil
-				}
-			}
+		if !matches {
+			continue
 		}
 
-		if requestedResources.memory.Cmp(currentApplicationUsage.memory) == 1 {
-			if memoryLimit, present := quota.Spec.Hard[corev1.ResourceMemory]; present {
-				availableMemory := memoryLimit
-				availableMemory.Sub(currentNamespaceUsage.memory)
-				if requestedResources.memory.Cmp(availableMemory) == 1 {
-					return fmt.Sprintf("%s %s/%s requests too much memory (%dMi requested, %dMi available).", kind, namespace, name, requestedResources.memory.Value()/(1<<20), availableMemory.Value()/(1<<20)), nil
-				}
+		currentNamespaceUsage, currentApplicationUsage := r.watcher.GetCurrentResourceUsageWithApplication(namespace, kind, name, quota)
+		for _, hardName := range sortedResourceNames(quota.Spec.Hard) {
+			resourceName, tracked := quotaResourceName(hardName)
+			if !tracked {
+				continue
+			}
+			requested := requestedResources.get(resourceName)
+			if requested.IsZero() {
+				continue
+			}
+			// If an existing application has increased its usage, check it against the quota again. If its usage hasn't increased, always allow it.
+			if onlyIncrease && requested.Cmp(currentApplicationUsage.get(resourceName)) != 1 {
+				continue
+			}
+			available := quota.Spec.Hard[hardName].DeepCopy()
+			available.Sub(currentNamespaceUsage.get(resourceName))
+			if requested.Cmp(available) == 1 {
+				return &QuotaViolation{
+					Quota:     quota.Name,
+					Resource:  resourceName,
+					Requested: requested,
+					Available: available,
+					Message:   exceededQuotaMessage(kind, namespace, name, quota.Name, resourceName, requested, available),
+				}, nil
 			}
 		}
 	}
-	return "", nil
+	return nil, nil
 }
 
-func (r *ResourceQuotaEnforcer) AdmitSparkApplication(app so.SparkApplication) (string, error) {
+func exceededQuotaMessage(kind, namespace, name, quotaName string, resourceName corev1.ResourceName, requested, available resource.Quantity) string {
+	switch resourceName {
+	case corev1.ResourceRequestsCPU:
+		return fmt.Sprintf("%s %s/%s requests too many cores (%.3f cores requested, %.3f available).", kind, namespace, name, float64(requested.MilliValue())/1000.0, float64(available.MilliValue())/1000.0)
+	case corev1.ResourceRequestsMemory:
+		return fmt.Sprintf("%s %s/%s requests too much memory (%dMi requested, %dMi available).", kind, namespace, name, requested.Value()/(1<<20), available.Value()/(1<<20))
+	case corev1.ResourcePods:
+		return fmt.Sprintf("%s %s/%s requests too many pods (%d requested, %d available).", kind, namespace, name, requested.Value(), available.Value())
+	}
+	return fmt.Sprintf("%s %s/%s exceeds %s of ResourceQuota %s (%s requested, %s available).", kind, namespace, name, resourceName, quotaName, requested.String(), available.String())
+}
+
+func sortedResourceNames(resources corev1.ResourceList) []corev1.ResourceName {
+	return ResourceList{quantities: resources}.names()
+}
+
+func (r *ResourceQuotaEnforcer) AdmitSparkApplication(app so.SparkApplication) (Admission, error) {
 	resourceUsage, err := sparkApplicationResourceUsage(app)
+	if err != nil {
+		return Admission{}, err
+	}
+	admission, err := r.admitResource(KindSparkApplication, app.ObjectMeta.Namespace, app.ObjectMeta.Name, resourceUsage)
+	if err == nil && admission.Violation != nil && admission.Mode == EnforcementModeQueue {
+		// The application is admitted and queued by the controller until it fits.
+		glog.V(2).Infof("Admitting SparkApplication %s/%s to be queued: %s", app.ObjectMeta.Namespace, app.ObjectMeta.Name, admission.Violation.Message)
+	}
+	return admission, err
+}
+
+// CheckSparkApplication returns the reason why the application does not fit into what is left of the resource
+// quotas of its namespace, or an empty string if it fits or its namespace is not in queue mode. Unlike admission,
+// the resources requested by the application are checked even if the application already exists.
+func (r *ResourceQuotaEnforcer) CheckSparkApplication(app so.SparkApplication) (string, error) {
+	namespace := namespaceOrDefault(app.ObjectMeta)
+	if r.ModeForNamespace(namespace) != EnforcementModeQueue {
+		return "", nil
+	}
+	resourceUsage, err := resourceUsage(app.Spec)
 	if err != nil {
 		return "", err
 	}
-	return r.admitResource(KindSparkApplication, app.ObjectMeta.Namespace, app.ObjectMeta.Name, resourceUsage)
+	violation, err := r.checkResource(KindSparkApplication, namespace, app.ObjectMeta.Name, resourceUsage, false)
+	if err != nil || violation == nil {
+		return "", err
+	}
+	return violation.Message, nil
 }
 
-func (r *ResourceQuotaEnforcer) AdmitScheduledSparkApplication(app so.ScheduledSparkApplication) (string, error) {
-	resourceUsage, err := scheduledSparkApplicationResourceUsage(app)
+// ReserveSparkApplication checks the queued application like CheckSparkApplication, and if it fits, reserves the
+// resources it requests once submitted until it is seen to have left the queue. Otherwise the applications
+// dequeued right after it would be checked against a usage not including it yet and could exceed the quotas.
+func (r *ResourceQuotaEnforcer) ReserveSparkApplication(app so.SparkApplication) (string, error) {
+	r.reservationLock.Lock()
+	defer r.reservationLock.Unlock()
+	reason, err := r.CheckSparkApplication(app)
+	if err != nil || reason != "" {
+		return reason, err
+	}
+	resourceUsage, err := resourceUsage(app.Spec)
 	if err != nil {
 		return "", err
 	}
-	return r.admitResource(KindScheduledSparkApplication, app.ObjectMeta.Namespace, app.ObjectMeta.Name, resourceUsage)
+	r.watcher.reserveSparkApplication(namespaceOrDefault(app.ObjectMeta), app.ObjectMeta.Name, resourceUsage)
+	return "", nil
+}
+
+func (r *ResourceQuotaEnforcer) AdmitScheduledSparkApplication(app so.ScheduledSparkApplication) (Admission, error) {
+	resourceUsage, err := scheduledSparkApplicationResourceUsage(app)
+	if err != nil {
+		return Admission{}, err
+	}
+	admission, err := r.admitResource(KindScheduledSparkApplication, app.ObjectMeta.Namespace, app.ObjectMeta.Name, resourceUsage)
+	if admission.Mode == EnforcementModeQueue {
+		// Only SparkApplications are queued.
+		admission.Mode = EnforcementModeReject
+	}
+	return admission, err
 }
@@ -0,0 +1,401 @@
+package resourceusage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+
+	so "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
+	crdinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func newTestEnforcer(mode EnforcementMode, quotas ...*corev1.ResourceQuota) ResourceQuotaEnforcer {
+	enforcer := NewResourceQuotaEnforcer(
+		crdinformers.NewSharedInformerFactory(crdclientfake.NewSimpleClientset(), 0),
+		informers.NewSharedInformerFactory(kubeclientfake.NewSimpleClientset(), 0),
+		mode)
+	for _, quota := range quotas {
+		enforcer.resourceQuotaInformer.Informer().GetIndexer().Add(quota)
+	}
+	return enforcer
+}
+
+func newTestQuota(name string, hard corev1.ResourceList, scopeSelector *corev1.ScopeSelector, scopes ...corev1.ResourceQuotaScope) *corev1.ResourceQuota {
+	return &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1.ResourceQuotaSpec{Hard: hard, ScopeSelector: scopeSelector, Scopes: scopes},
+	}
+}
+
+func newTestPod(name, priorityClassName, cpu string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: corev1.PodSpec{
+			PriorityClassName: priorityClassName,
+			Containers: []corev1.Container{{
+				Name: "main",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+				},
+			}},
+		},
+	}
+}
+
+// newTestApp returns an application of a driver and executors of 1 core and 1408Mi of memory each, submitted by a
+// submission Job pod requesting 512m cores and 500Mi of memory.
+func newTestApp(priorityClassName string, executors int32, gpu *so.GPUSpec) so.SparkApplication {
+	app := so.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: so.SparkApplicationSpec{
+			Type: so.JavaApplicationType,
+			Executor: so.ExecutorSpec{
+				Instances:    &executors,
+				SparkPodSpec: so.SparkPodSpec{GPU: gpu},
+			},
+		},
+	}
+	if priorityClassName != "" {
+		app.Spec.BatchSchedulerOptions = &so.BatchSchedulerConfiguration{PriorityClassName: &priorityClassName}
+	}
+	return app
+}
+
+func highPrioritySelector(operator corev1.ScopeSelectorOperator, values ...string) *corev1.ScopeSelector {
+	return &corev1.ScopeSelector{
+		MatchExpressions: []corev1.ScopedResourceSelectorRequirement{{
+			ScopeName: corev1.ResourceQuotaScopePriorityClass,
+			Operator:  operator,
+			Values:    values,
+		}},
+	}
+}
+
+func TestCheckSparkApplication(t *testing.T) {
+	type testcase struct {
+		name           string
+		quotas         []*corev1.ResourceQuota
+		pods           []*corev1.Pod
+		app            so.SparkApplication
+		expectedReason string
+	}
+
+	testcases := []testcase{
+		{
+			name:           "fits into cpu quota",
+			quotas:         []*corev1.ResourceQuota{newTestQuota("q", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("5")}, nil)},
+			pods:           []*corev1.Pod{newTestPod("pod", "", "1")},
+			app:            newTestApp("", 2, nil),
+			expectedReason: "",
+		},
+		{
+			name:           "exceeds requests.cpu quota",
+			quotas:         []*corev1.ResourceQuota{newTestQuota("q", corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("4")}, nil)},
+			pods:           []*corev1.Pod{newTestPod("pod", "", "2")},
+			app:            newTestApp("", 2, nil),
+			expectedReason: "SparkApplication default/app requests too many cores (3.512 cores requested, 2.000 available).",
+		},
+		{
+			name:           "exceeds limits.memory quota",
+			quotas:         []*corev1.ResourceQuota{newTestQuota("q", corev1.ResourceList{corev1.ResourceLimitsMemory: resource.MustParse("2Gi")}, nil)},
+			app:            newTestApp("", 1, nil),
+			expectedReason: "SparkApplication default/app exceeds limits.memory of ResourceQuota q (3816Mi requested, 2Gi available).",
+		},
+		{
+			name:           "exceeds extended resource quota",
+			quotas:         []*corev1.ResourceQuota{newTestQuota("q", corev1.ResourceList{"requests.nvidia.com/gpu": resource.MustParse("1")}, nil)},
+			app:            newTestApp("", 2, &so.GPUSpec{Name: "nvidia.com/gpu", Quantity: 1}),
+			expectedReason: "SparkApplication default/app exceeds requests.nvidia.com/gpu of ResourceQuota q (2 requested, 1 available).",
+		},
+		{
+			name:           "exceeds pods quota",
+			quotas:         []*corev1.ResourceQuota{newTestQuota("q", corev1.ResourceList{corev1.ResourcePods: resource.MustParse("3")}, nil)},
+			pods:           []*corev1.Pod{newTestPod("pod", "", "1")},
+			app:            newTestApp("", 2, nil),
+			expectedReason: "SparkApplication default/app requests too many pods (4 requested, 2 available).",
+		},
+		{
+			name:           "not selected by priority class scope",
+			quotas:         []*corev1.ResourceQuota{newTestQuota("q", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}, highPrioritySelector(corev1.ScopeSelectorOpIn, "high"))},
+			app:            newTestApp("low", 1, nil),
+			expectedReason: "",
+		},
+		{
+			name:           "selected by priority class scope",
+			quotas:         []*corev1.ResourceQuota{newTestQuota("q", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}, highPrioritySelector(corev1.ScopeSelectorOpIn, "high"))},
+			app:            newTestApp("high", 1, nil),
+			expectedReason: "SparkApplication default/app requests too many cores (2.512 cores requested, 1.000 available).",
+		},
+		{
+			name:           "selected by priority class scope in quota scopes",
+			quotas:         []*corev1.ResourceQuota{newTestQuota("q", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}, nil, corev1.ResourceQuotaScopePriorityClass)},
+			app:            newTestApp("high", 1, nil),
+			expectedReason: "SparkApplication default/app requests too many cores (2.512 cores requested, 1.000 available).",
+		},
+		{
+			name:           "scoped usage only counts objects in scope",
+			quotas:         []*corev1.ResourceQuota{newTestQuota("q", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")}, highPrioritySelector(corev1.ScopeSelectorOpIn, "high"))},
+			pods:           []*corev1.Pod{newTestPod("high", "high", "1"), newTestPod("low", "low", "10")},
+			app:            newTestApp("high", 1, nil),
+			expectedReason: "",
+		},
+		{
+			name:           "not selected by terminating scope",
+			quotas:         []*corev1.ResourceQuota{newTestQuota("q", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}, nil, corev1.ResourceQuotaScopeTerminating)},
+			app:            newTestApp("", 1, nil),
+			expectedReason: "",
+		},
+		{
+			name:           "selected by not best effort scope",
+			quotas:         []*corev1.ResourceQuota{newTestQuota("q", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}, nil, corev1.ResourceQuotaScopeNotBestEffort)},
+			app:            newTestApp("", 1, nil),
+			expectedReason: "SparkApplication default/app requests too many cores (2.512 cores requested, 1.000 available).",
+		},
+		{
+			name:           "unsupported scope is ignored",
+			quotas:         []*corev1.ResourceQuota{newTestQuota("q", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}, nil, "Unknown")},
+			app:            newTestApp("", 1, nil),
+			expectedReason: "",
+		},
+	}
+
+	for _, test := range testcases {
+		enforcer := newTestEnforcer(EnforcementModeQueue, test.quotas...)
+		for _, pod := range test.pods {
+			enforcer.watcher.onPodAdded(pod)
+		}
+		reason, err := enforcer.CheckSparkApplication(test.app)
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.expectedReason, reason, test.name)
+	}
+}
+
+func TestReserveSparkApplication(t *testing.T) {
+	enforcer := newTestEnforcer(EnforcementModeQueue, newTestQuota("q", corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("4")}, nil))
+	first := newTestApp("", 1, nil)
+	first.Name = "first"
+	first.ResourceVersion = "1"
+	first.Status.AppState.State = so.QueuedState
+	second := newTestApp("", 1, nil)
+	second.Name = "second"
+	second.Status.AppState.State = so.QueuedState
+	enforcer.watcher.onSparkApplicationAdded(&first)
+	enforcer.watcher.onSparkApplicationAdded(&second)
+
+	// Both queued applications fit on their own, but only the first one once its resources are reserved.
+	reason, err := enforcer.CheckSparkApplication(second)
+	assert.Nil(t, err)
+	assert.Equal(t, "", reason)
+	reason, err = enforcer.ReserveSparkApplication(first)
+	assert.Nil(t, err)
+	assert.Equal(t, "", reason)
+	reason, err = enforcer.ReserveSparkApplication(second)
+	assert.Nil(t, err)
+	assert.Equal(t, "SparkApplication default/second requests too many cores (2.512 cores requested, 1.488 available).", reason)
+
+	// The reservation is kept while the first application is still seen queued.
+	update := func(app so.SparkApplication, resourceVersion string, state so.ApplicationStateType) so.SparkApplication {
+		updated := *app.DeepCopy()
+		updated.ResourceVersion = resourceVersion
+		updated.Status.AppState.State = state
+		enforcer.watcher.onSparkApplicationUpdated(&app, &updated)
+		return updated
+	}
+	first = update(first, "2", so.QueuedState)
+	reason, _ = enforcer.ReserveSparkApplication(second)
+	assert.Equal(t, "SparkApplication default/second requests too many cores (2.512 cores requested, 1.488 available).", reason)
+
+	// The reservation is released once the first application has left the queue, which then uses the resources
+	// of its driver and executors.
+	first = update(first, "3", so.RunningState)
+	reason, _ = enforcer.ReserveSparkApplication(second)
+	assert.Equal(t, "SparkApplication default/second requests too many cores (2.512 cores requested, 2.000 available).", reason)
+	update(first, "4", so.CompletedState)
+	reason, _ = enforcer.ReserveSparkApplication(second)
+	assert.Equal(t, "", reason)
+}
+
+func TestAdmitSparkApplicationWithUnchangedUsage(t *testing.T) {
+	enforcer := newTestEnforcer(EnforcementModeQueue, newTestQuota("q", corev1.ResourceList{corev1.ResourcePods: resource.MustParse("2")}, nil))
+	app := newTestApp("", 2, nil)
+	enforcer.watcher.onSparkApplicationAdded(&app)
+
+	// The application is counted against the quota already, but its usage hasn't increased.
+	admission, err := enforcer.AdmitSparkApplication(app)
+	assert.Nil(t, err)
+	assert.Nil(t, admission.Violation)
+
+	reason, err := enforcer.CheckSparkApplication(app)
+	assert.Nil(t, err)
+	assert.Equal(t, "SparkApplication default/app requests too many pods (4 requested, 2 available).", reason)
+}
+
+func TestAdmitSparkApplicationByNamespaceMode(t *testing.T) {
+	type testcase struct {
+		name            string
+		namespaceLabel  string
+		expectedMode    EnforcementMode
+		expectedAllowed bool
+	}
+
+	testcases := []testcase{
+		{name: "default mode", expectedMode: EnforcementModeReject, expectedAllowed: false},
+		{name: "reject mode", namespaceLabel: "reject", expectedMode: EnforcementModeReject, expectedAllowed: false},
+		{name: "queue mode", namespaceLabel: "queue", expectedMode: EnforcementModeQueue, expectedAllowed: true},
+		{name: "warn mode", namespaceLabel: "warn", expectedMode: EnforcementModeWarn, expectedAllowed: true},
+		{name: "disabled mode", namespaceLabel: "disabled", expectedMode: EnforcementModeDisabled, expectedAllowed: true},
+		{name: "invalid mode", namespaceLabel: "ignore", expectedMode: EnforcementModeReject, expectedAllowed: false},
+	}
+
+	for _, test := range testcases {
+		enforcer := newTestEnforcer(EnforcementModeReject, newTestQuota("q", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}, nil))
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+		if test.namespaceLabel != "" {
+			namespace.Labels = map[string]string{config.QuotaEnforcementLabel: test.namespaceLabel}
+		}
+		enforcer.namespaceInformer.Informer().GetIndexer().Add(namespace)
+
+		admission, err := enforcer.AdmitSparkApplication(newTestApp("", 1, nil))
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.expectedMode, admission.Mode, test.name)
+		assert.Equal(t, test.expectedAllowed, admission.Allowed(), test.name)
+		if test.expectedMode == EnforcementModeDisabled {
+			assert.Nil(t, admission.Violation, test.name)
+			continue
+		}
+		assert.Equal(t, corev1.ResourceRequestsCPU, admission.Violation.Resource, test.name)
+		shortfall := admission.Violation.Shortfall()
+		assert.Equal(t, "1512m", shortfall.String(), test.name)
+	}
+}
+
+func TestReport(t *testing.T) {
+	enforcer := newTestEnforcer(EnforcementModeWarn,
+		newTestQuota("a", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4"), "count/services": resource.MustParse("1")}, nil),
+		newTestQuota("b", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}, nil, "Unknown"),
+		newTestQuota("c", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}, nil, corev1.ResourceQuotaScopeBestEffort))
+	enforcer.watcher.onPodAdded(newTestPod("pod", "", "1"))
+	app := newTestApp("", 1, nil)
+	enforcer.watcher.onSparkApplicationAdded(&app)
+
+	report, err := enforcer.Report("default", "")
+	assert.Nil(t, err)
+	assert.Equal(t, EnforcementModeWarn, report.Mode)
+	assert.Equal(t, []string{"1", "2512m"}, []string{
+		quantityString(report.Reserved[KindPod], corev1.ResourceRequestsCPU),
+		quantityString(report.Reserved[KindSparkApplication], corev1.ResourceRequestsCPU),
+	})
+	assert.Equal(t, 3, len(report.Quotas))
+	assert.Equal(t, "", report.Quotas[0].Error)
+	assert.Equal(t, "3512m", quantityString(report.Quotas[0].Used, corev1.ResourceCPU))
+	_, tracked := report.Quotas[0].Used["count/services"]
+	assert.False(t, tracked)
+	assert.Equal(t, "unsupported ResourceQuota scope Unknown", report.Quotas[1].Error)
+	assert.Equal(t, []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort}, report.Quotas[2].Scopes)
+
+	// Applications are only in scope of the ResourceQuotas selecting them.
+	selects, err := report.Quotas[0].Selects(app)
+	assert.Nil(t, err)
+	assert.True(t, selects)
+	selects, err = report.Quotas[2].Selects(app)
+	assert.Nil(t, err)
+	assert.False(t, selects)
+
+	// The usage of the given application is left out.
+	report, err = enforcer.Report("default", app.Name)
+	assert.Nil(t, err)
+	assert.Equal(t, "1", quantityString(report.Quotas[0].Used, corev1.ResourceCPU))
+}
+
+func quantityString(resources corev1.ResourceList, name corev1.ResourceName) string {
+	quantity := resources[name]
+	return quantity.String()
+}
+
+func newTestScheduledApp(name string, policy so.ConcurrencyPolicy) so.ScheduledSparkApplication {
+	return so.ScheduledSparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: so.ScheduledSparkApplicationSpec{
+			Schedule:          "@every 1h",
+			ConcurrencyPolicy: policy,
+			Template:          newTestApp("", 1, nil).Spec,
+		},
+	}
+}
+
+// newTestRun returns a run of the scheduled application in the given state, which requests 2 cores.
+func newTestRun(scheduledApp so.ScheduledSparkApplication, name string, state so.ApplicationStateType) *so.SparkApplication {
+	run := newTestApp("", 1, nil)
+	run.Name = name
+	run.OwnerReferences = []metav1.OwnerReference{{Kind: KindScheduledSparkApplication, Name: scheduledApp.Name}}
+	run.Status.AppState.State = state
+	return &run
+}
+
+func TestScheduledSparkApplicationReservation(t *testing.T) {
+	type testcase struct {
+		name     string
+		policy   so.ConcurrencyPolicy
+		states   []so.ApplicationStateType
+		expected string
+	}
+
+	// A ScheduledSparkApplication reserves the resources of its runs only while they are active, i.e. nothing
+	// between runs, and the resources of all of its overlapping runs if its concurrency policy allows them.
+	testcases := []testcase{
+		{name: "forbid between runs", policy: so.ConcurrencyForbid, states: []so.ApplicationStateType{so.CompletedState}, expected: "0"},
+		{name: "forbid during a run", policy: so.ConcurrencyForbid, states: []so.ApplicationStateType{so.CompletedState, so.RunningState}, expected: "2"},
+		{name: "replace during a run", policy: so.ConcurrencyReplace, states: []so.ApplicationStateType{so.FailedState, so.SubmittedState}, expected: "2"},
+		{name: "allow between runs", policy: so.ConcurrencyAllow, states: []so.ApplicationStateType{so.CompletedState, so.FailedState}, expected: "0"},
+		{name: "allow during overlapping runs", policy: so.ConcurrencyAllow, states: []so.ApplicationStateType{so.RunningState, so.RunningState, so.CompletedState}, expected: "4"},
+		{name: "allow with a queued run", policy: so.ConcurrencyAllow, states: []so.ApplicationStateType{so.RunningState, so.QueuedState}, expected: "2"},
+	}
+
+	for _, test := range testcases {
+		enforcer := newTestEnforcer(EnforcementModeReject)
+		scheduledApp := newTestScheduledApp("scheduled", test.policy)
+		for i, state := range test.states {
+			enforcer.watcher.onSparkApplicationAdded(newTestRun(scheduledApp, fmt.Sprintf("scheduled-%d", i), state))
+		}
+
+		reserved := enforcer.watcher.GetReservedResourcesByKind()["default"]
+		assert.Equal(t, "0", quantityString(reserved[KindSparkApplication].quantities, corev1.ResourceRequestsCPU), test.name)
+		assert.Equal(t, test.expected, quantityString(reserved[KindScheduledSparkApplication].quantities, corev1.ResourceRequestsCPU), test.name)
+
+		namespaceUsage, applicationUsage := enforcer.watcher.GetCurrentResourceUsageWithApplication("default", KindScheduledSparkApplication, scheduledApp.Name, nil)
+		assert.Equal(t, "0", quantityString(namespaceUsage.quantities, corev1.ResourceRequestsCPU), test.name)
+		assert.Equal(t, test.expected, quantityString(applicationUsage.quantities, corev1.ResourceRequestsCPU), test.name)
+	}
+}
+
+func TestAdmitScheduledSparkApplicationWithActiveRun(t *testing.T) {
+	enforcer := newTestEnforcer(EnforcementModeReject, newTestQuota("q", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("3")}, nil))
+	scheduledApp := newTestScheduledApp("scheduled", so.ConcurrencyForbid)
+	run := newTestRun(scheduledApp, "scheduled-1", so.RunningState)
+	enforcer.watcher.onSparkApplicationAdded(run)
+
+	// Updating the ScheduledSparkApplication is allowed, as its active run already reserves a run's resources.
+	admission, err := enforcer.AdmitScheduledSparkApplication(scheduledApp)
+	assert.Nil(t, err)
+	assert.True(t, admission.Allowed())
+
+	// Another ScheduledSparkApplication does not fit next to the active run.
+	admission, err = enforcer.AdmitScheduledSparkApplication(newTestScheduledApp("other", so.ConcurrencyForbid))
+	assert.Nil(t, err)
+	assert.False(t, admission.Allowed())
+
+	// Once the run is deleted, the ScheduledSparkApplication reserves nothing until its next run.
+	enforcer.watcher.onSparkApplicationDeleted(run)
+	admission, err = enforcer.AdmitScheduledSparkApplication(newTestScheduledApp("other", so.ConcurrencyForbid))
+	assert.Nil(t, err)
+	assert.True(t, admission.Allowed())
+	assert.Empty(t, enforcer.watcher.scheduledApplicationByNamespaceRun["default"])
+}
@@ -12,7 +12,7 @@ func (r *ResourceUsageWatcher) onPodAdded(obj interface{}) {
 	pod := obj.(*corev1.Pod)
 	// A pod launched by the Spark operator will already be accounted for by the CRD informer callback
 	if !launchedBySparkOperator(pod.ObjectMeta) {
-		r.setResources("Pod", namespaceOrDefault(pod.ObjectMeta), pod.ObjectMeta.Name, podResourceUsage(pod), r.usageByNamespacePod)
+		r.setResources(KindPod, namespaceOrDefault(pod.ObjectMeta), pod.ObjectMeta.Name, podResourceUsage(pod), r.usageByNamespacePod)
 	}
 }
 
@@ -20,9 +20,9 @@ func (r *ResourceUsageWatcher) onPodUpdated(oldObj, newObj interface{}) {
 	newPod := newObj.(*corev1.Pod)
 	if !launchedBySparkOperator(newPod.ObjectMeta) {
 		if newPod.Status.Phase == corev1.PodFailed || newPod.Status.Phase == corev1.PodSucceeded {
-			r.deleteResources("Pod", namespaceOrDefault(newPod.ObjectMeta), newPod.ObjectMeta.Name, r.usageByNamespacePod)
+			r.deleteResources(KindPod, namespaceOrDefault(newPod.ObjectMeta), newPod.ObjectMeta.Name, r.usageByNamespacePod)
 		} else {
-			r.setResources("Pod", namespaceOrDefault(newPod.ObjectMeta), newPod.ObjectMeta.Name, podResourceUsage(newPod), r.usageByNamespacePod)
+			r.setResources(KindPod, namespaceOrDefault(newPod.ObjectMeta), newPod.ObjectMeta.Name, podResourceUsage(newPod), r.usageByNamespacePod)
 		}
 	}
 }
@@ -38,7 +38,7 @@ func (r *ResourceUsageWatcher) onPodDeleted(obj interface{}) {
 		return
 	}
 	if !launchedBySparkOperator(pod.ObjectMeta) {
-		r.deleteResources("Pod", namespaceOrDefault(pod.ObjectMeta), pod.ObjectMeta.Name, r.usageByNamespacePod)
+		r.deleteResources(KindPod, namespaceOrDefault(pod.ObjectMeta), pod.ObjectMeta.Name, r.usageByNamespacePod)
 	}
 }
 
@@ -49,7 +49,8 @@ func (r *ResourceUsageWatcher) onSparkApplicationAdded(obj interface{}) {
 	if err != nil {
 		glog.Errorf("failed to determine resource usage of SparkApplication %s/%s: %v", namespace, app.ObjectMeta.Name, err)
 	} else {
-		r.setResources(KindSparkApplication, namespace, app.ObjectMeta.Name, resources, r.usageByNamespaceApplication)
+		r.setScheduledApplication(namespace, app.ObjectMeta.Name, scheduledSparkApplicationName(app.ObjectMeta))
+		r.setSparkApplicationResources(namespace, app.ObjectMeta.Name, app.Status.AppState.State, resources)
 	}
 }
 
@@ -64,7 +65,8 @@ func (r *ResourceUsageWatcher) onSparkApplicationUpdated(oldObj, newObj interfac
 	if err != nil {
 		glog.Errorf("failed to determine resource useage of SparkApplication %s/%s: %v", namespace, newApp.ObjectMeta.Name, err)
 	} else {
-		r.setResources(KindSparkApplication, namespace, newApp.ObjectMeta.Name, newResources, r.usageByNamespaceApplication)
+		r.setScheduledApplication(namespace, newApp.ObjectMeta.Name, scheduledSparkApplicationName(newApp.ObjectMeta))
+		r.setSparkApplicationResources(namespace, newApp.ObjectMeta.Name, newApp.Status.AppState.State, newResources)
 	}
 }
 
@@ -80,40 +82,6 @@ func (r *ResourceUsageWatcher) onSparkApplicationDeleted(obj interface{}) {
 	}
 	namespace := namespaceOrDefault(app.ObjectMeta)
 	r.deleteResources(KindSparkApplication, namespace, app.ObjectMeta.Name, r.usageByNamespaceApplication)
-}
-
-func (r *ResourceUsageWatcher) onScheduledSparkApplicationAdded(obj interface{}) {
-	app := obj.(*so.ScheduledSparkApplication)
-	namespace := namespaceOrDefault(app.ObjectMeta)
-	resources, err := scheduledSparkApplicationResourceUsage(*app)
-	if err != nil {
-		glog.Errorf("failed to determine resource usage of ScheduledSparkApplication %s/%s: %v", namespace, app.ObjectMeta.Name, err)
-	} else {
-		r.setResources(KindScheduledSparkApplication, namespace, app.ObjectMeta.Name, resources, r.usageByNamespaceScheduledApplication)
-	}
-}
-
-func (r *ResourceUsageWatcher) onScheduledSparkApplicationUpdated(oldObj, newObj interface{}) {
-	newApp := oldObj.(*so.ScheduledSparkApplication)
-	namespace := namespaceOrDefault(newApp.ObjectMeta)
-	newResources, err := scheduledSparkApplicationResourceUsage(*newApp)
-	if err != nil {
-		glog.Errorf("failed to determine resource usage of ScheduledSparkApplication %s/%s: %v", namespace, newApp.ObjectMeta.Name, err)
-	} else {
-		r.setResources(KindSparkApplication, namespace, newApp.ObjectMeta.Name, newResources, r.usageByNamespaceScheduledApplication)
-	}
-}
-
-func (r *ResourceUsageWatcher) onScheduledSparkApplicationDeleted(obj interface{}) {
-	var app *so.ScheduledSparkApplication
-	switch o := obj.(type) {
-	case *so.ScheduledSparkApplication:
-		app = o
-	case cache.DeletedFinalStateUnknown:
-		app = o.Obj.(*so.ScheduledSparkApplication)
-	default:
-		return
-	}
-	namespace := namespaceOrDefault(app.ObjectMeta)
-	r.deleteResources(KindScheduledSparkApplication, namespace, app.ObjectMeta.Name, r.usageByNamespaceScheduledApplication)
+	r.deleteResources(KindSparkApplication, namespace, app.ObjectMeta.Name, r.reservedByNamespaceApplication)
+	r.setScheduledApplication(namespace, app.ObjectMeta.Name, "")
 }
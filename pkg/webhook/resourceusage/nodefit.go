@@ -0,0 +1,292 @@
+package resourceusage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/informers"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	so "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+const sparkNodeSelectorConfPrefix = "spark.kubernetes.node.selector."
+
+// NodeFitChecker checks whether the driver and executor pods of applications fit on at least one schedulable node,
+// so that applications that would stay pending forever are rejected on admission.
+type NodeFitChecker struct {
+	nodeInformer corev1informers.NodeInformer
+}
+
+func NewNodeFitChecker(coreV1InformerFactory informers.SharedInformerFactory) NodeFitChecker {
+	informer := coreV1InformerFactory.Core().V1().Nodes()
+	informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{})
+	return NodeFitChecker{nodeInformer: informer}
+}
+
+func (c NodeFitChecker) WaitForCacheSync(stopCh <-chan struct{}) error {
+	if !cache.WaitForCacheSync(stopCh, c.nodeInformer.Informer().HasSynced) {
+		return fmt.Errorf("cache sync canceled")
+	}
+	return nil
+}
+
+// CheckSparkApplication returns the reason why the driver or executor pods of the application do not fit on any
+// schedulable node, or an empty string if they fit.
+func (c NodeFitChecker) CheckSparkApplication(app so.SparkApplication) (string, error) {
+	return c.check(KindSparkApplication, namespaceOrDefault(app.ObjectMeta), app.ObjectMeta.Name, app.Spec)
+}
+
+// CheckScheduledSparkApplication returns the reason why the driver or executor pods of the runs of the application
+// do not fit on any schedulable node, or an empty string if they fit.
+func (c NodeFitChecker) CheckScheduledSparkApplication(app so.ScheduledSparkApplication) (string, error) {
+	return c.check(KindScheduledSparkApplication, namespaceOrDefault(app.ObjectMeta), app.ObjectMeta.Name, app.Spec.Template)
+}
+
+func (c NodeFitChecker) check(kind, namespace, name string, spec so.SparkApplicationSpec) (string, error) {
+	nodes, err := c.nodeInformer.Lister().List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	var schedulableNodes []*corev1.Node
+	for _, node := range nodes {
+		if isSchedulable(node) {
+			schedulableNodes = append(schedulableNodes, node)
+		}
+	}
+	// Without any schedulable nodes, e.g. while a cluster autoscaler scales up from zero, nothing can be told.
+	if len(schedulableNodes) == 0 {
+		glog.V(2).Infof("Not checking whether %s %s/%s fits on a node, as there are no schedulable nodes", kind, namespace, name)
+		return "", nil
+	}
+	sort.Slice(schedulableNodes, func(i, j int) bool { return schedulableNodes[i].Name < schedulableNodes[j].Name })
+
+	var executors int64 = 1
+	if spec.Executor.Instances != nil {
+		executors = int64(*spec.Executor.Instances)
+	}
+	pods := []struct {
+		role        string
+		spec        so.SparkPodSpec
+		coreRequest *string
+	}{
+		{"driver", spec.Driver.SparkPodSpec, spec.Driver.CoreRequest},
+		{"executor", spec.Executor.SparkPodSpec, spec.Executor.CoreRequest},
+	}
+	for _, pod := range pods {
+		if pod.role == "executor" && executors == 0 {
+			continue
+		}
+		requests, err := SparkPodRequests(pod.spec, pod.coreRequest, spec.MemoryOverheadFactor, spec.Type)
+		if err != nil {
+			return "", err
+		}
+		nodeSelector := labels.SelectorFromSet(SparkPodNodeSelector(spec, pod.spec))
+
+		var failures nodeFitFailures
+		fits := false
+		for _, node := range schedulableNodes {
+			if failures.add(node, requests, nodeSelector, pod.spec.Affinity, pod.spec.Tolerations) {
+				fits = true
+				break
+			}
+		}
+		if !fits {
+			return fmt.Sprintf("%s %s/%s: the %s pod requesting %s does not fit on any of the %d schedulable nodes: %s.",
+				kind, namespace, name, pod.role, ResourceList{quantities: requests}, len(schedulableNodes), failures), nil
+		}
+	}
+	return "", nil
+}
+
+// podRequests returns the resources requested by a pod of the given usage, keyed by the names nodes allocate
+// resources under, e.g. cpu.
+func podRequests(usage ResourceList) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for name, quantity := range usage.quantities {
+		if strings.HasPrefix(string(name), requestsPrefix) {
+			requests[corev1.ResourceName(strings.TrimPrefix(string(name), requestsPrefix))] = quantity
+		}
+	}
+	return requests
+}
+
+// SparkPodNodeSelector returns the node selector of a driver or executor pod, which is made of the node selector of
+// the application, the node selectors set in the Spark configuration and the node selector of the pod.
+func SparkPodNodeSelector(spec so.SparkApplicationSpec, podSpec so.SparkPodSpec) labels.Set {
+	nodeSelector := labels.Set{}
+	for key, value := range spec.NodeSelector {
+		nodeSelector[key] = value
+	}
+	for key, value := range spec.SparkConf {
+		if strings.HasPrefix(key, sparkNodeSelectorConfPrefix) {
+			nodeSelector[strings.TrimPrefix(key, sparkNodeSelectorConfPrefix)] = value
+		}
+	}
+	for key, value := range podSpec.NodeSelector {
+		nodeSelector[key] = value
+	}
+	return nodeSelector
+}
+
+// isSchedulable returns whether new pods can be scheduled onto the node, which requires it to be ready and not
+// cordoned.
+func isSchedulable(node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nodeFitFailures counts the nodes a pod does not fit on by the reason it does not.
+type nodeFitFailures struct {
+	unmatched   int
+	untolerated int
+	// insufficient counts the nodes with too little of a resource, along with the most of it allocatable by any of
+	// them.
+	insufficient   map[corev1.ResourceName]int
+	maxAllocatable map[corev1.ResourceName]resource.Quantity
+}
+
+// add returns whether a pod of the given requests, node selector, affinity and tolerations fits on the node, and
+// counts the reason it does not if it does not.
+func (f *nodeFitFailures) add(
+	node *corev1.Node,
+	requests corev1.ResourceList,
+	nodeSelector labels.Selector,
+	affinity *corev1.Affinity,
+	tolerations []corev1.Toleration) bool {
+	if !nodeSelector.Matches(labels.Set(node.Labels)) || !matchesNodeAffinity(node, affinity) {
+		f.unmatched++
+		return false
+	}
+	if !toleratesTaints(node.Spec.Taints, tolerations) {
+		f.untolerated++
+		return false
+	}
+	for _, name := range sortedResourceNames(requests) {
+		requested := requests[name]
+		allocatable := node.Status.Allocatable[name]
+		if requested.Cmp(allocatable) != 1 {
+			continue
+		}
+		if f.insufficient == nil {
+			f.insufficient = make(map[corev1.ResourceName]int)
+			f.maxAllocatable = make(map[corev1.ResourceName]resource.Quantity)
+		}
+		f.insufficient[name]++
+		if maxAllocatable, present := f.maxAllocatable[name]; !present || allocatable.Cmp(maxAllocatable) == 1 {
+			f.maxAllocatable[name] = allocatable.DeepCopy()
+		}
+		return false
+	}
+	return true
+}
+
+func (f nodeFitFailures) String() string {
+	var reasons []string
+	if f.unmatched > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d do not match its node selector or affinity", f.unmatched))
+	}
+	if f.untolerated > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d have taints it does not tolerate", f.untolerated))
+	}
+	names := make([]corev1.ResourceName, 0, len(f.insufficient))
+	for name := range f.insufficient {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	for _, name := range names {
+		maxAllocatable := f.maxAllocatable[name]
+		reasons = append(reasons, fmt.Sprintf("%d have insufficient %s (at most %s allocatable)", f.insufficient[name], name, maxAllocatable.String()))
+	}
+	return strings.Join(reasons, ", ")
+}
+
+// matchesNodeAffinity returns whether the node matches the node affinity required by the affinity, if any.
+func matchesNodeAffinity(node *corev1.Node, affinity *corev1.Affinity) bool {
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+	// The terms are ORed, while the requirements of each term are ANDed.
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		if matchesNodeSelectorTerm(node, term) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesNodeSelectorTerm(node *corev1.Node, term corev1.NodeSelectorTerm) bool {
+	// Like Kubernetes, an empty term matches no nodes.
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+	for _, requirement := range term.MatchExpressions {
+		if !matchesNodeSelectorRequirement(requirement, labels.Set(node.Labels)) {
+			return false
+		}
+	}
+	// metadata.name is the only field nodes can be selected by.
+	fields := labels.Set{"metadata.name": node.Name}
+	for _, requirement := range term.MatchFields {
+		if !matchesNodeSelectorRequirement(requirement, fields) {
+			return false
+		}
+	}
+	return true
+}
+
+var nodeSelectorOperators = map[corev1.NodeSelectorOperator]selection.Operator{
+	corev1.NodeSelectorOpIn:           selection.In,
+	corev1.NodeSelectorOpNotIn:        selection.NotIn,
+	corev1.NodeSelectorOpExists:       selection.Exists,
+	corev1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	corev1.NodeSelectorOpGt:           selection.GreaterThan,
+	corev1.NodeSelectorOpLt:           selection.LessThan,
+}
+
+func matchesNodeSelectorRequirement(requirement corev1.NodeSelectorRequirement, set labels.Set) bool {
+	operator, present := nodeSelectorOperators[requirement.Operator]
+	if !present {
+		return false
+	}
+	selectorRequirement, err := labels.NewRequirement(requirement.Key, operator, requirement.Values)
+	if err != nil {
+		return false
+	}
+	return selectorRequirement.Matches(set)
+}
+
+// toleratesTaints returns whether the tolerations tolerate all taints keeping pods from being scheduled.
+func toleratesTaints(taints []corev1.Taint, tolerations []corev1.Toleration) bool {
+	for i := range taints {
+		taint := &taints[i]
+		if taint.Effect != corev1.TaintEffectNoSchedule && taint.Effect != corev1.TaintEffectNoExecute {
+			continue
+		}
+		tolerated := false
+		for j := range tolerations {
+			if tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
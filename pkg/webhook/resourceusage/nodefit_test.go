@@ -0,0 +1,163 @@
+package resourceusage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+
+	so "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func newTestNodeFitChecker(nodes ...*corev1.Node) NodeFitChecker {
+	checker := NewNodeFitChecker(informers.NewSharedInformerFactory(kubeclientfake.NewSimpleClientset(), 0))
+	for _, node := range nodes {
+		checker.nodeInformer.Informer().GetIndexer().Add(node)
+	}
+	return checker
+}
+
+// newTestNode returns a ready node of 4 cores and 8Gi of allocatable memory.
+func newTestNode(name string, labels map[string]string, taints ...corev1.Taint) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Spec:       corev1.NodeSpec{Taints: taints},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("8Gi"),
+			},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func TestNodeFitCheckSparkApplication(t *testing.T) {
+	type testcase struct {
+		name           string
+		nodes          []*corev1.Node
+		app            func(app *so.SparkApplication)
+		expectedReason string
+	}
+
+	spotTaint := corev1.Taint{Key: "spot", Value: "true", Effect: corev1.TaintEffectNoSchedule}
+	cordoned := newTestNode("cordoned", nil)
+	cordoned.Spec.Unschedulable = true
+	cordoned.Status.Allocatable[corev1.ResourceMemory] = resource.MustParse("64Gi")
+	notReady := newTestNode("not-ready", nil)
+	notReady.Status.Conditions[0].Status = corev1.ConditionFalse
+
+	testcases := []testcase{
+		{
+			name:           "fits",
+			nodes:          []*corev1.Node{newTestNode("node", nil)},
+			expectedReason: "",
+		},
+		{
+			name:  "memory overhead does not fit",
+			nodes: []*corev1.Node{newTestNode("node", nil)},
+			app: func(app *so.SparkApplication) {
+				memory := "7500m"
+				app.Spec.Executor.Memory = &memory
+			},
+			expectedReason: "SparkApplication default/app: the executor pod requesting cpu: 1, memory: 8250Mi does not fit on any of the 1 schedulable nodes: 1 have insufficient memory (at most 8Gi allocatable).",
+		},
+		{
+			name:  "core request fits",
+			nodes: []*corev1.Node{newTestNode("node", nil)},
+			app: func(app *so.SparkApplication) {
+				cores := int32(8)
+				coreRequest := "2"
+				app.Spec.Executor.Cores = &cores
+				app.Spec.Executor.CoreRequest = &coreRequest
+			},
+			expectedReason: "",
+		},
+		{
+			name:  "gpus do not fit",
+			nodes: []*corev1.Node{newTestNode("a", nil), newTestNode("b", nil)},
+			app: func(app *so.SparkApplication) {
+				app.Spec.Executor.GPU = &so.GPUSpec{Name: "nvidia.com/gpu", Quantity: 1}
+			},
+			expectedReason: "SparkApplication default/app: the executor pod requesting cpu: 1, memory: 1408Mi, nvidia.com/gpu: 1 does not fit on any of the 2 schedulable nodes: 2 have insufficient nvidia.com/gpu (at most 0 allocatable).",
+		},
+		{
+			name:  "node selector does not match",
+			nodes: []*corev1.Node{newTestNode("node", map[string]string{"pool": "default"})},
+			app: func(app *so.SparkApplication) {
+				app.Spec.NodeSelector = map[string]string{"pool": "spark"}
+			},
+			expectedReason: "SparkApplication default/app: the driver pod requesting cpu: 1, memory: 1408Mi does not fit on any of the 1 schedulable nodes: 1 do not match its node selector or affinity.",
+		},
+		{
+			name:  "node selector of spark conf matches",
+			nodes: []*corev1.Node{newTestNode("default", map[string]string{"pool": "default"}), newTestNode("spark", map[string]string{"pool": "spark"})},
+			app: func(app *so.SparkApplication) {
+				app.Spec.SparkConf = map[string]string{"spark.kubernetes.node.selector.pool": "spark"}
+			},
+			expectedReason: "",
+		},
+		{
+			name:  "required affinity does not match",
+			nodes: []*corev1.Node{newTestNode("node", map[string]string{"zone": "a"})},
+			app: func(app *so.SparkApplication) {
+				app.Spec.Executor.Affinity = &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+							MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"b"}}},
+						}},
+					},
+				}}
+			},
+			expectedReason: "SparkApplication default/app: the executor pod requesting cpu: 1, memory: 1408Mi does not fit on any of the 1 schedulable nodes: 1 do not match its node selector or affinity.",
+		},
+		{
+			name:           "taint is not tolerated",
+			nodes:          []*corev1.Node{newTestNode("node", nil, spotTaint)},
+			expectedReason: "SparkApplication default/app: the driver pod requesting cpu: 1, memory: 1408Mi does not fit on any of the 1 schedulable nodes: 1 have taints it does not tolerate.",
+		},
+		{
+			name:  "taint is tolerated",
+			nodes: []*corev1.Node{newTestNode("node", nil, spotTaint)},
+			app: func(app *so.SparkApplication) {
+				tolerations := []corev1.Toleration{{Key: "spot", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}}
+				app.Spec.Driver.Tolerations = tolerations
+				app.Spec.Executor.Tolerations = tolerations
+			},
+			expectedReason: "",
+		},
+		{
+			name:  "unschedulable nodes are ignored",
+			nodes: []*corev1.Node{cordoned, notReady, newTestNode("node", nil)},
+			app: func(app *so.SparkApplication) {
+				memory := "15g"
+				app.Spec.Driver.Memory = &memory
+			},
+			expectedReason: "SparkApplication default/app: the driver pod requesting cpu: 1, memory: 16896Mi does not fit on any of the 1 schedulable nodes: 1 have insufficient memory (at most 8Gi allocatable).",
+		},
+		{
+			name:  "no schedulable nodes",
+			nodes: []*corev1.Node{cordoned},
+			app: func(app *so.SparkApplication) {
+				memory := "128g"
+				app.Spec.Driver.Memory = &memory
+			},
+			expectedReason: "",
+		},
+	}
+
+	for _, test := range testcases {
+		checker := newTestNodeFitChecker(test.nodes...)
+		app := newTestApp("", 2, nil)
+		if test.app != nil {
+			test.app(&app)
+		}
+		reason, err := checker.CheckSparkApplication(app)
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.expectedReason, reason, test.name)
+	}
+}
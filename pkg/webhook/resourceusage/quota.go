@@ -0,0 +1,176 @@
+package resourceusage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	requestsPrefix = "requests."
+	limitsPrefix   = "limits."
+)
+
+// more convenient replacement for corev1.ResourceList, keyed by the names ResourceQuotas limit resources under, i.e.
+// requests.<resource>, limits.<resource> and pods. It also holds the attributes quota scopes select objects by.
+type ResourceList struct {
+	quantities corev1.ResourceList
+	scope      usageScope
+}
+
+// usageScope holds the attributes of an object that ResourceQuota scopes select objects by.
+type usageScope struct {
+	priorityClassName string
+	terminating       bool
+	bestEffort        bool
+}
+
+func (r ResourceList) String() string {
+	names := r.names()
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		quantity := r.quantities[name]
+		values = append(values, fmt.Sprintf("%s: %s", name, quantity.String()))
+	}
+	return strings.Join(values, ", ")
+}
+
+// get returns the quantity of the resource with the given name, which is zero if the resource is not used.
+func (r ResourceList) get(name corev1.ResourceName) resource.Quantity {
+	if quantity, present := r.quantities[name]; present {
+		return quantity.DeepCopy()
+	}
+	return resource.Quantity{}
+}
+
+// set sets the quantity of the resource with the given name, dropping zero quantities.
+func (r *ResourceList) set(name corev1.ResourceName, quantity resource.Quantity) {
+	if quantity.IsZero() {
+		delete(r.quantities, name)
+		return
+	}
+	if r.quantities == nil {
+		r.quantities = corev1.ResourceList{}
+	}
+	r.quantities[name] = quantity
+}
+
+// add adds the quantities of other to the quantities of r.
+func (r *ResourceList) add(other ResourceList) {
+	for name, quantity := range other.quantities {
+		sum := r.get(name)
+		sum.Add(quantity)
+		r.set(name, sum)
+	}
+}
+
+// sub subtracts the quantities of other from the quantities of r.
+func (r *ResourceList) sub(other ResourceList) {
+	for name, quantity := range other.quantities {
+		difference := r.get(name)
+		difference.Sub(quantity)
+		r.set(name, difference)
+	}
+}
+
+// times returns the quantities of r multiplied by n.
+func (r ResourceList) times(n int64) ResourceList {
+	result := ResourceList{scope: r.scope}
+	for name, quantity := range r.quantities {
+		if quantity.MilliValue()%1000 == 0 {
+			result.set(name, *resource.NewQuantity(quantity.Value()*n, quantity.Format))
+		} else {
+			result.set(name, *resource.NewMilliQuantity(quantity.MilliValue()*n, quantity.Format))
+		}
+	}
+	return result
+}
+
+// names returns the names of the used resources in a stable order.
+func (r ResourceList) names() []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(r.quantities))
+	for name := range r.quantities {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// quotaResourceName returns the name a resource limited by a ResourceQuota under the given name is tracked by, and
+// whether resources of that name are tracked at all. Object counts other than those of pods are not tracked.
+func quotaResourceName(name corev1.ResourceName) (corev1.ResourceName, bool) {
+	switch {
+	case name == corev1.ResourceCPU || name == corev1.ResourceMemory || name == corev1.ResourceEphemeralStorage:
+		return corev1.ResourceName(requestsPrefix + string(name)), true
+	case name == corev1.ResourcePods || name == "count/pods":
+		return corev1.ResourcePods, true
+	case strings.HasPrefix(string(name), requestsPrefix) || strings.HasPrefix(string(name), limitsPrefix):
+		return name, true
+	}
+	return "", false
+}
+
+// matchesQuota returns whether an object of the given scope is subject to the given ResourceQuota, or an error if
+// the ResourceQuota selects objects by a scope that is not supported.
+func (s usageScope) matchesQuota(quota *corev1.ResourceQuota) (bool, error) {
+	for _, scope := range quota.Spec.Scopes {
+		matches, err := s.matchesScope(scope, corev1.ScopeSelectorOpExists, nil)
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+	if quota.Spec.ScopeSelector == nil {
+		return true, nil
+	}
+	for _, requirement := range quota.Spec.ScopeSelector.MatchExpressions {
+		matches, err := s.matchesScope(requirement.ScopeName, requirement.Operator, requirement.Values)
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (s usageScope) matchesScope(scope corev1.ResourceQuotaScope, operator corev1.ScopeSelectorOperator, values []string) (bool, error) {
+	var hasScope bool
+	switch scope {
+	case corev1.ResourceQuotaScopeTerminating:
+		hasScope = s.terminating
+	case corev1.ResourceQuotaScopeNotTerminating:
+		hasScope = !s.terminating
+	case corev1.ResourceQuotaScopeBestEffort:
+		hasScope = s.bestEffort
+	case corev1.ResourceQuotaScopeNotBestEffort:
+		hasScope = !s.bestEffort
+	case corev1.ResourceQuotaScopePriorityClass:
+		switch operator {
+		case corev1.ScopeSelectorOpIn:
+			return containsString(values, s.priorityClassName), nil
+		case corev1.ScopeSelectorOpNotIn:
+			return !containsString(values, s.priorityClassName), nil
+		}
+		hasScope = s.priorityClassName != ""
+	default:
+		return false, fmt.Errorf("unsupported ResourceQuota scope %s", scope)
+	}
+
+	switch operator {
+	case corev1.ScopeSelectorOpExists:
+		return hasScope, nil
+	case corev1.ScopeSelectorOpDoesNotExist:
+		return !hasScope, nil
+	}
+	return false, fmt.Errorf("unsupported operator %s for ResourceQuota scope %s", operator, scope)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
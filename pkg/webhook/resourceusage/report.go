@@ -0,0 +1,149 @@
+package resourceusage
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	so "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+// ReportPath is the path of the read-only debug endpoint of the webhook server serving the NamespaceReport of the
+// namespace given by the namespace query parameter. It is only served if enabled, as it is not authenticated.
+const ReportPath = "/debug/quota"
+
+// NamespaceReport is the view of the enforcer on the resource quotas of a namespace and the resources reserved in
+// it, as served by the quota debug endpoint.
+type NamespaceReport struct {
+	Namespace string          `json:"namespace"`
+	Mode      EnforcementMode `json:"mode"`
+	// Reserved holds the resources reserved by each kind of object, keyed by the names ResourceQuotas limit
+	// resources under, e.g. requests.cpu.
+	Reserved map[string]corev1.ResourceList `json:"reserved"`
+	Quotas   []QuotaReport                  `json:"quotas"`
+}
+
+// QuotaReport is the view of the enforcer on a ResourceQuota.
+type QuotaReport struct {
+	Name          string                      `json:"name"`
+	Hard          corev1.ResourceList         `json:"hard"`
+	Scopes        []corev1.ResourceQuotaScope `json:"scopes,omitempty"`
+	ScopeSelector *corev1.ScopeSelector       `json:"scopeSelector,omitempty"`
+	// Used holds the resources reserved by the objects in scope of the ResourceQuota, keyed like Hard. Resources
+	// not tracked by the enforcer are left out.
+	Used corev1.ResourceList `json:"used"`
+	// Error tells why the ResourceQuota is ignored, if it is.
+	Error string `json:"error,omitempty"`
+}
+
+// Report returns the view of the enforcer on the resource quotas of the namespace and the resources reserved in it.
+// The resources reserved by the SparkApplication of the given name, if any, are left out of the usage of the
+// ResourceQuotas, so that the report shows whether it fits as if it was created anew.
+func (r *ResourceQuotaEnforcer) Report(namespace, application string) (*NamespaceReport, error) {
+	resourceQuotas, err := r.resourceQuotaInformer.Lister().ResourceQuotas(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(resourceQuotas, func(i, j int) bool { return resourceQuotas[i].Name < resourceQuotas[j].Name })
+
+	report := &NamespaceReport{
+		Namespace: namespace,
+		Mode:      r.ModeForNamespace(namespace),
+		Reserved:  make(map[string]corev1.ResourceList),
+		Quotas:    []QuotaReport{},
+	}
+	for kind, resources := range r.watcher.GetReservedResourcesByKind()[namespace] {
+		if len(resources.quantities) > 0 {
+			report.Reserved[kind] = resources.quantities
+		}
+	}
+	for _, quota := range resourceQuotas {
+		quotaReport := QuotaReport{
+			Name:          quota.Name,
+			Hard:          quota.Spec.Hard,
+			Scopes:        quota.Spec.Scopes,
+			ScopeSelector: quota.Spec.ScopeSelector,
+			Used:          corev1.ResourceList{},
+		}
+		// Checking a scope of no attributes tells whether the scopes of the ResourceQuota are supported.
+		if _, err := (usageScope{}).matchesQuota(quota); err != nil {
+			quotaReport.Error = err.Error()
+			report.Quotas = append(report.Quotas, quotaReport)
+			continue
+		}
+		used, _ := r.watcher.GetCurrentResourceUsageWithApplication(namespace, KindSparkApplication, application, quota)
+		for hardName := range quota.Spec.Hard {
+			if resourceName, tracked := quotaResourceName(hardName); tracked {
+				quotaReport.Used[hardName] = used.get(resourceName)
+			}
+		}
+		report.Quotas = append(report.Quotas, quotaReport)
+	}
+	return report, nil
+}
+
+// SparkApplicationRequest returns the resources the application requests from the resource quotas of its
+// namespace, keyed by the names ResourceQuotas limit resources under, e.g. requests.cpu.
+func SparkApplicationRequest(app so.SparkApplication) (corev1.ResourceList, error) {
+	usage, err := resourceUsage(app.Spec)
+	if err != nil {
+		return nil, err
+	}
+	return usage.quantities, nil
+}
+
+// Selects returns whether the application is in scope of the ResourceQuota, or an error if the ResourceQuota
+// selects objects by a scope that is not supported.
+func (q QuotaReport) Selects(app so.SparkApplication) (bool, error) {
+	usage, err := resourceUsage(app.Spec)
+	if err != nil {
+		return false, err
+	}
+	return usage.scope.matchesQuota(&corev1.ResourceQuota{
+		Spec: corev1.ResourceQuotaSpec{Hard: q.Hard, Scopes: q.Scopes, ScopeSelector: q.ScopeSelector},
+	})
+}
+
+// QuotaResourceName returns the name the resources limited by a ResourceQuota under the given name are requested
+// under, e.g. requests.cpu for cpu, and whether they are tracked at all.
+func QuotaResourceName(name corev1.ResourceName) (corev1.ResourceName, bool) {
+	return quotaResourceName(name)
+}
+
+// reservationCollector exports the resources reserved in each namespace by each kind of object, as seen by the
+// enforcer. The quantities are computed from the watcher on each scrape.
+type reservationCollector struct {
+	watcher *ResourceUsageWatcher
+	desc    *prometheus.Desc
+}
+
+// NewReservationCollector returns a Prometheus collector of the resources reserved in each namespace by each kind
+// of object, in cores for CPU, bytes for memory and units otherwise.
+func NewReservationCollector(prefix string, enforcer *ResourceQuotaEnforcer) prometheus.Collector {
+	return &reservationCollector{
+		watcher: &enforcer.watcher,
+		desc: prometheus.NewDesc(
+			util.CreateValidMetricNameLabel(prefix, "resource_quota_reserved"),
+			"Resources reserved against the resource quotas by namespace, kind of object and resource",
+			[]string{"namespace", "kind", "resource"},
+			nil),
+	}
+}
+
+func (c *reservationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *reservationCollector) Collect(ch chan<- prometheus.Metric) {
+	for namespace, reservedByKind := range c.watcher.GetReservedResourcesByKind() {
+		for kind, resources := range reservedByKind {
+			for name, quantity := range resources.quantities {
+				ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(quantity.MilliValue())/1000,
+					namespace, kind, string(name))
+			}
+		}
+	}
+}
@@ -13,14 +13,6 @@ import (
 	"strings"
 )
 
-// ...are you serious, Go?
-func max(x, y int64) int64 {
-	if x > y {
-		return x
-	}
-	return y
-}
-
 const (
 	// https://spark.apache.org/docs/latest/configuration.html
 	defaultCpuMillicores  = 1000
@@ -45,28 +37,29 @@ func launchedBySparkOperator(meta metav1.ObjectMeta) bool {
 	return present && val == "true"
 }
 
-func resourcesRequiredToSchedule(resourceRequirements corev1.ResourceRequirements) (cpu int64, memoryBytes int64) {
-	if coresRequest, present := resourceRequirements.Requests[corev1.ResourceCPU]; present {
-		cpu = coresRequest.MilliValue()
-	} else if coresLimit, present := resourceRequirements.Limits[corev1.ResourceCPU]; present {
-		cpu = coresLimit.MilliValue()
-	}
-	if memoryRequest, present := resourceRequirements.Requests[corev1.ResourceMemory]; present {
-		memoryBytes = memoryRequest.Value()
-	} else if memoryLimit, present := resourceRequirements.Limits[corev1.ResourceMemory]; present {
-		memoryBytes = memoryLimit.Value()
+// scheduledSparkApplicationName returns the name of the ScheduledSparkApplication owning the object, which is the
+// case for SparkApplications that are runs of it, or an empty string if there is none.
+func scheduledSparkApplicationName(meta metav1.ObjectMeta) string {
+	for _, owner := range meta.OwnerReferences {
+		if owner.Kind == KindScheduledSparkApplication {
+			return owner.Name
+		}
 	}
-	return cpu, memoryBytes
+	return ""
 }
 
-func coresRequiredForSparkPod(spec so.SparkPodSpec, instances int64) (int64, error) {
-	var cpu int64
-	if spec.Cores != nil {
-		cpu = int64(*spec.Cores) * 1000
-	} else {
-		cpu = defaultCpuMillicores
+// containerResourceUsage returns the resources requested and limited by a container. As with Kubernetes, resources
+// with a limit but no request are requested up to the limit.
+func containerResourceUsage(resourceRequirements corev1.ResourceRequirements) ResourceList {
+	var usage ResourceList
+	for name, quantity := range resourceRequirements.Limits {
+		usage.set(corev1.ResourceName(limitsPrefix+string(name)), quantity.DeepCopy())
+		usage.set(corev1.ResourceName(requestsPrefix+string(name)), quantity.DeepCopy())
 	}
-	return cpu * instances, nil
+	for name, quantity := range resourceRequirements.Requests {
+		usage.set(corev1.ResourceName(requestsPrefix+string(name)), quantity.DeepCopy())
+	}
+	return usage
 }
 
 var javaStringSuffixes = map[string]int64{
@@ -150,37 +143,118 @@ func MemoryRequiredForSparkPod(spec so.SparkPodSpec, memoryOverheadFactor *strin
 	return (memoryBytes + memoryOverheadBytes) * replicas, nil
 }
 
-func resourceUsage(spec so.SparkApplicationSpec) (ResourceList, error) {
-	driverMemoryOverheadFactor := spec.MemoryOverheadFactor
-	executorMemoryOverheadFactor := spec.MemoryOverheadFactor
-	driverMemory, err := MemoryRequiredForSparkPod(spec.Driver.SparkPodSpec, driverMemoryOverheadFactor, spec.Type, 1)
+// sparkPodResourceUsage returns the resources requested and limited by the given number of instances of a driver or
+// executor pod, including its sidecars and init containers.
+func sparkPodResourceUsage(spec so.SparkPodSpec, coreRequest *string, memoryOverheadFactor *string, appType so.SparkApplicationType, instances int64) (ResourceList, error) {
+	memory, err := MemoryRequiredForSparkPod(spec, memoryOverheadFactor, appType, 1)
 	if err != nil {
 		return ResourceList{}, err
 	}
+	// The CPU request of Spark pods is their core request if set, and their number of cores otherwise.
+	cpuRequest := *resource.NewMilliQuantity(defaultCpuMillicores, resource.DecimalSI)
+	if coreRequest != nil {
+		cpuRequest, err = resource.ParseQuantity(*coreRequest)
+		if err != nil {
+			return ResourceList{}, fmt.Errorf("failed to parse core request %q: %v", *coreRequest, err)
+		}
+	} else if spec.Cores != nil {
+		cpuRequest = *resource.NewQuantity(int64(*spec.Cores), resource.DecimalSI)
+	}
+	// Pods without a CPU limit are assumed to be given one as high as their request, as by a LimitRange, since
+	// they could not be created at all in namespaces with quotas on CPU limits otherwise.
+	cpuLimit := cpuRequest.DeepCopy()
+	if spec.CoreLimit != nil {
+		cpuLimit, err = resource.ParseQuantity(*spec.CoreLimit)
+		if err != nil {
+			return ResourceList{}, fmt.Errorf("failed to parse core limit %q: %v", *spec.CoreLimit, err)
+		}
+	}
 
-	var instances int64 = 1
-	if spec.Executor.Instances != nil {
-		instances = int64(*spec.Executor.Instances)
+	var usage ResourceList
+	usage.set(corev1.ResourceRequestsCPU, cpuRequest)
+	usage.set(corev1.ResourceLimitsCPU, cpuLimit)
+	// The memory limit of Spark pods is their memory request.
+	usage.set(corev1.ResourceRequestsMemory, *resource.NewQuantity(memory, resource.BinarySI))
+	usage.set(corev1.ResourceLimitsMemory, *resource.NewQuantity(memory, resource.BinarySI))
+	if spec.GPU != nil {
+		// Extended resources are requested up to their limit.
+		gpus := *resource.NewQuantity(spec.GPU.Quantity, resource.DecimalSI)
+		usage.set(corev1.ResourceName(requestsPrefix+spec.GPU.Name), gpus)
+		usage.set(corev1.ResourceName(limitsPrefix+spec.GPU.Name), gpus.DeepCopy())
+	}
+	// Sidecars run along the Spark container, while init containers run one at a time before them, so a pod
+	// requests the larger of the sum of its containers and the largest of its init containers.
+	for _, sidecar := range spec.Sidecars {
+		usage.add(containerResourceUsage(sidecar.Resources))
+	}
+	var initUsage ResourceList
+	for _, initContainer := range spec.InitContainers {
+		initUsage = maxResourceList(initUsage, containerResourceUsage(initContainer.Resources))
+	}
+	usage = maxResourceList(initUsage, usage)
+	usage.set(corev1.ResourcePods, *resource.NewQuantity(1, resource.DecimalSI))
+	return usage.times(instances), nil
+}
+
+// SparkPodRequests returns the resources requested by a single driver or executor pod, keyed by the names nodes
+// allocate resources under, e.g. cpu.
+func SparkPodRequests(spec so.SparkPodSpec, coreRequest *string, memoryOverheadFactor *string, appType so.SparkApplicationType) (corev1.ResourceList, error) {
+	usage, err := sparkPodResourceUsage(spec, coreRequest, memoryOverheadFactor, appType, 1)
+	if err != nil {
+		return nil, err
+	}
+	return podRequests(usage), nil
+}
+
+// submissionResourceUsage returns the resources requested and limited by the pod of the submission Job of an
+// application, which is only created in cluster mode.
+func submissionResourceUsage(spec so.SparkApplicationSpec) ResourceList {
+	var usage ResourceList
+	if spec.Mode == so.ClientMode {
+		return usage
 	}
-	executorMemory, err := MemoryRequiredForSparkPod(spec.Executor.SparkPodSpec, executorMemoryOverheadFactor, spec.Type, instances)
+	usage.set(corev1.ResourceRequestsCPU, resource.MustParse(config.SparkSubmitPodCPURequest))
+	usage.set(corev1.ResourceLimitsCPU, resource.MustParse(config.SparkSubmitPodCPULimit))
+	usage.set(corev1.ResourceRequestsMemory, resource.MustParse(config.SparkSubmitPodMemoryRequest))
+	usage.set(corev1.ResourceLimitsMemory, resource.MustParse(config.SparkSubmitPodMemoryLimit))
+	usage.set(corev1.ResourcePods, *resource.NewQuantity(1, resource.DecimalSI))
+	return usage
+}
+
+// resourceUsage returns the resources requested and limited by the pods of an application being submitted, which are
+// its driver and executors and the pod of its submission Job.
+func resourceUsage(spec so.SparkApplicationSpec) (ResourceList, error) {
+	usage, err := sparkPodsResourceUsage(spec)
 	if err != nil {
 		return ResourceList{}, err
 	}
+	usage.add(submissionResourceUsage(spec))
+	return usage, nil
+}
 
-	driverCores, err := coresRequiredForSparkPod(spec.Driver.SparkPodSpec, 1)
+// sparkPodsResourceUsage returns the resources requested and limited by the driver and executors of an application.
+func sparkPodsResourceUsage(spec so.SparkApplicationSpec) (ResourceList, error) {
+	usage, err := sparkPodResourceUsage(spec.Driver.SparkPodSpec, spec.Driver.CoreRequest, spec.MemoryOverheadFactor, spec.Type, 1)
 	if err != nil {
 		return ResourceList{}, err
 	}
 
-	executorCores, err := coresRequiredForSparkPod(spec.Executor.SparkPodSpec, instances)
+	var instances int64 = 1
+	if spec.Executor.Instances != nil {
+		instances = int64(*spec.Executor.Instances)
+	}
+	executorUsage, err := sparkPodResourceUsage(spec.Executor.SparkPodSpec, spec.Executor.CoreRequest, spec.MemoryOverheadFactor, spec.Type, instances)
 	if err != nil {
 		return ResourceList{}, err
 	}
+	usage.add(executorUsage)
 
-	return ResourceList{
-		cpu:    *resource.NewMilliQuantity(driverCores+executorCores, resource.DecimalSI),
-		memory: *resource.NewQuantity(driverMemory+executorMemory, resource.DecimalSI),
-	}, nil
+	// Spark pods always request CPU and memory and have no active deadline, so they are never best effort nor
+	// terminating.
+	if spec.BatchSchedulerOptions != nil && spec.BatchSchedulerOptions.PriorityClassName != nil {
+		usage.scope.priorityClassName = *spec.BatchSchedulerOptions.PriorityClassName
+	}
+	return usage, nil
 }
 
 func sparkApplicationResourceUsage(sparkApp so.SparkApplication) (ResourceList, error) {
@@ -188,9 +262,20 @@ func sparkApplicationResourceUsage(sparkApp so.SparkApplication) (ResourceList,
 	if !sparkApp.Status.TerminationTime.IsZero() || sparkApp.Status.AppState.State == so.FailedState || sparkApp.Status.AppState.State == so.CompletedState {
 		return ResourceList{}, nil
 	}
-	return resourceUsage(sparkApp.Spec)
+	// Neither does a SparkApplication queued until it fits into the resource quotas
+	if sparkApp.Status.AppState.State == so.QueuedState {
+		return ResourceList{}, nil
+	}
+	// The pod of the submission Job only runs until the application is submitted
+	switch sparkApp.Status.AppState.State {
+	case so.NewState, so.PendingSubmissionState, so.PendingRerunState, so.InvalidatingState:
+		return resourceUsage(sparkApp.Spec)
+	}
+	return sparkPodsResourceUsage(sparkApp.Spec)
 }
 
+// scheduledSparkApplicationResourceUsage returns the resources requested by a single run of the
+// ScheduledSparkApplication, which is what admitting it requires.
 func scheduledSparkApplicationResourceUsage(sparkApp so.ScheduledSparkApplication) (ResourceList, error) {
 	// Failed validation, will consume no resources
 	if sparkApp.Status.ScheduleState == so.FailedValidationState {
@@ -201,8 +286,7 @@ func scheduledSparkApplicationResourceUsage(sparkApp so.ScheduledSparkApplicatio
 
 func podResourceUsage(pod *corev1.Pod) ResourceList {
 	spec := pod.Spec
-	var initCores int64
-	var initMemoryBytes int64
+	var initUsage ResourceList
 	completed := make(map[string]struct{})
 
 	for _, containerStatus := range pod.Status.InitContainerStatuses {
@@ -216,26 +300,50 @@ func podResourceUsage(pod *corev1.Pod) ResourceList {
 		}
 	}
 
+	bestEffort := true
 	for _, container := range spec.InitContainers {
+		bestEffort = bestEffort && isBestEffort(container.Resources)
 		if _, present := completed[container.Name]; !present {
-			c, m := resourcesRequiredToSchedule(container.Resources)
-			initCores = max(c, initCores)
-			initMemoryBytes = max(m, initMemoryBytes)
+			initUsage = maxResourceList(initUsage, containerResourceUsage(container.Resources))
 		}
 	}
-	var cores int64
-	var memoryBytes int64
+	var usage ResourceList
 	for _, container := range spec.Containers {
+		bestEffort = bestEffort && isBestEffort(container.Resources)
 		if _, present := completed[container.Name]; !present {
-			c, m := resourcesRequiredToSchedule(container.Resources)
-			cores += c
-			memoryBytes += m
+			usage.add(containerResourceUsage(container.Resources))
+		}
+	}
+	usage = maxResourceList(initUsage, usage)
+	usage.set(corev1.ResourcePods, *resource.NewQuantity(1, resource.DecimalSI))
+	usage.scope = usageScope{
+		priorityClassName: spec.PriorityClassName,
+		terminating:       spec.ActiveDeadlineSeconds != nil && *spec.ActiveDeadlineSeconds >= 0,
+		bestEffort:        bestEffort,
+	}
+	return usage
+}
+
+// maxResourceList returns the maximum quantities of each resource of a and b.
+func maxResourceList(a, b ResourceList) ResourceList {
+	var result ResourceList
+	result.add(a)
+	for name, quantity := range b.quantities {
+		if quantity.Cmp(result.get(name)) == 1 {
+			result.set(name, quantity.DeepCopy())
 		}
 	}
-	cores = max(initCores, cores)
-	memoryBytes = max(initMemoryBytes, memoryBytes)
-	return ResourceList{
-		cpu:    *resource.NewMilliQuantity(cores, resource.DecimalSI),
-		memory: *resource.NewQuantity(memoryBytes, resource.DecimalSI),
+	return result
+}
+
+// isBestEffort returns whether a container neither requests nor limits CPU or memory.
+func isBestEffort(resourceRequirements corev1.ResourceRequirements) bool {
+	for _, resources := range []corev1.ResourceList{resourceRequirements.Requests, resourceRequirements.Limits} {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			if quantity, present := resources[name]; present && !quantity.IsZero() {
+				return false
+			}
+		}
 	}
+	return true
 }
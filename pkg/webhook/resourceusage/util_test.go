@@ -2,6 +2,11 @@ package resourceusage
 
 import (
 	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	so "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
 )
 
 func assertMemory(memoryString string, expectedBytes int64, t *testing.T) {
@@ -23,3 +28,160 @@ func TestJavaMemoryString(t *testing.T) {
 	assertMemory("10TB", 10*1024*1024*1024*1024, t)
 	assertMemory("10PB", 10*1024*1024*1024*1024*1024, t)
 }
+
+func TestPodResourceUsage(t *testing.T) {
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{
+				Name: "init",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("3")},
+				},
+			}},
+			Containers: []corev1.Container{
+				{
+					Name: "main",
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("1"),
+							corev1.ResourceMemory: resource.MustParse("1Gi"),
+							"nvidia.com/gpu":      resource.MustParse("1"),
+						},
+					},
+				},
+				{
+					Name: "sidecar",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+					},
+				},
+			},
+		},
+	}
+
+	usage := podResourceUsage(pod)
+	expected := map[corev1.ResourceName]string{
+		corev1.ResourceRequestsCPU:    "3",
+		corev1.ResourceLimitsCPU:      "1",
+		corev1.ResourceRequestsMemory: "1Gi",
+		corev1.ResourceLimitsMemory:   "1Gi",
+		"requests.nvidia.com/gpu":     "1",
+		"limits.nvidia.com/gpu":       "1",
+		corev1.ResourcePods:           "1",
+	}
+	if len(usage.quantities) != len(expected) {
+		t.Errorf("expected %d resources, got %v", len(expected), usage)
+	}
+	for name, value := range expected {
+		quantity := usage.get(name)
+		if quantity.Cmp(resource.MustParse(value)) != 0 {
+			t.Errorf("%s: expected %s, got %s", name, value, quantity.String())
+		}
+	}
+	if usage.scope.bestEffort || usage.scope.terminating {
+		t.Errorf("expected a pod neither best effort nor terminating, got %+v", usage.scope)
+	}
+}
+
+func assertUsage(name string, usage ResourceList, expected map[corev1.ResourceName]string, t *testing.T) {
+	if len(usage.quantities) != len(expected) {
+		t.Errorf("%s: expected %d resources, got %v", name, len(expected), usage)
+	}
+	for resourceName, value := range expected {
+		quantity := usage.get(resourceName)
+		if quantity.Cmp(resource.MustParse(value)) != 0 {
+			t.Errorf("%s: %s: expected %s, got %s", name, resourceName, value, quantity.String())
+		}
+	}
+}
+
+func TestSparkApplicationResourceUsage(t *testing.T) {
+	driverCores := int32(1)
+	executorCores := int32(2)
+	executors := int32(2)
+	coreRequest := "500m"
+	coreLimit := "2"
+	driverMemory := "1g"
+	executorMemory := "2g"
+	spec := so.SparkApplicationSpec{
+		Type: so.JavaApplicationType,
+		Driver: so.DriverSpec{
+			CoreRequest: &coreRequest,
+			SparkPodSpec: so.SparkPodSpec{
+				Cores:     &driverCores,
+				CoreLimit: &coreLimit,
+				Memory:    &driverMemory,
+				Sidecars: []corev1.Container{{
+					Name: "sidecar",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("250m"),
+							corev1.ResourceMemory: resource.MustParse("128Mi"),
+						},
+						Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+					},
+				}},
+				InitContainers: []corev1.Container{{
+					Name: "init",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("1"),
+							corev1.ResourceMemory: resource.MustParse("4Gi"),
+						},
+					},
+				}},
+			},
+		},
+		Executor: so.ExecutorSpec{
+			Instances: &executors,
+			SparkPodSpec: so.SparkPodSpec{
+				Cores:  &executorCores,
+				Memory: &executorMemory,
+			},
+		},
+	}
+
+	// The driver container requests 500m cores and 1Gi+384Mi of memory and is limited to 2 cores, and its sidecar
+	// requests 250m cores and 128Mi of memory and is limited to 256Mi of memory. Its init container requests more
+	// CPU and memory than both of them together, so the driver pod requests 1 core and 4Gi of memory and is
+	// limited to 2 cores and 1664Mi of memory. The two executor pods request and are limited to 2 cores and
+	// 2Gi+384Mi of memory each.
+	sparkPods := map[corev1.ResourceName]string{
+		corev1.ResourceRequestsCPU:    "5",
+		corev1.ResourceLimitsCPU:      "6",
+		corev1.ResourceRequestsMemory: "8960Mi",
+		corev1.ResourceLimitsMemory:   "6528Mi",
+		corev1.ResourcePods:           "3",
+	}
+	// The submission Job pod requests 512m cores and 500Mi of memory and is limited to 1024m cores and 1000Mi of
+	// memory.
+	sparkPodsAndSubmission := map[corev1.ResourceName]string{
+		corev1.ResourceRequestsCPU:    "5512m",
+		corev1.ResourceLimitsCPU:      "7024m",
+		corev1.ResourceRequestsMemory: "9460Mi",
+		corev1.ResourceLimitsMemory:   "7528Mi",
+		corev1.ResourcePods:           "4",
+	}
+
+	app := so.SparkApplication{Spec: spec}
+	usage, err := sparkApplicationResourceUsage(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertUsage("new application", usage, sparkPodsAndSubmission, t)
+
+	app.Status.AppState.State = so.RunningState
+	usage, err = sparkApplicationResourceUsage(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertUsage("running application", usage, sparkPods, t)
+
+	app.Status.AppState.State = so.NewState
+	app.Spec.Mode = so.ClientMode
+	usage, err = sparkApplicationResourceUsage(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertUsage("new application in client mode", usage, sparkPods, t)
+}
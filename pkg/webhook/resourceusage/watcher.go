@@ -1,54 +1,53 @@
 package resourceusage
 
 import (
-	"fmt"
 	"sync"
 
+	so "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
 	crdinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
 
 	"github.com/golang/glog"
-	"k8s.io/apimachinery/pkg/api/resource"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/informers"
 	corev1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/tools/cache"
 )
 
+// ResourceUsageWatcher tracks the resources reserved by pods and SparkApplications. A ScheduledSparkApplication
+// reserves nothing by itself, but is attributed the resources of its runs while they are active, so one of the
+// Forbid or Replace concurrency policies reserves the resources of a single run only while the run is active, and
+// one of the Allow concurrency policy reserves the resources of all of its overlapping runs.
 type ResourceUsageWatcher struct {
-	currentUsageLock                     *sync.RWMutex
-	currentUsageByNamespace              map[string]*ResourceList
-	usageByNamespacePod                  map[string]map[string]*ResourceList
-	usageByNamespaceScheduledApplication map[string]map[string]*ResourceList
-	usageByNamespaceApplication          map[string]map[string]*ResourceList
-	crdInformerFactory                   crdinformers.SharedInformerFactory
-	coreV1InformerFactory                informers.SharedInformerFactory
-	podInformer                          corev1informers.PodInformer
-}
-
-// more convenient replacement for corev1.ResourceList
-type ResourceList struct {
-	cpu    resource.Quantity
-	memory resource.Quantity
+	currentUsageLock            *sync.RWMutex
+	usageByNamespacePod         map[string]map[string]*ResourceList
+	usageByNamespaceApplication map[string]map[string]*ResourceList
+	// scheduledApplicationByNamespaceRun maps the SparkApplications that are runs of ScheduledSparkApplications to
+	// the names of their ScheduledSparkApplications.
+	scheduledApplicationByNamespaceRun map[string]map[string]string
+	// reservedByNamespaceApplication holds the resources reserved for queued SparkApplications released for
+	// submission, which are their usage until they are seen to have left the queue.
+	reservedByNamespaceApplication map[string]map[string]*ResourceList
+	crdInformerFactory             crdinformers.SharedInformerFactory
+	coreV1InformerFactory          informers.SharedInformerFactory
+	podInformer                    corev1informers.PodInformer
 }
 
 const (
+	KindPod                       = "Pod"
 	KindSparkApplication          = "SparkApplication"
 	KindScheduledSparkApplication = "ScheduledSparkApplication"
 )
 
-func (r ResourceList) String() string {
-	return fmt.Sprintf("cpu: %v mcpu, memory %v bytes", r.cpu.MilliValue(), r.memory.Value())
-}
-
 func newResourceUsageWatcher(crdInformerFactory crdinformers.SharedInformerFactory, coreV1InformerFactory informers.SharedInformerFactory) ResourceUsageWatcher {
 	glog.V(2).Infof("Creating new resource usage watcher")
 	r := ResourceUsageWatcher{
-		crdInformerFactory:                   crdInformerFactory,
-		currentUsageLock:                     &sync.RWMutex{},
-		coreV1InformerFactory:                coreV1InformerFactory,
-		currentUsageByNamespace:              make(map[string]*ResourceList),
-		usageByNamespacePod:                  make(map[string]map[string]*ResourceList),
-		usageByNamespaceScheduledApplication: make(map[string]map[string]*ResourceList),
-		usageByNamespaceApplication:          make(map[string]map[string]*ResourceList),
+		crdInformerFactory:                 crdInformerFactory,
+		currentUsageLock:                   &sync.RWMutex{},
+		coreV1InformerFactory:              coreV1InformerFactory,
+		usageByNamespacePod:                make(map[string]map[string]*ResourceList),
+		usageByNamespaceApplication:        make(map[string]map[string]*ResourceList),
+		scheduledApplicationByNamespaceRun: make(map[string]map[string]string),
+		reservedByNamespaceApplication:     make(map[string]map[string]*ResourceList),
 	}
 	// Note: Events for each handler are processed serially, so no coordination is needed between
 	// the different callbacks. Coordination is still needed around updating the shared state.
@@ -58,12 +57,6 @@ func newResourceUsageWatcher(crdInformerFactory crdinformers.SharedInformerFacto
 		UpdateFunc: r.onSparkApplicationUpdated,
 		DeleteFunc: r.onSparkApplicationDeleted,
 	})
-	scheduledSparkApplicationInformer := r.crdInformerFactory.Sparkoperator().V1beta2().ScheduledSparkApplications()
-	scheduledSparkApplicationInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    r.onScheduledSparkApplicationAdded,
-		UpdateFunc: r.onScheduledSparkApplicationUpdated,
-		DeleteFunc: r.onScheduledSparkApplicationDeleted,
-	})
 	r.podInformer = r.coreV1InformerFactory.Core().V1().Pods()
 	r.podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    r.onPodAdded,
@@ -74,69 +67,135 @@ func newResourceUsageWatcher(crdInformerFactory crdinformers.SharedInformerFacto
 }
 
 func (r *ResourceUsageWatcher) GetCurrentResourceUsage(namespace string) ResourceList {
+	namespaceResources, _ := r.GetCurrentResourceUsageWithApplication(namespace, "", "", nil)
+	return namespaceResources
+}
+
+// GetCurrentResourceUsageWithApplication returns the resources used by the objects in the namespace other than the
+// given application, and the resources used by the application, including those of its runs if it is a
+// ScheduledSparkApplication. Only the resources of objects of a scope matching the quota are counted, or of all
+// objects if the quota is nil.
+func (r *ResourceUsageWatcher) GetCurrentResourceUsageWithApplication(namespace, kind, name string, quota *corev1.ResourceQuota) (namespaceResources, applicationResources ResourceList) {
 	r.currentUsageLock.RLock()
 	defer r.currentUsageLock.RUnlock()
-	if resourceUsageInternal, present := r.currentUsageByNamespace[namespace]; present {
-		return ResourceList{
-			cpu:    resourceUsageInternal.cpu,
-			memory: resourceUsageInternal.memory,
+	for objectKind, resourceMap := range r.resourceMapsByKind() {
+		for objectName, resources := range resourceMap[namespace] {
+			if quota != nil {
+				// Unsupported scopes are rejected before objects are matched against them.
+				if matches, _ := resources.scope.matchesQuota(quota); !matches {
+					continue
+				}
+			}
+			if objectKind == kind && objectName == name {
+				applicationResources.add(*resources)
+				continue
+			}
+			ownerKind, ownerName := r.unsafeAttributedObject(namespace, objectKind, objectName)
+			if ownerKind == kind && ownerName == name {
+				applicationResources.add(*resources)
+			} else {
+				namespaceResources.add(*resources)
+			}
 		}
 	}
-	return ResourceList{}
+	return namespaceResources, applicationResources
 }
 
-func (r *ResourceUsageWatcher) GetCurrentResourceUsageWithApplication(namespace, kind, name string) (namespaceResources, applicationResources ResourceList) {
+// GetReservedResourcesByKind returns the resources reserved in each namespace by each kind of object. The resources
+// of the runs of ScheduledSparkApplications are reserved by the ScheduledSparkApplications.
+func (r *ResourceUsageWatcher) GetReservedResourcesByKind() map[string]map[string]ResourceList {
 	r.currentUsageLock.RLock()
 	defer r.currentUsageLock.RUnlock()
-	if resourceUsageInternal, present := r.currentUsageByNamespace[namespace]; present {
-		var applicationResources ResourceList
-		var namespaceMap map[string]map[string]*ResourceList
-		switch kind {
-		case KindSparkApplication:
-			namespaceMap = r.usageByNamespaceApplication
-		case KindScheduledSparkApplication:
-			namespaceMap = r.usageByNamespaceScheduledApplication
-		}
-		if applicationMap, present := namespaceMap[namespace]; present {
-			if ar, present := applicationMap[name]; present {
-				applicationResources = *ar
+	reserved := make(map[string]map[string]ResourceList)
+	for objectKind, resourceMap := range r.resourceMapsByKind() {
+		for namespace, namespaceMap := range resourceMap {
+			if _, present := reserved[namespace]; !present {
+				reserved[namespace] = make(map[string]ResourceList)
+			}
+			for objectName, resources := range namespaceMap {
+				kind, _ := r.unsafeAttributedObject(namespace, objectKind, objectName)
+				total := reserved[namespace][kind]
+				total.add(*resources)
+				reserved[namespace][kind] = total
 			}
 		}
-		currentUsage := *resourceUsageInternal // Creates a copy
-		currentUsage.cpu.Sub(applicationResources.cpu)
-		currentUsage.memory.Sub(applicationResources.memory)
-		return currentUsage, applicationResources
 	}
-	return ResourceList{}, ResourceList{}
+	return reserved
+}
+
+func (r *ResourceUsageWatcher) resourceMapsByKind() map[string]map[string]map[string]*ResourceList {
+	return map[string]map[string]map[string]*ResourceList{
+		KindPod:              r.usageByNamespacePod,
+		KindSparkApplication: r.usageByNamespaceApplication,
+	}
+}
+
+// unsafeAttributedObject returns the kind and name of the object the resources of the given object are attributed
+// to, which is the ScheduledSparkApplication of a SparkApplication that is one of its runs and the object itself
+// otherwise.
+func (r *ResourceUsageWatcher) unsafeAttributedObject(namespace, kind, name string) (string, string) {
+	if kind == KindSparkApplication {
+		if scheduledName, present := r.scheduledApplicationByNamespaceRun[namespace][name]; present {
+			return KindScheduledSparkApplication, scheduledName
+		}
+	}
+	return kind, name
+}
+
+// setScheduledApplication records the ScheduledSparkApplication the SparkApplication of the given name is a run of,
+// or that it is not a run of any if scheduledName is empty.
+func (r *ResourceUsageWatcher) setScheduledApplication(namespace, name, scheduledName string) {
+	r.currentUsageLock.Lock()
+	defer r.currentUsageLock.Unlock()
+	if scheduledName == "" {
+		if namespaceMap, present := r.scheduledApplicationByNamespaceRun[namespace]; present {
+			delete(namespaceMap, name)
+		}
+		return
+	}
+	if _, present := r.scheduledApplicationByNamespaceRun[namespace]; !present {
+		r.scheduledApplicationByNamespaceRun[namespace] = make(map[string]string)
+	}
+	r.scheduledApplicationByNamespaceRun[namespace][name] = scheduledName
+}
+
+// reserveSparkApplication reserves the given resources for the queued SparkApplication, which are counted as its
+// usage until it is seen to have left the queue.
+func (r *ResourceUsageWatcher) reserveSparkApplication(namespace, name string, resources ResourceList) {
+	glog.V(3).Infof("Reserving resources %v for SparkApplication %s/%s", resources, namespace, name)
+	r.currentUsageLock.Lock()
+	defer r.currentUsageLock.Unlock()
+	r.unsafeSetResources(namespace, name, resources, r.reservedByNamespaceApplication)
+	r.unsafeSetResources(namespace, name, resources, r.usageByNamespaceApplication)
+}
+
+// setSparkApplicationResources updates the resources of the SparkApplication in the given state. The resources
+// reserved for it are kept as long as it is seen queued, and released once it has left the queue.
+func (r *ResourceUsageWatcher) setSparkApplicationResources(namespace, name string, state so.ApplicationStateType, resources ResourceList) {
+	glog.V(3).Infof("Updating object %s %s/%s with resources %v", KindSparkApplication, namespace, name, resources)
+	r.currentUsageLock.Lock()
+	if reserved, present := r.reservedByNamespaceApplication[namespace][name]; present && state == so.QueuedState {
+		resources = *reserved
+	} else {
+		r.unsafeDeleteResources(namespace, name, r.reservedByNamespaceApplication)
+	}
+	r.unsafeSetResources(namespace, name, resources, r.usageByNamespaceApplication)
+	r.currentUsageLock.Unlock()
+	if glog.V(3) {
+		glog.Infof("Current resources for namespace %s: %v", namespace, r.GetCurrentResourceUsage(namespace))
+	}
 }
 
 func (r *ResourceUsageWatcher) unsafeSetResources(namespace, name string, resources ResourceList, resourceMap map[string]map[string]*ResourceList) {
 	if _, present := resourceMap[namespace]; !present {
 		resourceMap[namespace] = make(map[string]*ResourceList)
 	}
-	// Clear any resource usage currently stored for this object
-	r.unsafeDeleteResources(namespace, name, resourceMap)
 	resourceMap[namespace][name] = &resources
-	if current, present := r.currentUsageByNamespace[namespace]; present {
-		current.cpu.Add(resources.cpu)
-		current.memory.Add(resources.memory)
-	} else {
-		r.currentUsageByNamespace[namespace] = &ResourceList{
-			cpu:    resources.cpu,
-			memory: resources.memory,
-		}
-	}
 }
 
 func (r *ResourceUsageWatcher) unsafeDeleteResources(namespace, name string, resourceMap map[string]map[string]*ResourceList) {
 	if namespaceMap, present := resourceMap[namespace]; present {
-		if resources, present := namespaceMap[name]; present {
-			delete(resourceMap[namespace], name)
-			if current, present := r.currentUsageByNamespace[namespace]; present {
-				current.cpu.Sub(resources.cpu)
-				current.memory.Sub(resources.memory)
-			}
-		}
+		delete(namespaceMap, name)
 	}
 }
 
@@ -145,7 +204,9 @@ func (r *ResourceUsageWatcher) setResources(typeName, namespace, name string, re
 	r.currentUsageLock.Lock()
 	r.unsafeSetResources(namespace, name, resources, resourceMap)
 	r.currentUsageLock.Unlock()
-	glog.V(3).Infof("Current resources for namespace %s: %v", namespace, r.currentUsageByNamespace[namespace])
+	if glog.V(3) {
+		glog.Infof("Current resources for namespace %s: %v", namespace, r.GetCurrentResourceUsage(namespace))
+	}
 }
 
 func (r *ResourceUsageWatcher) deleteResources(typeName, namespace, name string, resourceMap map[string]map[string]*ResourceList) {
@@ -153,5 +214,7 @@ func (r *ResourceUsageWatcher) deleteResources(typeName, namespace, name string,
 	r.currentUsageLock.Lock()
 	r.unsafeDeleteResources(namespace, name, resourceMap)
 	r.currentUsageLock.Unlock()
-	glog.V(3).Infof("Current resources for namespace %s: %v", namespace, r.currentUsageByNamespace[namespace])
+	if glog.V(3) {
+		glog.Infof("Current resources for namespace %s: %v", namespace, r.GetCurrentResourceUsage(namespace))
+	}
 }
@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/golang/glog"
+	"go.opentelemetry.io/otel/attribute"
 
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	"k8s.io/api/admissionregistration/v1beta1"
@@ -36,14 +37,18 @@ import (
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/informers"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
+	kubescheme "k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 
 	crdapi "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io"
 	crdv1beta2 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
 	crinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
 	crdlisters "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/listers/sparkoperator.k8s.io/v1beta2"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/tracing"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/webhook/resourceusage"
 )
@@ -84,8 +89,23 @@ type WebHook struct {
 	sparkJobNamespace              string
 	deregisterOnExit               bool
 	enableResourceQuotaEnforcement bool
-	resourceQuotaEnforcer          resourceusage.ResourceQuotaEnforcer
-	coreV1InformerFactory          informers.SharedInformerFactory
+	resourceQuotaEnforcer          *resourceusage.ResourceQuotaEnforcer
+	nodeFitChecker                 *resourceusage.NodeFitChecker
+	recorder                       record.EventRecorder
+	metrics                        *webhookMetrics
+	tracer                         *tracing.Tracer
+}
+
+// admissionReview is an AdmissionReview holding an admissionResponse.
+type admissionReview struct {
+	Response *admissionResponse `json:"response,omitempty"`
+}
+
+// admissionResponse extends AdmissionResponse with the warnings returned to API clients, which are supported by
+// API servers from Kubernetes 1.19 on and ignored by older ones.
+type admissionResponse struct {
+	*admissionv1beta1.AdmissionResponse
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 // Configuration parsed from command-line flags
@@ -100,6 +120,7 @@ type webhookFlags struct {
 	webhookConfigName        string
 	webhookFailOnError       bool
 	webhookNamespaceSelector string
+	enableQuotaReport        bool
 }
 
 var userConfig webhookFlags
@@ -115,6 +136,7 @@ func init() {
 	flag.IntVar(&userConfig.webhookPort, "webhook-port", 8080, "Service port of the webhook server.")
 	flag.BoolVar(&userConfig.webhookFailOnError, "webhook-fail-on-error", false, "Whether Kubernetes should reject requests when the webhook fails.")
 	flag.StringVar(&userConfig.webhookNamespaceSelector, "webhook-namespace-selector", "", "The webhook will only operate on namespaces with this label, specified in the form key1=value1,key2=value2. Required if webhook-fail-on-error is true.")
+	flag.BoolVar(&userConfig.enableQuotaReport, "webhook-enable-quota-report", false, "Whether the webhook server serves the resource quotas and usage of namespaces, and the names of the applications reserving resources in them, on the unauthenticated endpoint "+resourceusage.ReportPath+" read by sparkctl quota. Only enable it if access to the webhook port is restricted.")
 }
 
 // New creates a new WebHook instance.
@@ -123,8 +145,10 @@ func New(
 	informerFactory crinformers.SharedInformerFactory,
 	jobNamespace string,
 	deregisterOnExit bool,
-	enableResourceQuotaEnforcement bool,
-	coreV1InformerFactory informers.SharedInformerFactory) (*WebHook, error) {
+	resourceQuotaEnforcer *resourceusage.ResourceQuotaEnforcer,
+	nodeFitChecker *resourceusage.NodeFitChecker,
+	metricsConfig *util.MetricConfig,
+	tracer *tracing.Tracer) (*WebHook, error) {
 
 	cert, err := NewCertProvider(
 		userConfig.serverCert,
@@ -151,14 +175,25 @@ func New(
 		sparkJobNamespace:              jobNamespace,
 		deregisterOnExit:               deregisterOnExit,
 		failurePolicy:                  arv1beta1.Ignore,
-		coreV1InformerFactory:          coreV1InformerFactory,
-		enableResourceQuotaEnforcement: enableResourceQuotaEnforcement,
+		enableResourceQuotaEnforcement: resourceQuotaEnforcer != nil,
+		resourceQuotaEnforcer:          resourceQuotaEnforcer,
+		nodeFitChecker:                 nodeFitChecker,
+		tracer:                         tracer,
 	}
 
 	if userConfig.webhookFailOnError {
 		hook.failurePolicy = arv1beta1.Fail
 	}
 
+	if resourceQuotaEnforcer != nil {
+		eventBroadcaster := record.NewBroadcaster()
+		eventBroadcaster.StartLogging(glog.V(2).Infof)
+		eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+			Interface: clientset.CoreV1().Events(jobNamespace),
+		})
+		hook.recorder = eventBroadcaster.NewRecorder(kubescheme.Scheme, apiv1.EventSource{Component: "spark-operator"})
+	}
+
 	if userConfig.webhookNamespaceSelector == "" {
 		if userConfig.webhookFailOnError {
 			return nil, fmt.Errorf("webhook-namespace-selector must be set when webhook-fail-on-error is true")
@@ -171,12 +206,19 @@ func New(
 		hook.selector = selector
 	}
 
-	if enableResourceQuotaEnforcement {
-		hook.resourceQuotaEnforcer = resourceusage.NewResourceQuotaEnforcer(informerFactory, coreV1InformerFactory)
+	if metricsConfig != nil {
+		hook.metrics = newWebhookMetrics(metricsConfig)
+		hook.metrics.registerMetrics()
+		if resourceQuotaEnforcer != nil {
+			util.RegisterMetric(resourceusage.NewReservationCollector(metricsConfig.MetricsPrefix, resourceQuotaEnforcer))
+		}
 	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc(path, hook.serve)
+	if resourceQuotaEnforcer != nil && userConfig.enableQuotaReport {
+		mux.HandleFunc(resourceusage.ReportPath, hook.serveQuotaReport)
+	}
 	hook.server = &http.Server{
 		Addr:    fmt.Sprintf(":%d", userConfig.webhookPort),
 		Handler: mux,
@@ -213,6 +255,11 @@ func (wh *WebHook) Start(stopCh <-chan struct{}) error {
 			return err
 		}
 	}
+	if wh.nodeFitChecker != nil {
+		if err := wh.nodeFitChecker.WaitForCacheSync(stopCh); err != nil {
+			return err
+		}
+	}
 
 	go func() {
 		glog.Info("Starting the Spark admission webhook server")
@@ -244,6 +291,18 @@ func (wh *WebHook) Stop() error {
 
 func (wh *WebHook) serve(w http.ResponseWriter, r *http.Request) {
 	glog.V(2).Info("Serving admission request")
+	start := time.Now()
+	resource, outcome := unknownAdmissionResource, admissionError
+	var review *admissionv1beta1.AdmissionReview
+	defer func() {
+		if wh.metrics != nil {
+			wh.metrics.observeAdmission(resource, outcome, start)
+		}
+		if wh.tracer != nil {
+			wh.recordAdmissionSpan(review, resource, outcome, start)
+		}
+	}()
+
 	var body []byte
 	if r.Body != nil {
 		data, err := ioutil.ReadAll(r.Body)
@@ -265,29 +324,34 @@ func (wh *WebHook) serve(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	review := &admissionv1beta1.AdmissionReview{}
+	decoded := &admissionv1beta1.AdmissionReview{}
 	deserializer := codecs.UniversalDeserializer()
-	if _, _, err := deserializer.Decode(body, nil, review); err != nil {
+	if _, _, err := deserializer.Decode(body, nil, decoded); err != nil {
 		internalError(w, err)
 		return
 	}
+	review = decoded
+	if review.Request != nil {
+		resource = review.Request.Resource.Resource
+	}
 	var whErr error
 	var reviewResponse *admissionv1beta1.AdmissionResponse
+	var warnings []string
 	switch review.Request.Resource {
 	case podResource:
 		reviewResponse, whErr = mutatePods(review, wh.lister, wh.sparkJobNamespace)
 	case sparkApplicationResource:
-		if !wh.enableResourceQuotaEnforcement {
+		if !wh.enableValidation() {
 			unexpectedResourceType(w, review.Request.Resource.String())
 			return
 		}
-		reviewResponse, whErr = admitSparkApplications(review, wh.resourceQuotaEnforcer)
+		reviewResponse, warnings, whErr = wh.admitSparkApplications(review)
 	case scheduledSparkApplicationResource:
-		if !wh.enableResourceQuotaEnforcement {
+		if !wh.enableValidation() {
 			unexpectedResourceType(w, review.Request.Resource.String())
 			return
 		}
-		reviewResponse, whErr = admitScheduledSparkApplications(review, wh.resourceQuotaEnforcer)
+		reviewResponse, warnings, whErr = wh.admitScheduledSparkApplications(review)
 	default:
 		unexpectedResourceType(w, review.Request.Resource.String())
 		return
@@ -297,9 +361,9 @@ func (wh *WebHook) serve(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := admissionv1beta1.AdmissionReview{}
+	response := admissionReview{}
 	if reviewResponse != nil {
-		response.Response = reviewResponse
+		response.Response = &admissionResponse{AdmissionResponse: reviewResponse, Warnings: warnings}
 		if review.Request != nil {
 			response.Response.UID = review.Request.UID
 		}
@@ -312,7 +376,77 @@ func (wh *WebHook) serve(w http.ResponseWriter, r *http.Request) {
 	}
 	if _, err := w.Write(resp); err != nil {
 		internalError(w, err)
+		return
+	}
+	if reviewResponse == nil || reviewResponse.Allowed {
+		outcome = admissionAllowed
+	} else {
+		outcome = admissionDenied
+	}
+}
+
+// serveQuotaReport serves the view of the resource quota enforcer on the namespace given by the namespace query
+// parameter as JSON, leaving out the usage of the SparkApplication given by the optional application query parameter.
+func (wh *WebHook) serveQuotaReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	namespace := r.URL.Query().Get("namespace")
+	if namespace == "" {
+		http.Error(w, "missing namespace query parameter", http.StatusBadRequest)
+		return
+	}
+	report, err := wh.resourceQuotaEnforcer.Report(namespace, r.URL.Query().Get("application"))
+	if err != nil {
+		glog.Errorf("failed to report resource quotas of namespace %s: %v", namespace, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		glog.Errorf("failed to write the resource quota report of namespace %s: %v", namespace, err)
+	}
+}
+
+// recordAdmissionSpan records the span of the given admission request, linked to the current run of the
+// SparkApplication the admitted object belongs to if any.
+func (wh *WebHook) recordAdmissionSpan(
+	review *admissionv1beta1.AdmissionReview,
+	resource string,
+	outcome string,
+	start time.Time) {
+	attributes := []attribute.KeyValue{tracing.ResourceKey.String(resource), tracing.OutcomeKey.String(outcome)}
+	var app *crdv1beta2.SparkApplication
+	if review != nil && review.Request != nil {
+		attributes = append(attributes, tracing.OperationKey.String(string(review.Request.Operation)))
+		app = wh.getAdmittedApplication(review.Request)
+	}
+	wh.tracer.RecordAdmission(app, start, time.Now(), attributes...)
+}
+
+// getAdmittedApplication returns the SparkApplication the object of the given admission request belongs to, or nil
+// if it is not found.
+func (wh *WebHook) getAdmittedApplication(request *admissionv1beta1.AdmissionRequest) *crdv1beta2.SparkApplication {
+	var appName string
+	switch request.Resource {
+	case podResource:
+		pod := &corev1.Pod{}
+		if err := json.Unmarshal(request.Object.Raw, pod); err != nil {
+			return nil
+		}
+		appName = pod.Labels[config.SparkAppNameLabel]
+	case sparkApplicationResource:
+		appName = request.Name
+	}
+	if appName == "" {
+		return nil
+	}
+	app, err := wh.lister.SparkApplications(request.Namespace).Get(appName)
+	if err != nil {
+		return nil
 	}
+	return app
 }
 
 func unexpectedResourceType(w http.ResponseWriter, kind string) {
@@ -430,7 +564,7 @@ func (wh *WebHook) selfRegistration(webhookConfigName string) error {
 		}
 	}
 
-	if wh.enableResourceQuotaEnforcement {
+	if wh.enableValidation() {
 		validatingExisting, validatingGetErr := vwcClient.Get(webhookConfigName, metav1.GetOptions{})
 		if validatingGetErr != nil {
 			if !errors.IsNotFound(validatingGetErr) {
@@ -465,7 +599,7 @@ func (wh *WebHook) selfRegistration(webhookConfigName string) error {
 func (wh *WebHook) selfDeregistration(webhookConfigName string) error {
 	mutatingConfigs := wh.clientset.AdmissionregistrationV1beta1().MutatingWebhookConfigurations()
 	validatingConfigs := wh.clientset.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations()
-	if wh.enableResourceQuotaEnforcement {
+	if wh.enableValidation() {
 		err := validatingConfigs.Delete(webhookConfigName, metav1.NewDeleteOptions(0))
 		if err != nil {
 			return err
@@ -474,54 +608,134 @@ func (wh *WebHook) selfDeregistration(webhookConfigName string) error {
 	return mutatingConfigs.Delete(webhookConfigName, metav1.NewDeleteOptions(0))
 }
 
-func admitSparkApplications(review *admissionv1beta1.AdmissionReview, enforcer resourceusage.ResourceQuotaEnforcer) (*admissionv1beta1.AdmissionResponse, error) {
+// enableValidation returns whether SparkApplications and ScheduledSparkApplications are validated on admission,
+// which is the case if resource quotas are enforced or pods are checked to fit on nodes.
+func (wh *WebHook) enableValidation() bool {
+	return wh.enableResourceQuotaEnforcement || wh.nodeFitChecker != nil
+}
+
+func (wh *WebHook) admitSparkApplications(review *admissionv1beta1.AdmissionReview) (*admissionv1beta1.AdmissionResponse, []string, error) {
 	if review.Request.Resource != sparkApplicationResource {
-		return nil, fmt.Errorf("expected resource to be %s, got %s", sparkApplicationResource, review.Request.Resource)
+		return nil, nil, fmt.Errorf("expected resource to be %s, got %s", sparkApplicationResource, review.Request.Resource)
 	}
 
 	raw := review.Request.Object.Raw
 	app := &crdv1beta2.SparkApplication{}
 	if err := json.Unmarshal(raw, app); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal a SparkApplication from the raw data in the admission request: %v", err)
+		return nil, nil, fmt.Errorf("failed to unmarshal a SparkApplication from the raw data in the admission request: %v", err)
 	}
 
-	reason, err := enforcer.AdmitSparkApplication(*app)
-	if err != nil {
-		return nil, fmt.Errorf("resource quota enforcement failed for SparkApplication: %v", err)
-	}
-	response := &admissionv1beta1.AdmissionResponse{Allowed: reason == ""}
-	if reason != "" {
-		response.Result = &metav1.Status{
-			Message: reason,
-			Code:    400,
+	if wh.nodeFitChecker != nil {
+		oldApp := &crdv1beta2.SparkApplication{}
+		if review.Request.Operation == admissionv1beta1.Update {
+			if err := json.Unmarshal(review.Request.OldObject.Raw, oldApp); err != nil {
+				return nil, nil, fmt.Errorf("failed to unmarshal the old SparkApplication from the raw data in the admission request: %v", err)
+			}
+		}
+		// Updates not changing the spec are always allowed, even if the nodes have changed since.
+		if review.Request.Operation != admissionv1beta1.Update || !equality.Semantic.DeepEqual(oldApp.Spec, app.Spec) {
+			reason, err := wh.nodeFitChecker.CheckSparkApplication(*app)
+			if err != nil {
+				return nil, nil, fmt.Errorf("node fit check failed for SparkApplication: %v", err)
+			}
+			if reason != "" {
+				return deniedResponse(reason), nil, nil
+			}
 		}
 	}
-	return response, nil
+
+	if !wh.enableResourceQuotaEnforcement {
+		return &admissionv1beta1.AdmissionResponse{Allowed: true}, nil, nil
+	}
+	admission, err := wh.resourceQuotaEnforcer.AdmitSparkApplication(*app)
+	if err != nil {
+		return nil, nil, fmt.Errorf("resource quota enforcement failed for SparkApplication: %v", err)
+	}
+	response, warnings := wh.quotaAdmissionResponse(app, resourceusage.KindSparkApplication, app.Namespace, admission)
+	return response, warnings, nil
 }
 
-func admitScheduledSparkApplications(review *admissionv1beta1.AdmissionReview, enforcer resourceusage.ResourceQuotaEnforcer) (*admissionv1beta1.AdmissionResponse, error) {
+func (wh *WebHook) admitScheduledSparkApplications(review *admissionv1beta1.AdmissionReview) (*admissionv1beta1.AdmissionResponse, []string, error) {
 	if review.Request.Resource != scheduledSparkApplicationResource {
-		return nil, fmt.Errorf("expected resource to be %s, got %s", scheduledSparkApplicationResource, review.Request.Resource)
+		return nil, nil, fmt.Errorf("expected resource to be %s, got %s", scheduledSparkApplicationResource, review.Request.Resource)
 	}
 
 	raw := review.Request.Object.Raw
 	app := &crdv1beta2.ScheduledSparkApplication{}
 	if err := json.Unmarshal(raw, app); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal a ScheduledSparkApplication from the raw data in the admission request: %v", err)
+		return nil, nil, fmt.Errorf("failed to unmarshal a ScheduledSparkApplication from the raw data in the admission request: %v", err)
 	}
 
-	response := &admissionv1beta1.AdmissionResponse{Allowed: true}
-	reason, err := enforcer.AdmitScheduledSparkApplication(*app)
+	if wh.nodeFitChecker != nil {
+		oldApp := &crdv1beta2.ScheduledSparkApplication{}
+		if review.Request.Operation == admissionv1beta1.Update {
+			if err := json.Unmarshal(review.Request.OldObject.Raw, oldApp); err != nil {
+				return nil, nil, fmt.Errorf("failed to unmarshal the old ScheduledSparkApplication from the raw data in the admission request: %v", err)
+			}
+		}
+		// Updates not changing the template are always allowed, even if the nodes have changed since.
+		if review.Request.Operation != admissionv1beta1.Update || !equality.Semantic.DeepEqual(oldApp.Spec.Template, app.Spec.Template) {
+			reason, err := wh.nodeFitChecker.CheckScheduledSparkApplication(*app)
+			if err != nil {
+				return nil, nil, fmt.Errorf("node fit check failed for ScheduledSparkApplication: %v", err)
+			}
+			if reason != "" {
+				return deniedResponse(reason), nil, nil
+			}
+		}
+	}
+
+	if !wh.enableResourceQuotaEnforcement {
+		return &admissionv1beta1.AdmissionResponse{Allowed: true}, nil, nil
+	}
+	admission, err := wh.resourceQuotaEnforcer.AdmitScheduledSparkApplication(*app)
 	if err != nil {
-		return nil, fmt.Errorf("resource quota enforcement failed for ScheduledSparkApplication: %v", err)
-	} else if reason != "" {
-		response.Allowed = false
-		response.Result = &metav1.Status{
+		return nil, nil, fmt.Errorf("resource quota enforcement failed for ScheduledSparkApplication: %v", err)
+	}
+	response, warnings := wh.quotaAdmissionResponse(app, resourceusage.KindScheduledSparkApplication, app.Namespace, admission)
+	return response, warnings, nil
+}
+
+// deniedResponse returns the response to an admission request denied for the given reason.
+func deniedResponse(reason string) *admissionv1beta1.AdmissionResponse {
+	return &admissionv1beta1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
 			Message: reason,
 			Code:    400,
-		}
+		},
 	}
-	return response, nil
+}
+
+// quotaAdmissionResponse returns the response to the admission of an object checked against the resource quotas
+// of its namespace, and the warnings to return with it. Objects exceeding the resource quotas of namespaces in warn
+// mode are admitted and recorded as an event and in the metrics, which is all API servers before Kubernetes 1.19
+// show of it, as they drop the warnings.
+func (wh *WebHook) quotaAdmissionResponse(
+	obj runtime.Object,
+	kind string,
+	namespace string,
+	admission resourceusage.Admission) (*admissionv1beta1.AdmissionResponse, []string) {
+	response := &admissionv1beta1.AdmissionResponse{Allowed: admission.Allowed()}
+	violation := admission.Violation
+	if violation == nil {
+		return response, nil
+	}
+	if !response.Allowed {
+		return deniedResponse(violation.Message), nil
+	}
+	if admission.Mode != resourceusage.EnforcementModeWarn {
+		return response, nil
+	}
+
+	glog.V(2).Infof("Admitting object exceeding the resource quotas in warn mode: %s", violation.Message)
+	if wh.recorder != nil {
+		wh.recorder.Eventf(obj, apiv1.EventTypeWarning, "ResourceQuotaExceeded", "%s", violation.Message)
+	}
+	if wh.metrics != nil {
+		wh.metrics.observeQuotaWarning(kind, namespace, violation)
+	}
+	return response, []string{violation.Message}
 }
 
 func mutatePods(
@@ -0,0 +1,99 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/webhook/resourceusage"
+)
+
+// Outcomes of admission requests.
+const (
+	admissionAllowed = "allowed"
+	admissionDenied  = "denied"
+	admissionError   = "error"
+)
+
+const unknownAdmissionResource = "unknown"
+
+type webhookMetrics struct {
+	admissionLatency  *prometheus.HistogramVec
+	admissionCount    *prometheus.CounterVec
+	quotaWarningCount *prometheus.CounterVec
+	quotaShortfall    *prometheus.GaugeVec
+}
+
+func newWebhookMetrics(metricsConfig *util.MetricConfig) *webhookMetrics {
+	prefix := metricsConfig.MetricsPrefix
+	labels := []string{"resource", "outcome"}
+	return &webhookMetrics{
+		admissionLatency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    util.CreateValidMetricNameLabel(prefix, "webhook_admission_duration_seconds"),
+				Help:    "Latency of the admission requests served by the webhook by resource and outcome",
+				Buckets: prometheus.DefBuckets,
+			},
+			labels,
+		),
+		admissionCount: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: util.CreateValidMetricNameLabel(prefix, "webhook_admission_count"),
+				Help: "Number of admission requests served by the webhook by resource and outcome",
+			},
+			labels,
+		),
+		quotaWarningCount: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: util.CreateValidMetricNameLabel(prefix, "webhook_quota_warning_count"),
+				Help: "Number of objects admitted with a warning despite exceeding a resource quota by kind, namespace and resource",
+			},
+			[]string{"kind", "namespace", "resource"},
+		),
+		quotaShortfall: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: util.CreateValidMetricNameLabel(prefix, "webhook_quota_shortfall"),
+				Help: "By how much the last object admitted with a warning exceeded a resource quota by namespace and resource, in cores for CPU, bytes for memory and units otherwise",
+			},
+			[]string{"namespace", "resource"},
+		),
+	}
+}
+
+func (m *webhookMetrics) registerMetrics() {
+	util.RegisterMetric(m.admissionLatency)
+	util.RegisterMetric(m.admissionCount)
+	util.RegisterMetric(m.quotaWarningCount)
+	util.RegisterMetric(m.quotaShortfall)
+}
+
+// observeAdmission records an admission request for the given resource started at the given time.
+func (m *webhookMetrics) observeAdmission(resource string, outcome string, start time.Time) {
+	m.admissionLatency.WithLabelValues(resource, outcome).Observe(time.Since(start).Seconds())
+	m.admissionCount.WithLabelValues(resource, outcome).Inc()
+}
+
+// observeQuotaWarning records an object of the given kind admitted with a warning despite the quota violation.
+func (m *webhookMetrics) observeQuotaWarning(kind string, namespace string, violation *resourceusage.QuotaViolation) {
+	resource := string(violation.Resource)
+	m.quotaWarningCount.WithLabelValues(kind, namespace, resource).Inc()
+	shortfall := violation.Shortfall()
+	m.quotaShortfall.WithLabelValues(namespace, resource).Set(float64(shortfall.MilliValue()) / 1000)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	prometheusmodel "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	crdclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
+	crdinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+func TestServeExportsMetrics(t *testing.T) {
+	crdClient := crdclientfake.NewSimpleClientset()
+	informerFactory := crdinformers.NewSharedInformerFactory(crdClient, 0*time.Second)
+	hook := &WebHook{
+		lister:            informerFactory.Sparkoperator().V1beta2().SparkApplications().Lister(),
+		sparkJobNamespace: "default",
+		metrics:           newWebhookMetrics(&util.MetricConfig{}),
+	}
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+	podBytes, err := serializePod(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	review := &v1beta1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1beta1", Kind: "AdmissionReview"},
+		Request: &v1beta1.AdmissionRequest{
+			Resource:  podResource,
+			Object:    runtime.RawExtension{Raw: podBytes},
+			Namespace: "default",
+		},
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(body []byte, contentType string) {
+		request := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+		request.Header.Set("Content-Type", contentType)
+		hook.serve(httptest.NewRecorder(), request)
+	}
+	serve(body, "application/json")
+	serve(body, "application/json")
+	serve(body, "text/plain")
+
+	assert.Equal(t, float64(2), fetchAdmissionCount(hook.metrics, "pods", admissionAllowed))
+	assert.Equal(t, float64(1), fetchAdmissionCount(hook.metrics, unknownAdmissionResource, admissionError))
+}
+
+func fetchAdmissionCount(m *webhookMetrics, resource string, outcome string) float64 {
+	pb := &prometheusmodel.Metric{}
+	m.admissionCount.WithLabelValues(resource, outcome).Write(pb)
+	return pb.GetCounter().GetValue()
+}
+
+func fetchQuotaShortfall(m *webhookMetrics, namespace string, resource string) float64 {
+	pb := &prometheusmodel.Metric{}
+	m.quotaShortfall.WithLabelValues(namespace, resource).Write(pb)
+	return pb.GetGauge().GetValue()
+}
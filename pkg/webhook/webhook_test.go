@@ -17,7 +17,10 @@ limitations under the License.
 package webhook
 
 import (
+	"bytes"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -26,13 +29,19 @@ import (
 	"k8s.io/api/admission/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 
 	spov1beta2 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
 	crdclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
 	crdinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/webhook/resourceusage"
 )
 
 func TestMutatePod(t *testing.T) {
@@ -219,3 +228,143 @@ func TestNamespaceSelectorParsing(t *testing.T) {
 		},
 	}, t)
 }
+
+func TestAdmitSparkApplicationExceedingQuotaByNamespaceMode(t *testing.T) {
+	for _, mode := range []resourceusage.EnforcementMode{resourceusage.EnforcementModeReject, resourceusage.EnforcementModeWarn} {
+		kubeClient := kubeclientfake.NewSimpleClientset(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "default",
+				Labels: map[string]string{config.QuotaEnforcementLabel: string(mode)},
+			}},
+			&corev1.ResourceQuota{
+				ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: "default"},
+				Spec:       corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+			})
+		crdInformerFactory := crdinformers.NewSharedInformerFactory(crdclientfake.NewSimpleClientset(), 0*time.Second)
+		coreV1InformerFactory := informers.NewSharedInformerFactory(kubeClient, 0*time.Second)
+		enforcer := resourceusage.NewResourceQuotaEnforcer(crdInformerFactory, coreV1InformerFactory, resourceusage.EnforcementModeReject)
+		stopCh := make(chan struct{})
+		coreV1InformerFactory.Start(stopCh)
+		assert.Nil(t, enforcer.WaitForCacheSync(stopCh))
+
+		recorder := record.NewFakeRecorder(1)
+		hook := &WebHook{
+			lister:                         crdInformerFactory.Sparkoperator().V1beta2().SparkApplications().Lister(),
+			enableResourceQuotaEnforcement: true,
+			resourceQuotaEnforcer:          &enforcer,
+			recorder:                       recorder,
+			metrics:                        newWebhookMetrics(&util.MetricConfig{}),
+		}
+
+		app := &spov1beta2.SparkApplication{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "sparkoperator.k8s.io/v1beta2", Kind: "SparkApplication"},
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		}
+		appBytes, err := json.Marshal(app)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := json.Marshal(&v1beta1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1beta1", Kind: "AdmissionReview"},
+			Request: &v1beta1.AdmissionRequest{
+				Resource:  sparkApplicationResource,
+				Object:    runtime.RawExtension{Raw: appBytes},
+				Namespace: "default",
+				Name:      "foo",
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		request := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		responseRecorder := httptest.NewRecorder()
+		hook.serve(responseRecorder, request)
+		close(stopCh)
+
+		var response struct {
+			Response struct {
+				Allowed  bool     `json:"allowed"`
+				Warnings []string `json:"warnings"`
+			} `json:"response"`
+		}
+		assert.Nil(t, json.Unmarshal(responseRecorder.Body.Bytes(), &response))
+		message := "SparkApplication default/foo requests too many cores (2.512 cores requested, 1.000 available)."
+		if mode == resourceusage.EnforcementModeReject {
+			assert.False(t, response.Response.Allowed)
+			assert.Empty(t, response.Response.Warnings)
+			assert.Equal(t, 0, len(recorder.Events))
+			continue
+		}
+		assert.True(t, response.Response.Allowed)
+		assert.Equal(t, []string{message}, response.Response.Warnings)
+		assert.Equal(t, "Warning ResourceQuotaExceeded "+message, <-recorder.Events)
+		assert.Equal(t, 1.512, fetchQuotaShortfall(hook.metrics, "default", "requests.cpu"))
+	}
+}
+
+func TestAdmitSparkApplicationsWithNodeFitCheck(t *testing.T) {
+	kubeClient := kubeclientfake.NewSimpleClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node"},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	})
+	coreV1InformerFactory := informers.NewSharedInformerFactory(kubeClient, 0*time.Second)
+	checker := resourceusage.NewNodeFitChecker(coreV1InformerFactory)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	coreV1InformerFactory.Start(stopCh)
+	assert.Nil(t, checker.WaitForCacheSync(stopCh))
+	hook := &WebHook{nodeFitChecker: &checker}
+
+	fitting := &spov1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec:       spov1beta2.SparkApplicationSpec{Type: spov1beta2.JavaApplicationType},
+	}
+	// The executor memory fits, but not along with its overhead of at least 384Mi.
+	notFitting := fitting.DeepCopy()
+	memory := "3800m"
+	notFitting.Spec.Executor.Memory = &memory
+
+	review := func(operation v1beta1.Operation, oldApp, app *spov1beta2.SparkApplication) *v1beta1.AdmissionReview {
+		request := &v1beta1.AdmissionRequest{Resource: sparkApplicationResource, Operation: operation}
+		appBytes, err := json.Marshal(app)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Object = runtime.RawExtension{Raw: appBytes}
+		if oldApp != nil {
+			oldAppBytes, err := json.Marshal(oldApp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			request.OldObject = runtime.RawExtension{Raw: oldAppBytes}
+		}
+		return &v1beta1.AdmissionReview{Request: request}
+	}
+
+	response, _, err := hook.admitSparkApplications(review(v1beta1.Create, nil, fitting))
+	assert.Nil(t, err)
+	assert.True(t, response.Allowed)
+
+	response, _, err = hook.admitSparkApplications(review(v1beta1.Create, nil, notFitting))
+	assert.Nil(t, err)
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "SparkApplication default/foo: the executor pod requesting cpu: 1, memory: 4184Mi does not fit on any of the 1 schedulable nodes: 1 have insufficient memory (at most 4Gi allocatable).", response.Result.Message)
+
+	// Updates not changing the spec are allowed even if the application does not fit.
+	updated := notFitting.DeepCopy()
+	updated.Labels = map[string]string{"foo": "bar"}
+	response, _, err = hook.admitSparkApplications(review(v1beta1.Update, notFitting, updated))
+	assert.Nil(t, err)
+	assert.True(t, response.Allowed)
+
+	response, _, err = hook.admitSparkApplications(review(v1beta1.Update, fitting, notFitting))
+	assert.Nil(t, err)
+	assert.False(t, response.Allowed)
+}
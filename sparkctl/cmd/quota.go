@@ -0,0 +1,192 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	apiv1 "k8s.io/api/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/webhook/resourceusage"
+)
+
+var OperatorNamespace string
+var WebhookServiceName string
+var WebhookServicePort string
+
+var quotaCmd = &cobra.Command{
+	Use:   "quota [<yaml file>]",
+	Short: "Show the resource quotas of a namespace",
+	Long: `Show the resource quotas of a namespace and the resources reserved against them as seen by the operator,
+and optionally what a SparkApplication in a given YAML file would request. Requires resource quota enforcement and
+the quota report of the webhook to be enabled in the operator.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			fmt.Fprintln(os.Stderr, "must specify at most one YAML file of a SparkApplication")
+			return
+		}
+
+		var app *v1beta2.SparkApplication
+		var requested apiv1.ResourceList
+		if len(args) == 1 {
+			var err error
+			app, err = loadFromYAML(args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to read a SparkApplication from %s: %v\n", args[0], err)
+				return
+			}
+			requested, err = resourceusage.SparkApplicationRequest(*app)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to determine the resources requested by SparkApplication %s: %v\n", app.Name, err)
+				return
+			}
+		}
+
+		kubeClient, err := getKubeClient()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to get Kubernetes client: %v\n", err)
+			return
+		}
+
+		if err := doQuota(kubeClient, app, requested); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to show the resource quotas of namespace %s: %v\n", Namespace, err)
+		}
+	},
+}
+
+func init() {
+	quotaCmd.Flags().StringVar(&OperatorNamespace, "operator-namespace", "spark-operator",
+		"the namespace of the Service of the operator webhook")
+	quotaCmd.Flags().StringVar(&WebhookServiceName, "webhook-service", "spark-webhook",
+		"the name of the Service of the operator webhook")
+	quotaCmd.Flags().StringVar(&WebhookServicePort, "webhook-service-port", "443",
+		"the port of the Service of the operator webhook")
+}
+
+func doQuota(kubeClient clientset.Interface, app *v1beta2.SparkApplication, requested apiv1.ResourceList) error {
+	params := map[string]string{"namespace": Namespace}
+	if app != nil {
+		// The resources reserved by the application if it already exists are left out of the usage it is checked
+		// against.
+		params["application"] = app.Name
+	}
+	// The webhook server is reached through the service proxy of the API server.
+	body, err := kubeClient.CoreV1().Services(OperatorNamespace).ProxyGet("https", WebhookServiceName,
+		WebhookServicePort, resourceusage.ReportPath, params).DoRaw()
+	if err != nil {
+		return fmt.Errorf("failed to get the resource quota report from the operator: %v", err)
+	}
+	report := &resourceusage.NamespaceReport{}
+	if err := json.Unmarshal(body, report); err != nil {
+		return fmt.Errorf("failed to parse the resource quota report: %v", err)
+	}
+
+	fmt.Printf("Namespace: %s\nEnforcement mode: %s\n\n", report.Namespace, report.Mode)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Kind", "Resource", "Reserved"})
+	table.AppendBulk(getReservedRows(report))
+	table.Render()
+	fmt.Println()
+
+	header := []string{"Quota", "Resource", "Hard", "Reserved"}
+	if requested != nil {
+		header = append(header, "Requested", "Fits")
+	}
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(header)
+	table.AppendBulk(getQuotaRows(report, app, requested))
+	table.Render()
+
+	return nil
+}
+
+// getReservedRows returns a row of the resources reserved by each kind of object for each resource.
+func getReservedRows(report *resourceusage.NamespaceReport) [][]string {
+	var kinds []string
+	for kind := range report.Reserved {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	var rows [][]string
+	for _, kind := range kinds {
+		resources := report.Reserved[kind]
+		for _, name := range sortedResourceNames(resources) {
+			quantity := resources[name]
+			rows = append(rows, []string{kind, string(name), quantity.String()})
+		}
+	}
+	return rows
+}
+
+// getQuotaRows returns a row of the hard limit and reserved amount for each resource of each quota, along with the
+// amount requested by the application and whether it fits if requested is not nil. Nothing is requested from the
+// quotas the application is not in scope of.
+func getQuotaRows(report *resourceusage.NamespaceReport, app *v1beta2.SparkApplication, requested apiv1.ResourceList) [][]string {
+	var rows [][]string
+	for _, quota := range report.Quotas {
+		if quota.Error != "" {
+			rows = append(rows, []string{quota.Name, "", "", fmt.Sprintf("ignored: %s", quota.Error)})
+			continue
+		}
+		inScope := requested != nil
+		if inScope {
+			var err error
+			if inScope, err = quota.Selects(*app); err != nil {
+				rows = append(rows, []string{quota.Name, "", "", fmt.Sprintf("ignored: %v", err)})
+				continue
+			}
+		}
+		for _, name := range sortedResourceNames(quota.Hard) {
+			hard := quota.Hard[name]
+			used, tracked := quota.Used[name]
+			if !tracked {
+				continue
+			}
+			row := []string{quota.Name, string(name), hard.String(), used.String()}
+			if requested != nil && !inScope {
+				row = append(row, "", "out of scope")
+			} else if requested != nil {
+				requestedName, _ := resourceusage.QuotaResourceName(name)
+				request := requested[requestedName]
+				available := hard.DeepCopy()
+				available.Sub(used)
+				row = append(row, request.String(), fmt.Sprintf("%t", request.Cmp(available) <= 0))
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func sortedResourceNames(resources apiv1.ResourceList) []apiv1.ResourceName {
+	var names []apiv1.ResourceName
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/webhook/resourceusage"
+)
+
+func TestGetQuotaRows(t *testing.T) {
+	report := &resourceusage.NamespaceReport{
+		Namespace: "default",
+		Mode:      resourceusage.EnforcementModeReject,
+		Reserved: map[string]apiv1.ResourceList{
+			"SparkApplication": {apiv1.ResourceRequestsCPU: resource.MustParse("3")},
+			"Pod":              {apiv1.ResourceRequestsCPU: resource.MustParse("1"), apiv1.ResourcePods: resource.MustParse("1")},
+		},
+		Quotas: []resourceusage.QuotaReport{
+			{
+				Name: "compute",
+				Hard: apiv1.ResourceList{apiv1.ResourceCPU: resource.MustParse("8"), "count/services": resource.MustParse("1")},
+				Used: apiv1.ResourceList{apiv1.ResourceCPU: resource.MustParse("4")},
+			},
+			{
+				Name:   "best-effort",
+				Hard:   apiv1.ResourceList{apiv1.ResourcePods: resource.MustParse("1")},
+				Scopes: []apiv1.ResourceQuotaScope{apiv1.ResourceQuotaScopeBestEffort},
+				Used:   apiv1.ResourceList{apiv1.ResourcePods: resource.MustParse("1")},
+			},
+			{Name: "scoped", Error: "unsupported ResourceQuota scope Unknown"},
+		},
+	}
+
+	assert.Equal(t, [][]string{
+		{"Pod", "pods", "1"},
+		{"Pod", "requests.cpu", "1"},
+		{"SparkApplication", "requests.cpu", "3"},
+	}, getReservedRows(report))
+
+	assert.Equal(t, [][]string{
+		{"compute", "cpu", "8", "4"},
+		{"best-effort", "pods", "1", "1"},
+		{"scoped", "", "", "ignored: unsupported ResourceQuota scope Unknown"},
+	}, getQuotaRows(report, nil, nil))
+
+	// Spark pods are never best effort, so the application requests nothing from the best-effort quota.
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	requested := apiv1.ResourceList{apiv1.ResourceRequestsCPU: resource.MustParse("5"), apiv1.ResourcePods: resource.MustParse("3")}
+	assert.Equal(t, [][]string{
+		{"compute", "cpu", "8", "4", "5", "false"},
+		{"best-effort", "pods", "1", "1", "", "out of scope"},
+		{"scoped", "", "", "ignored: unsupported ResourceQuota scope Unknown"},
+	}, getQuotaRows(report, app, requested))
+
+	requested = apiv1.ResourceList{apiv1.ResourceRequestsCPU: resource.MustParse("4")}
+	assert.Equal(t, "true", getQuotaRows(report, app, requested)[0][5])
+}